@@ -0,0 +1,124 @@
+package http
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	stdhttp "net/http"
+	"net/url"
+	"strings"
+)
+
+// WithBasicAuth authenticates every request that doesn't already set an Authorization header with HTTP
+// basic authentication. Redirects are only authenticated if they stay on the host (and port) of the original
+// request, so that credentials aren't sent to whichever host a redirect names.
+//
+// Like WithDefaultHeaders, the credentials are added beneath the instrumentation, so they are never seen by
+// WithClientCaptureRequestHeaders (which redacts Authorization regardless).
+func WithBasicAuth(username, password string) ClientOption {
+	return func(c *stdhttp.Client) error {
+		if username == "" {
+			return errors.New("basic auth username must not be empty")
+		}
+		value := "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+		wrapAuth(c, func(context.Context) (string, error) { return value, nil })
+		return nil
+	}
+}
+
+// WithBearerToken authenticates every request that doesn't already set an Authorization header with the
+// bearer token. As for WithBasicAuth, redirects to other hosts are not authenticated. For tokens that expire,
+// use WithBearerTokenSource.
+func WithBearerToken(token string) ClientOption {
+	return func(c *stdhttp.Client) error {
+		if token == "" {
+			return errors.New("bearer token must not be empty")
+		}
+		value := "Bearer " + token
+		wrapAuth(c, func(context.Context) (string, error) { return value, nil })
+		return nil
+	}
+}
+
+// WithBearerTokenSource is like WithBearerToken, but calls source for the token each time a request is sent
+// (including for each retry), so that short-lived tokens can be refreshed. The source is called with the
+// request's context, and should cache tokens itself where fetching them is expensive. If it fails, the
+// request fails with its error.
+func WithBearerTokenSource(source func(context.Context) (string, error)) ClientOption {
+	return func(c *stdhttp.Client) error {
+		if source == nil {
+			return errors.New("bearer token source must not be nil")
+		}
+		wrapAuth(c, func(ctx context.Context) (string, error) {
+			token, err := source(ctx)
+			if err != nil {
+				return "", fmt.Errorf("getting bearer token: %w", err)
+			}
+			if token == "" {
+				return "", errors.New("getting bearer token: token is empty")
+			}
+			return "Bearer " + token, nil
+		})
+		return nil
+	}
+}
+
+// wrapAuth adds an authTransport beneath any resilience layers, so that each attempt is authenticated
+// afresh.
+func wrapAuth(c *stdhttp.Client, authorization func(context.Context) (string, error)) {
+	wrapInnermost(c, func(base stdhttp.RoundTripper) stdhttp.RoundTripper {
+		return &authTransport{base: base, authorization: authorization}
+	})
+}
+
+// authTransport is a RoundTripper that sets the Authorization header on requests that don't set it.
+type authTransport struct {
+	base          stdhttp.RoundTripper
+	authorization func(context.Context) (string, error)
+}
+
+func (t *authTransport) unwrap() stdhttp.RoundTripper {
+	return t.base
+}
+
+func (t *authTransport) setBase(base stdhttp.RoundTripper) {
+	t.base = base
+}
+
+// RoundTrip implements http.RoundTripper. The request is copied before the header is set, as a
+// RoundTripper must not modify it.
+func (t *authTransport) RoundTrip(req *stdhttp.Request) (*stdhttp.Response, error) {
+	if req.Header.Get("Authorization") != "" || !sameOrigin(req.URL, originalRequest(req).URL) {
+		return t.base.RoundTrip(req)
+	}
+	value, err := t.authorization(req.Context())
+	if err != nil {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		return nil, err
+	}
+	next := *req
+	next.Header = req.Header.Clone()
+	if next.Header == nil {
+		next.Header = make(stdhttp.Header, 1)
+	}
+	next.Header.Set("Authorization", value)
+	return t.base.RoundTrip(&next)
+}
+
+// originalRequest returns the request that led to req, following the redirects recorded in req.Response back
+// to the first.
+func originalRequest(req *stdhttp.Request) *stdhttp.Request {
+	for req.Response != nil && req.Response.Request != nil {
+		req = req.Response.Request
+	}
+	return req
+}
+
+// sameOrigin reports whether a and b refer to the same host and port, taking the default port for the scheme
+// when none is given.
+func sameOrigin(a, b *url.URL) bool {
+	return strings.EqualFold(a.Hostname(), b.Hostname()) && urlPort(a.Port(), a.Scheme) == urlPort(b.Port(), b.Scheme)
+}
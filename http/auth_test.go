@@ -0,0 +1,184 @@
+package http
+
+import (
+	"context"
+	"errors"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestWithAuth(t *testing.T) {
+	var got string
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		got = r.Header.Get("Authorization")
+	}))
+	defer ts.Close()
+
+	var calls int
+	source := func(context.Context) (string, error) {
+		calls++
+		return "token-" + strconv.Itoa(calls), nil
+	}
+
+	for _, tc := range []struct {
+		name string
+		opt  ClientOption
+		want []string
+	}{
+		{name: "basic", opt: WithBasicAuth("user", "pass"), want: []string{"Basic dXNlcjpwYXNz", "Basic dXNlcjpwYXNz"}},
+		{name: "bearer", opt: WithBearerToken("secret"), want: []string{"Bearer secret", "Bearer secret"}},
+		{name: "source", opt: WithBearerTokenSource(source), want: []string{"Bearer token-1", "Bearer token-2"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClient(tc.opt)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, want := range tc.want {
+				resp, err := c.Get(ts.URL)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				_ = resp.Body.Close()
+				if got != want {
+					t.Errorf("expected Authorization %q, got %q", want, got)
+				}
+			}
+
+			// An Authorization header set on the request is left alone.
+			req, _ := stdhttp.NewRequest(stdhttp.MethodGet, ts.URL, nil)
+			req.Header.Set("Authorization", "Bearer override")
+			resp, err := c.Do(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			_ = resp.Body.Close()
+			if got != "Bearer override" {
+				t.Errorf("expected the request's Authorization to be kept, got %q", got)
+			}
+			if req.Header.Get("Authorization") != "Bearer override" || len(req.Header) != 1 {
+				t.Errorf("expected the request not to be modified, got %v", req.Header)
+			}
+		})
+	}
+
+	for _, opt := range []ClientOption{WithBasicAuth("", "pass"), WithBearerToken(""), WithBearerTokenSource(nil)} {
+		if _, err := NewClient(opt); err == nil {
+			t.Error("expected an error for missing credentials")
+		}
+	}
+}
+
+func TestWithAuth_CrossHostRedirect(t *testing.T) {
+	var got []string
+	b := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		got = r.Header.Values("Authorization")
+	}))
+	defer b.Close()
+	var gotA string
+	a := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		if r.URL.Path == "/same" {
+			gotA = r.Header.Get("Authorization")
+			return
+		}
+		target := b.URL
+		if r.URL.Query().Has("same") {
+			target = "/same"
+		}
+		stdhttp.Redirect(w, r, target, stdhttp.StatusFound)
+	}))
+	defer a.Close()
+
+	for _, opt := range []ClientOption{WithBasicAuth("user", "pass"), WithBearerToken("secret")} {
+		got, gotA = nil, ""
+		c, err := NewClient(opt)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// The second server is on another port, so another origin, and must not receive the credentials.
+		resp, err := c.Get(a.URL)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		_ = resp.Body.Close()
+		if len(got) != 0 {
+			t.Errorf("expected no Authorization on the redirected host, got %q", got)
+		}
+
+		// Redirects on the same host are still authenticated.
+		resp, err = c.Get(a.URL + "?same")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		_ = resp.Body.Close()
+		if gotA == "" {
+			t.Error("expected Authorization on a redirect to the same host")
+		}
+	}
+}
+
+func TestWithBearerTokenSource_Error(t *testing.T) {
+	var served bool
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		served = true
+	}))
+	defer ts.Close()
+
+	errExpired := errors.New("refresh token expired")
+	c, err := NewClient(WithBearerTokenSource(func(context.Context) (string, error) { return "", errExpired }))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.Get(ts.URL); !errors.Is(err, errExpired) {
+		t.Errorf("expected the token source's error, got %v", err)
+	}
+	if served {
+		t.Error("expected the request not to be sent")
+	}
+}
+
+func TestWithBearerToken_NotCaptured(t *testing.T) {
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {}))
+	defer ts.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+	for _, opts := range [][]ClientOption{
+		{WithBearerToken("secret")},
+		{WithBearerToken("secret"), WithClientCaptureRequestHeaders("Authorization")},
+	} {
+		c, err := NewClient(append(opts, WithClientTracerProvider(tp))...)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ctx, span := tp.Tracer("test").Start(context.Background(), "parent")
+		req, _ := stdhttp.NewRequestWithContext(ctx, stdhttp.MethodGet, ts.URL, nil)
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		_ = resp.Body.Close()
+		span.End()
+	}
+
+	for _, s := range exporter.GetSpans() {
+		for _, kv := range s.Attributes {
+			if v := kv.Value.Emit(); v == "secret" || v == "Bearer secret" {
+				t.Errorf("expected the token not to be recorded, got %s=%s", kv.Key, v)
+			}
+		}
+		for _, e := range s.Events {
+			for _, kv := range e.Attributes {
+				if v := kv.Value.Emit(); v == "secret" || v == "Bearer secret" {
+					t.Errorf("expected the token not to be recorded, got %s=%s on %s", kv.Key, v, e.Name)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package http
+
+import (
+	"context"
+	"sync"
+)
+
+// backgroundTasksKey is the context key for the server's background task tracker.
+type backgroundTasksKey struct{}
+
+// WithBackgroundTask registers background work started by a handler, such as an asynchronous audit write,
+// so that the server waits for it to finish when shutting down rather than abandoning it mid-flight. ctx must
+// be the request context (or derived from it).
+//
+// It returns a context for the task, which carries the request's values but is not cancelled when the
+// request completes, and a done function that must be called exactly once when the task finishes:
+//
+//	taskCtx, done := http.WithBackgroundTask(r.Context())
+//	go func() {
+//		defer done()
+//		audit.Write(taskCtx, event)
+//	}()
+//
+// Shutdown waits for background tasks after in-flight requests, within the same shutdown timeout; tasks
+// still running when it expires are abandoned, and shutdown reports an error. Tasks registered once shutdown
+// has started waiting for them, by a handler still running after Shutdown, run untracked. Outside of a Server
+// the task is not tracked and done does nothing.
+func WithBackgroundTask(ctx context.Context) (context.Context, func()) {
+	taskCtx := context.WithoutCancel(ctx)
+	tasks, ok := ctx.Value(backgroundTasksKey{}).(*backgroundTasks)
+	if !ok || !tasks.add() {
+		return taskCtx, func() {}
+	}
+	return taskCtx, sync.OnceFunc(tasks.done)
+}
+
+// backgroundTasks tracks the tasks registered with WithBackgroundTask, so that shutdown can wait for them.
+// Unlike a sync.WaitGroup, it may be added to while it is being waited for, which a handler that outlives
+// Shutdown can do.
+type backgroundTasks struct {
+	mu      sync.Mutex
+	running int
+	// idle is created when shutdown starts waiting, and closed once no tasks are running. New tasks are not
+	// tracked after that.
+	idle chan struct{}
+}
+
+// add tracks a new task, and reports whether it is tracked.
+func (b *backgroundTasks) add() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.idle != nil {
+		return false
+	}
+	b.running++
+	return true
+}
+
+// done marks a tracked task as finished.
+func (b *backgroundTasks) done() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.running--
+	if b.running == 0 && b.idle != nil {
+		close(b.idle)
+	}
+}
+
+// wait stops new tasks being tracked, and returns a channel that is closed once the tracked tasks finish.
+func (b *backgroundTasks) wait() <-chan struct{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.idle == nil {
+		b.idle = make(chan struct{})
+		if b.running == 0 {
+			close(b.idle)
+		}
+	}
+	return b.idle
+}
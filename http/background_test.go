@@ -0,0 +1,123 @@
+package http
+
+import (
+	"context"
+	"errors"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithBackgroundTask(t *testing.T) {
+	var completed atomic.Bool
+	handler := stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		ctx, done := WithBackgroundTask(r.Context())
+		go func() {
+			defer done()
+			time.Sleep(50 * time.Millisecond)
+			if ctx.Err() == nil {
+				completed.Store(true)
+			}
+		}()
+		w.WriteHeader(stdhttp.StatusAccepted)
+	})
+
+	s, err := NewServer(":0", handler)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.server.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/audit", nil))
+
+	if err := s.shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected shutdown error: %v", err)
+	}
+	if !completed.Load() {
+		t.Error("expected the background task to complete, uncancelled, before shutdown returned")
+	}
+}
+
+func TestWithBackgroundTask_ShutdownTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	handler := stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		_, done := WithBackgroundTask(r.Context())
+		go func() {
+			defer done()
+			<-release
+		}()
+	})
+
+	s, err := NewServer(":0", handler)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.server.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/audit", nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected shutdown to give up on the background task, got %v", err)
+	}
+}
+
+func TestWithBackgroundTask_OutsideServer(t *testing.T) {
+	ctx, done := WithBackgroundTask(context.Background())
+	done()
+	done()
+	if ctx == nil {
+		t.Error("expected a context")
+	}
+}
+
+func TestWithBackgroundTask_HandlerTimeout(t *testing.T) {
+	releaseEarly, releaseLate := make(chan struct{}), make(chan struct{})
+	defer close(releaseLate)
+	unblock, registered := make(chan struct{}), make(chan struct{})
+	handler := stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		release := releaseEarly
+		if r.URL.Path == "/late" {
+			// Cut off by the handler timeout, this handler keeps running until after shutdown has begun.
+			<-unblock
+			release = releaseLate
+		}
+		_, done := WithBackgroundTask(r.Context())
+		go func() {
+			defer done()
+			<-release
+		}()
+		if r.URL.Path == "/late" {
+			close(registered)
+		}
+	})
+
+	s, err := NewServer(":0", handler, WithServerHandlerTimeout(10*time.Millisecond, ""))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.server.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/early", nil))
+	s.server.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/late", nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- s.shutdown(ctx) }()
+	for {
+		s.background.mu.Lock()
+		waiting := s.background.idle != nil
+		s.background.mu.Unlock()
+		if waiting {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// The task registered after shutdown started waiting runs untracked, so only the early one is waited for.
+	close(unblock)
+	<-registered
+	close(releaseEarly)
+	if err := <-shutdown; err != nil {
+		t.Errorf("expected shutdown to wait only for the early task, got %v", err)
+	}
+}
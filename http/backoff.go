@@ -0,0 +1,118 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	stdhttp "net/http"
+	"strings"
+	"time"
+)
+
+// backoff determines the delay before each retry.
+type backoff struct {
+	base        time.Duration
+	max         time.Duration
+	exponential bool
+	jitter      bool
+}
+
+// WithExponentialBackoff delays each retry by up to base, doubling for every further retry, capped at max. With
+// the default full jitter, the delay is chosen at random between zero and that value, so that clients retrying
+// at the same time don't do so in lockstep.
+//
+// A Retry-After header on the failed response (in either delta-seconds or HTTP-date form) is honoured instead,
+// capped at max.
+func WithExponentialBackoff(base, max time.Duration) RetryOption {
+	return func(rt *retryTransport) error {
+		if base <= 0 {
+			return errors.New("backoff base must be positive")
+		}
+		if max < base {
+			return errors.New("backoff max must be at least the base")
+		}
+		rt.backoff = &backoff{base: base, max: max, exponential: true, jitter: rt.backoffJitter()}
+		return nil
+	}
+}
+
+// WithConstantBackoff delays each retry by up to d (with the default full jitter, a random delay between zero and
+// d). A Retry-After header on the failed response is honoured instead, capped at d.
+func WithConstantBackoff(d time.Duration) RetryOption {
+	return func(rt *retryTransport) error {
+		if d <= 0 {
+			return errors.New("backoff must be positive")
+		}
+		rt.backoff = &backoff{base: d, max: d, jitter: rt.backoffJitter()}
+		return nil
+	}
+}
+
+// WithoutBackoffJitter makes the backoff delay exactly the computed value, rather than a random delay up to it.
+func WithoutBackoffJitter() RetryOption {
+	return func(rt *retryTransport) error {
+		rt.noJitter = true
+		if rt.backoff != nil {
+			rt.backoff.jitter = false
+		}
+		return nil
+	}
+}
+
+// backoffJitter reports whether backoffs should be jittered, given the options applied so far.
+func (t *retryTransport) backoffJitter() bool {
+	return !t.noJitter
+}
+
+// delay returns how long to wait before the given retry (1 for the first), following a failed response, which
+// may be nil.
+func (b *backoff) delay(retry int, resp *stdhttp.Response) time.Duration {
+	if resp != nil {
+		if d, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return min(d, b.max)
+		}
+	}
+
+	d := b.base
+	if b.exponential {
+		// Stop doubling once the cap is reached, which also avoids overflow.
+		for i := 1; i < retry && d < b.max; i++ {
+			d *= 2
+		}
+		d = min(d, b.max)
+	}
+	if b.jitter {
+		d = rand.N(d + 1)
+	}
+	return d
+}
+
+// retryAfter parses a Retry-After header value, in either delta-seconds or HTTP-date form, as a delay from now.
+func retryAfter(v string, now time.Time) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+	if d, ok := deltaSeconds(v); ok {
+		return d, true
+	}
+	if at, err := stdhttp.ParseTime(v); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// sleep waits for d, returning early with the context's error if it is done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,151 @@
+package http
+
+import (
+	"context"
+	"errors"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBackoff_Delay(t *testing.T) {
+	b := &backoff{base: 100 * time.Millisecond, max: time.Second, exponential: true}
+	for retry, want := range map[int]time.Duration{
+		1:  100 * time.Millisecond,
+		2:  200 * time.Millisecond,
+		4:  800 * time.Millisecond,
+		5:  time.Second,
+		80: time.Second,
+	} {
+		if got := b.delay(retry, nil); got != want {
+			t.Errorf("retry %d: expected %v, got %v", retry, want, got)
+		}
+	}
+
+	b.jitter = true
+	for range 100 {
+		if got := b.delay(3, nil); got < 0 || got > 400*time.Millisecond {
+			t.Fatalf("expected jittered delay within [0, 400ms], got %v", got)
+		}
+	}
+
+	constant := &backoff{base: 50 * time.Millisecond, max: 50 * time.Millisecond}
+	if got := constant.delay(10, nil); got != 50*time.Millisecond {
+		t.Errorf("expected constant delay of 50ms, got %v", got)
+	}
+}
+
+func TestBackoff_RetryAfter(t *testing.T) {
+	b := &backoff{base: 10 * time.Millisecond, max: 5 * time.Second, exponential: true, jitter: true}
+
+	resp := &stdhttp.Response{Header: stdhttp.Header{"Retry-After": []string{"2"}}}
+	if got := b.delay(1, resp); got != 2*time.Second {
+		t.Errorf("expected delta-seconds Retry-After of 2s, got %v", got)
+	}
+
+	resp.Header.Set("Retry-After", "120")
+	if got := b.delay(1, resp); got != 5*time.Second {
+		t.Errorf("expected Retry-After capped at 5s, got %v", got)
+	}
+
+	// Values large enough to overflow a duration are still capped, rather than wrapping to an immediate retry.
+	for _, v := range []string{"9223372036854775807", "99999999999999999999999"} {
+		resp.Header.Set("Retry-After", v)
+		if got := b.delay(1, resp); got != 5*time.Second {
+			t.Errorf("expected Retry-After %s capped at 5s, got %v", v, got)
+		}
+	}
+
+	resp.Header.Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(stdhttp.TimeFormat))
+	if got := b.delay(1, resp); got != 5*time.Second {
+		t.Errorf("expected HTTP-date Retry-After capped at 5s, got %v", got)
+	}
+
+	now := time.Now()
+	if got, ok := retryAfter(now.Add(3*time.Second).UTC().Format(stdhttp.TimeFormat), now); !ok ||
+		got <= time.Second || got > 3*time.Second {
+		t.Errorf("expected HTTP-date Retry-After of about 3s, got %v (%v)", got, ok)
+	}
+	if got, ok := retryAfter(now.Add(-time.Hour).UTC().Format(stdhttp.TimeFormat), now); !ok || got != 0 {
+		t.Errorf("expected a past HTTP-date to mean no delay, got %v (%v)", got, ok)
+	}
+	for _, v := range []string{"", "-1", "soon"} {
+		if _, ok := retryAfter(v, now); ok {
+			t.Errorf("expected %q not to parse", v)
+		}
+	}
+}
+
+func TestWithExponentialBackoff(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(stdhttp.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(stdhttp.StatusOK)
+	}))
+	defer ts.Close()
+
+	c, err := NewClient(WithRetry(3,
+		WithoutBackoffJitter(), WithExponentialBackoff(20*time.Millisecond, time.Second),
+	))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	start := time.Now()
+	resp, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	// Without jitter, the retries wait 20ms and then 40ms.
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("expected at least 60ms of backoff, got %v", elapsed)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("expected 3 attempts, got %d", got)
+	}
+
+	for _, opt := range []RetryOption{
+		WithExponentialBackoff(0, time.Second),
+		WithExponentialBackoff(time.Second, time.Millisecond),
+		WithConstantBackoff(0),
+	} {
+		if _, err := NewClient(WithRetry(3, opt)); err == nil {
+			t.Error("expected an error for an invalid backoff")
+		}
+	}
+}
+
+func TestWithConstantBackoff_Cancel(t *testing.T) {
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		w.WriteHeader(stdhttp.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	c, err := NewClient(WithTimeout(0), WithRetry(3, WithConstantBackoff(time.Minute), WithoutBackoffJitter()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, err := stdhttp.NewRequestWithContext(ctx, stdhttp.MethodGet, ts.URL, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	start := time.Now()
+	_, err = c.Do(req)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the backoff to be interrupted, took %v", elapsed)
+	}
+}
@@ -0,0 +1,148 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	stdhttp "net/http"
+	"strconv"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WithMaxRequestBodySize limits request bodies to n bytes, as with http.MaxBytesReader. A request whose body is
+// over the limit gets a 413 Content Too Large response with an application/problem+json body stating the limit:
+//   - If its Content-Length is over the limit, it is rejected before the handler is called.
+//   - Otherwise, reading past the limit fails with an *http.MaxBytesError, and whatever the handler then
+//     responds with (typically a 400 from a failed decode) is replaced by the 413, however the handler read the
+//     body. If the handler had already written its response headers, the response is left as it is.
+//
+// Chunked bodies, which declare no Content-Length, are limited as they are read. A limit of zero means
+// unlimited.
+//
+// Oversized bodies are recorded as an http.request.body_too_large event on the span, with error.type set to
+// body_too_large, and counted in http.server.body_too_large.
+func WithMaxRequestBodySize(n int64) ServerOption {
+	return func(s *Server) error {
+		if n < 0 {
+			return errors.New("max request body size must not be negative")
+		}
+		s.maxRequestBodySize = n
+		return nil
+	}
+}
+
+// problemDetails is an RFC 9457 problem details object.
+type problemDetails struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// limitedBody wraps a request body limited by http.MaxBytesReader, recording when the limit is exceeded.
+type limitedBody struct {
+	io.ReadCloser
+	ctx      context.Context
+	limit    int64
+	counter  metric.Int64Counter
+	exceeded atomic.Bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var mbe *stdhttp.MaxBytesError
+	if err != nil && errors.As(err, &mbe) && b.exceeded.CompareAndSwap(false, true) {
+		recordBodyTooLarge(b.ctx, b.counter, b.limit)
+	}
+	return n, err
+}
+
+// errorTypeBodyTooLarge is the error.type recorded on the span of a request whose body is over the limit.
+const errorTypeBodyTooLarge = "body_too_large"
+
+// recordBodyTooLarge records a request body over the limit on the span and counter.
+func recordBodyTooLarge(ctx context.Context, counter metric.Int64Counter, limit int64) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(semconv.ErrorTypeKey.String(errorTypeBodyTooLarge))
+	span.AddEvent("http.request.body_too_large", trace.WithAttributes(
+		attribute.Int64("http.request.body.limit", limit),
+	))
+	if counter != nil {
+		counter.Add(ctx, 1)
+	}
+}
+
+// bodyLimitWriter replaces the handler's response with a 413 once the request body has exceeded its limit,
+// provided the response headers have not been written yet.
+type bodyLimitWriter struct {
+	stdhttp.ResponseWriter
+	body        *limitedBody
+	wroteHeader bool
+	replaced    bool
+}
+
+func (w *bodyLimitWriter) WriteHeader(statusCode int) {
+	if w.replace() {
+		return
+	}
+	if statusCode >= 200 {
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *bodyLimitWriter) Write(b []byte) (int, error) {
+	if w.replace() {
+		// The handler's response is discarded in favour of the 413.
+		return len(b), nil
+	}
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying ResponseWriter, allowing http.ResponseController to reach it.
+func (w *bodyLimitWriter) Unwrap() stdhttp.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// replace writes the 413 if the body has exceeded its limit and nothing has been written yet, reporting
+// whether the handler's response is being replaced.
+func (w *bodyLimitWriter) replace() bool {
+	if w.replaced {
+		return true
+	}
+	if w.wroteHeader || !w.body.exceeded.Load() {
+		return false
+	}
+	w.replaced = true
+	writeBodyTooLarge(w.ResponseWriter, w.body.limit)
+	return true
+}
+
+// finish writes the 413 if the handler returned without responding after the body exceeded its limit.
+func (w *bodyLimitWriter) finish() {
+	w.replace()
+}
+
+// writeBodyTooLarge responds with a 413 problem details response stating the limit.
+func writeBodyTooLarge(w stdhttp.ResponseWriter, limit int64) {
+	h := w.Header()
+	for _, k := range []string{"Content-Length", "Content-Encoding", "Etag", "Last-Modified"} {
+		h.Del(k)
+	}
+	h.Set("Content-Type", "application/problem+json")
+	h.Set("Connection", "close")
+	w.WriteHeader(stdhttp.StatusRequestEntityTooLarge)
+	_ = json.NewEncoder(w).Encode(problemDetails{
+		Type:   "about:blank",
+		Title:  stdhttp.StatusText(stdhttp.StatusRequestEntityTooLarge),
+		Status: stdhttp.StatusRequestEntityTooLarge,
+		Detail: "request body exceeds the limit of " + strconv.FormatInt(limit, 10) + " bytes",
+	})
+}
@@ -0,0 +1,161 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+func TestWithMaxRequestBodySize(t *testing.T) {
+	const limit = 16
+
+	handlers := map[string]stdhttp.HandlerFunc{
+		"decoder": func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+			var v string
+			if err := json.NewDecoder(r.Body).Decode(&v); err != nil {
+				stdhttp.Error(w, err.Error(), stdhttp.StatusBadRequest)
+				return
+			}
+			_, _ = io.WriteString(w, v)
+		},
+		"read all": func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				w.WriteHeader(stdhttp.StatusInternalServerError)
+				return
+			}
+			var v string
+			_ = json.Unmarshal(body, &v)
+			_, _ = io.WriteString(w, v)
+		},
+		"ignores error": func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+			_, _ = io.ReadAll(r.Body)
+		},
+	}
+
+	for name, handler := range handlers {
+		for _, tc := range []struct {
+			name         string
+			body         string
+			chunked      bool
+			wantTooLarge bool
+		}{
+			{name: "under", body: `"` + strings.Repeat("a", limit-2) + `"`},
+			{name: "under chunked", body: `"` + strings.Repeat("a", limit-2) + `"`, chunked: true},
+			{name: "over", body: `"` + strings.Repeat("a", limit-1) + `"`, chunked: true, wantTooLarge: true},
+			{name: "declared over", body: `"` + strings.Repeat("a", limit-1) + `"`, wantTooLarge: true},
+		} {
+			t.Run(name+"/"+tc.name, func(t *testing.T) {
+				exporter := tracetest.NewInMemoryExporter()
+				tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+				reader := sdkmetric.NewManualReader()
+				mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+				s, err := NewServer(":0", handler,
+					WithServerTracerProvider(tp),
+					WithServerMeterProvider(mp),
+					WithMaxRequestBodySize(limit),
+				)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				req := httptest.NewRequest(stdhttp.MethodPost, "/", strings.NewReader(tc.body))
+				if tc.chunked {
+					req.ContentLength = -1
+				}
+				rec := httptest.NewRecorder()
+				s.server.Handler.ServeHTTP(rec, req)
+
+				var wantCount int64
+				if tc.wantTooLarge {
+					wantCount = 1
+					if rec.Code != stdhttp.StatusRequestEntityTooLarge {
+						t.Fatalf("expected a 413, got %d", rec.Code)
+					}
+					if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+						t.Errorf("expected a problem details response, got %q", ct)
+					}
+					var problem problemDetails
+					if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+						t.Fatalf("unexpected error: %v", err)
+					}
+					if problem.Status != stdhttp.StatusRequestEntityTooLarge ||
+						!strings.Contains(problem.Detail, "16 bytes") {
+						t.Errorf("expected the problem to state the limit, got %+v", problem)
+					}
+
+					span := exporter.GetSpans()[0]
+					var found bool
+					for _, e := range span.Events {
+						found = found || e.Name == "http.request.body_too_large"
+					}
+					if !found {
+						t.Error("expected a body too large span event")
+					}
+					if !hasAttr(span.Attributes, semconv.ErrorTypeKey.String("body_too_large")) {
+						t.Errorf("expected error.type=body_too_large on the span, got %v", span.Attributes)
+					}
+				} else if rec.Code != stdhttp.StatusOK {
+					t.Errorf("expected a 200, got %d", rec.Code)
+				}
+
+				if got := sumCounter(t, reader, "http.server.body_too_large"); got != wantCount {
+					t.Errorf("expected %d oversized bodies counted, got %d", wantCount, got)
+				}
+			})
+		}
+	}
+}
+
+func TestWithMaxRequestBodySize_HeadersAlreadyWritten(t *testing.T) {
+	s, err := NewServer(":0", stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		w.WriteHeader(stdhttp.StatusAccepted)
+		if _, err := io.ReadAll(r.Body); err != nil {
+			_, _ = io.WriteString(w, "too late")
+		}
+	}), WithMaxRequestBodySize(1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := httptest.NewRequest(stdhttp.MethodPost, "/", strings.NewReader("ab"))
+	req.ContentLength = -1
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+	if rec.Code != stdhttp.StatusAccepted || rec.Body.String() != "too late" {
+		t.Errorf("expected the committed response to be left alone, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	if _, err := NewServer(":0", stdhttp.NotFoundHandler(), WithMaxRequestBodySize(-1)); err == nil {
+		t.Error("expected an error for a negative limit")
+	}
+}
+
+func TestWithMaxRequestBodySize_Unlimited(t *testing.T) {
+	body := strings.Repeat("a", 1<<20)
+	s, err := NewServer(":0", stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil || len(b) != len(body) {
+			w.WriteHeader(stdhttp.StatusBadRequest)
+		}
+	}), WithMaxRequestBodySize(0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := httptest.NewRequest(stdhttp.MethodPost, "/", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+	if rec.Code != stdhttp.StatusOK {
+		t.Errorf("expected a zero limit to be unlimited, got %d", rec.Code)
+	}
+}
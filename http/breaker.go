@@ -0,0 +1,371 @@
+package http
+
+import (
+	"context"
+	"errors"
+	stdhttp "net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// ErrCircuitOpen is returned for requests to a host whose circuit breaker is open, without a connection
+// being attempted.
+var ErrCircuitOpen = errors.New("http: circuit breaker is open")
+
+// Defaults for WithCircuitBreaker.
+const (
+	defaultBreakerFailureRatio = 0.5
+	defaultBreakerWindow       = 20
+	defaultBreakerCooldown     = 5 * time.Second
+	defaultBreakerProbes       = 1
+)
+
+// breakerIdleTimeout is how long a closed breaker must go unused before it is discarded, so that memory is
+// only held for hosts the client still talks to.
+const breakerIdleTimeout = time.Minute
+
+// circuitState is the state of a circuit breaker, as reported by the http.client.circuit.state gauge.
+type circuitState int64
+
+const (
+	circuitClosed circuitState = iota
+	circuitHalfOpen
+	circuitOpen
+)
+
+// BreakerOption configures the circuit breaker enabled by WithCircuitBreaker.
+type BreakerOption func(*breakerTransport) error
+
+// WithBreakerFailureRatio sets the ratio of failed requests within the window (by default 0.5) above which the
+// breaker opens.
+func WithBreakerFailureRatio(ratio float64) BreakerOption {
+	return func(bt *breakerTransport) error {
+		if ratio <= 0 || ratio >= 1 {
+			return errors.New("breaker failure ratio must be between 0 and 1")
+		}
+		bt.failureRatio = ratio
+		return nil
+	}
+}
+
+// WithBreakerWindow sets the number of most recent requests (by default 20) over which the failure ratio is
+// measured. The breaker does not open until the window is full.
+func WithBreakerWindow(size int) BreakerOption {
+	return func(bt *breakerTransport) error {
+		if size < 1 {
+			return errors.New("breaker window must be at least 1")
+		}
+		bt.window = size
+		return nil
+	}
+}
+
+// WithBreakerCooldown sets how long the breaker stays open (by default 5s) before allowing probe requests.
+func WithBreakerCooldown(d time.Duration) BreakerOption {
+	return func(bt *breakerTransport) error {
+		if d <= 0 {
+			return errors.New("breaker cooldown must be positive")
+		}
+		bt.cooldown = d
+		return nil
+	}
+}
+
+// WithBreakerProbes sets the number of probe requests (by default 1) allowed while the breaker is half-open.
+// The breaker closes once they all succeed, and opens again if any fails.
+func WithBreakerProbes(n int) BreakerOption {
+	return func(bt *breakerTransport) error {
+		if n < 1 {
+			return errors.New("breaker probes must be at least 1")
+		}
+		bt.probes = n
+		return nil
+	}
+}
+
+// WithCircuitBreaker adds a circuit breaker for each host (req.URL.Host). A request fails if it returns a
+// transport error or a 5xx response. When the ratio of failures among the most recent requests exceeds the
+// threshold, the breaker opens, and requests to that host fail immediately with ErrCircuitOpen. After a
+// cooldown it becomes half-open and lets a limited number of probe requests through, which decide whether it
+// closes again or reopens.
+//
+// Requests whose own context is cancelled or times out are not counted either way, as the caller gave up
+// rather than the host failing; a host that hangs is still caught by the transport's timeouts (such as
+// WithResponseHeaderTimeout), which fail the request with its context intact. Closed breakers that have been
+// idle for a minute are discarded.
+//
+// The breaker sees each attempt, beneath any retries, and requests rejected by an open breaker are not
+// retried. The state of each breaker is reported by the http.client.circuit.state gauge (0 for closed, 1 for
+// half-open and 2 for open) with the host as server.address.
+func WithCircuitBreaker(opts ...BreakerOption) ClientOption {
+	return func(c *stdhttp.Client) error {
+		bt := &breakerTransport{
+			failureRatio: defaultBreakerFailureRatio,
+			window:       defaultBreakerWindow,
+			cooldown:     defaultBreakerCooldown,
+			probes:       defaultBreakerProbes,
+			breakers:     make(map[string]*breaker),
+			now:          time.Now,
+		}
+		for _, opt := range opts {
+			if err := opt(bt); err != nil {
+				return err
+			}
+		}
+
+		wrapInnermost(c, func(base stdhttp.RoundTripper) stdhttp.RoundTripper {
+			bt.base = base
+			return bt
+		})
+		return nil
+	}
+}
+
+// breakerTransport is a RoundTripper that keeps a circuit breaker for each host.
+type breakerTransport struct {
+	base         stdhttp.RoundTripper
+	failureRatio float64
+	window       int
+	cooldown     time.Duration
+	probes       int
+
+	// now returns the current time, and is replaced in tests.
+	now func() time.Time
+
+	mu        sync.Mutex
+	breakers  map[string]*breaker
+	lastSweep time.Time
+
+	// registration is the gauge callback, replaced whenever the transport is instrumented again.
+	registration metric.Registration
+}
+
+func (t *breakerTransport) unwrap() stdhttp.RoundTripper {
+	return t.base
+}
+
+func (t *breakerTransport) setBase(base stdhttp.RoundTripper) {
+	t.base = base
+}
+
+func (t *breakerTransport) resilience() {}
+
+func (t *breakerTransport) instrument(meter metric.Meter) error {
+	state, err := meter.Int64ObservableGauge("http.client.circuit.state")
+	if err != nil {
+		return err
+	}
+	if t.registration != nil {
+		if err := t.registration.Unregister(); err != nil {
+			return err
+		}
+	}
+	t.registration, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		for host, b := range t.breakers {
+			o.ObserveInt64(state, int64(b.observe(t.now())), metric.WithAttributes(hostAttrs(host)...))
+		}
+		return nil
+	}, state)
+	return err
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *breakerTransport) RoundTrip(req *stdhttp.Request) (*stdhttp.Response, error) {
+	now := t.now()
+	b := t.breaker(req.URL.Host, now)
+	probe, ok := b.allow(now)
+	if !ok {
+		if lr := logicalRequestFromContext(req.Context()); lr != nil {
+			lr.shortCircuited.Store(true)
+		}
+		return nil, ErrCircuitOpen
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if req.Context().Err() != nil {
+		b.release(t.now(), probe)
+		return resp, err
+	}
+	b.record(t.now(), probe, err == nil && resp.StatusCode < 500)
+	return resp, err
+}
+
+// breaker returns the breaker for host, creating it if needed.
+func (t *breakerTransport) breaker(host string, now time.Time) *breaker {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sweep(now)
+	b, ok := t.breakers[host]
+	if !ok {
+		b = &breaker{
+			failureRatio: t.failureRatio,
+			cooldown:     t.cooldown,
+			probes:       t.probes,
+			outcomes:     make([]bool, t.window),
+		}
+		t.breakers[host] = b
+	}
+	return b
+}
+
+// sweep discards the breakers that are closed and have been idle for breakerIdleTimeout, at most once per
+// breakerIdleTimeout. A closed breaker's window only holds recent outcomes, so little is lost. The caller must
+// hold t.mu.
+func (t *breakerTransport) sweep(now time.Time) {
+	if now.Sub(t.lastSweep) < breakerIdleTimeout {
+		return
+	}
+	t.lastSweep = now
+	for host, b := range t.breakers {
+		if b.idle(now) {
+			delete(t.breakers, host)
+		}
+	}
+}
+
+// hostAttrs describes a host (as in URL.Host) as server.address and, if it has one, server.port.
+func hostAttrs(host string) []attribute.KeyValue {
+	u := &url.URL{Host: host}
+	attrs := []attribute.KeyValue{semconv.ServerAddress(u.Hostname())}
+	if port, err := strconv.Atoi(u.Port()); err == nil {
+		attrs = append(attrs, semconv.ServerPort(port))
+	}
+	return attrs
+}
+
+// breaker is the circuit breaker for a single host.
+type breaker struct {
+	failureRatio float64
+	cooldown     time.Duration
+	probes       int
+
+	mu    sync.Mutex
+	state circuitState
+
+	// outcomes is a ring buffer of whether the most recent requests succeeded, of which next is the oldest
+	// and count are filled. failures counts the failed requests within it.
+	outcomes []bool
+	next     int
+	count    int
+	failures int
+
+	// openedAt is when the breaker last opened.
+	openedAt time.Time
+
+	// probing and probed count the probes in flight and the probes that succeeded while half-open.
+	probing int
+	probed  int
+
+	// lastUsed is when a request was last allowed or recorded.
+	lastUsed time.Time
+}
+
+// idle reports whether the breaker is closed and has gone unused for breakerIdleTimeout at now.
+func (b *breaker) idle(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.currentState(now) == circuitClosed && now.Sub(b.lastUsed) >= breakerIdleTimeout
+}
+
+// observe returns the breaker's state at now.
+func (b *breaker) observe(now time.Time) circuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.currentState(now)
+}
+
+// currentState returns the breaker's state at now, moving from open to half-open once the cooldown passes.
+// The caller must hold b.mu.
+func (b *breaker) currentState(now time.Time) circuitState {
+	if b.state == circuitOpen && now.Sub(b.openedAt) >= b.cooldown {
+		b.state, b.probing, b.probed = circuitHalfOpen, 0, 0
+	}
+	return b.state
+}
+
+// allow reports whether a request may be made, and whether it is a probe.
+func (b *breaker) allow(now time.Time) (probe, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastUsed = now
+	switch b.currentState(now) {
+	case circuitOpen:
+		return false, false
+	case circuitHalfOpen:
+		if b.probing+b.probed >= b.probes {
+			return false, false
+		}
+		b.probing++
+		return true, true
+	}
+	return false, true
+}
+
+// record records the outcome of a request allowed by allow.
+func (b *breaker) record(now time.Time, probe, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastUsed = now
+
+	if probe {
+		b.probing--
+		if b.state != circuitHalfOpen {
+			return
+		}
+		if !success {
+			b.open(now)
+			return
+		}
+		if b.probed++; b.probed >= b.probes {
+			b.state = circuitClosed
+			b.reset()
+		}
+		return
+	}
+
+	// Requests allowed before the breaker opened may complete after it, but no longer count.
+	if b.state != circuitClosed {
+		return
+	}
+	if b.count == len(b.outcomes) && !b.outcomes[b.next] {
+		b.failures--
+	}
+	b.outcomes[b.next] = success
+	b.next = (b.next + 1) % len(b.outcomes)
+	b.count = min(b.count+1, len(b.outcomes))
+	if !success {
+		b.failures++
+	}
+	if b.count == len(b.outcomes) && float64(b.failures)/float64(b.count) > b.failureRatio {
+		b.open(now)
+	}
+}
+
+// release records that a request allowed by allow ended without an outcome, freeing its probe, if it was one.
+func (b *breaker) release(now time.Time, probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastUsed = now
+	if probe {
+		b.probing--
+	}
+}
+
+// open opens the breaker at now. The caller must hold b.mu.
+func (b *breaker) open(now time.Time) {
+	b.state, b.openedAt = circuitOpen, now
+	b.reset()
+}
+
+// reset empties the window. The caller must hold b.mu.
+func (b *breaker) reset() {
+	b.next, b.count, b.failures = 0, 0, 0
+}
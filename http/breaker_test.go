@@ -0,0 +1,228 @@
+package http
+
+import (
+	"context"
+	"errors"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// observedCircuitState returns the value of the http.client.circuit.state gauge for the only host observed.
+func observedCircuitState(t *testing.T, reader *sdkmetric.ManualReader) int64 {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if g, ok := m.Data.(metricdata.Gauge[int64]); ok && m.Name == "http.client.circuit.state" {
+				if len(g.DataPoints) != 1 {
+					t.Fatalf("expected 1 host, got %d", len(g.DataPoints))
+				}
+				if _, ok := g.DataPoints[0].Attributes.Value(semconv.ServerAddressKey); !ok {
+					t.Error("expected server.address to be set")
+				}
+				return g.DataPoints[0].Value
+			}
+		}
+	}
+	t.Fatal("expected the circuit state to be observed")
+	return 0
+}
+
+func TestWithCircuitBreaker(t *testing.T) {
+	var calls atomic.Int32
+	var healthy atomic.Bool
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		calls.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(stdhttp.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	c, err := NewClient(
+		WithClientMeterProvider(mp),
+		WithRetry(3),
+		WithCircuitBreaker(WithBreakerWindow(2), WithBreakerCooldown(50*time.Millisecond)),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The first request fails twice (the second time on a retry), which fills the window and opens the
+	// breaker, so its final retry is rejected.
+	_, err = c.Get(ts.URL)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("expected 2 requests to reach the server, got %d", got)
+	}
+	if got := observedCircuitState(t, reader); got != int64(circuitOpen) {
+		t.Errorf("expected the circuit to be open, got %d", got)
+	}
+
+	// After the cooldown, a successful probe closes the breaker.
+	healthy.Store(true)
+	time.Sleep(60 * time.Millisecond)
+	if got := observedCircuitState(t, reader); got != int64(circuitHalfOpen) {
+		t.Errorf("expected the circuit to be half-open, got %d", got)
+	}
+	resp, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+	if got := observedCircuitState(t, reader); got != int64(circuitClosed) {
+		t.Errorf("expected the circuit to be closed, got %d", got)
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	now := time.Now()
+	b := &breaker{failureRatio: 0.5, cooldown: time.Second, probes: 2, outcomes: make([]bool, 4)}
+
+	// The window must be full before the breaker can open.
+	for _, success := range []bool{true, false, false} {
+		b.record(now, false, success)
+	}
+	if b.state != circuitClosed {
+		t.Fatal("expected the breaker to stay closed until the window is full")
+	}
+
+	// Old outcomes slide out of the window, so the breaker opens only once 3 of the last 4 requests fail.
+	for _, success := range []bool{true, true, true, false, false} {
+		b.record(now, false, success)
+		if b.state != circuitClosed {
+			t.Fatal("expected the breaker to stay closed at or below the failure ratio")
+		}
+	}
+	b.record(now, false, false)
+	if b.state != circuitOpen {
+		t.Fatal("expected the breaker to open above the failure ratio")
+	}
+	if _, ok := b.allow(now.Add(time.Millisecond)); ok {
+		t.Error("expected an open breaker to reject requests")
+	}
+
+	// Once half-open, only the configured number of probes are allowed, and a failed one reopens it.
+	now = now.Add(time.Second)
+	for range 2 {
+		if probe, ok := b.allow(now); !ok || !probe {
+			t.Fatal("expected a probe to be allowed")
+		}
+	}
+	if _, ok := b.allow(now); ok {
+		t.Error("expected probes beyond the limit to be rejected")
+	}
+	b.record(now, true, true)
+	b.record(now, true, false)
+	if b.state != circuitOpen {
+		t.Fatal("expected a failed probe to reopen the breaker")
+	}
+
+	now = now.Add(time.Second)
+	for range 2 {
+		probe, _ := b.allow(now)
+		b.record(now, probe, true)
+	}
+	if b.state != circuitClosed {
+		t.Error("expected successful probes to close the breaker")
+	}
+}
+
+func TestWithCircuitBreaker_CallerCancellation(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		calls.Add(1)
+		if r.URL.Query().Has("slow") {
+			<-r.Context().Done()
+		}
+	}))
+	defer ts.Close()
+
+	c, err := NewClient(WithCircuitBreaker(WithBreakerWindow(1), WithBreakerCooldown(time.Hour)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Callers giving up, by cancelling or with a deadline, don't count as failures of the host.
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	req, _ := stdhttp.NewRequestWithContext(ctx, stdhttp.MethodGet, ts.URL+"?slow", nil)
+	if _, err := c.Do(req); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, _ = stdhttp.NewRequestWithContext(ctx, stdhttp.MethodGet, ts.URL+"?slow", nil)
+	if _, err := c.Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	resp, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("expected the breaker to stay closed, got %v", err)
+	}
+	_ = resp.Body.Close()
+	if got := calls.Load(); got != 3 {
+		t.Errorf("expected 3 requests to reach the server, got %d", got)
+	}
+}
+
+func TestBreakerTransport_Eviction(t *testing.T) {
+	now := time.Now()
+	bt := &breakerTransport{
+		failureRatio: defaultBreakerFailureRatio,
+		window:       1,
+		cooldown:     time.Hour,
+		probes:       defaultBreakerProbes,
+		breakers:     make(map[string]*breaker),
+		lastSweep:    now,
+	}
+
+	closed := bt.breaker("closed.example", now)
+	closed.allow(now)
+	closed.record(now, false, true)
+	open := bt.breaker("open.example", now)
+	open.allow(now)
+	open.record(now, false, false)
+
+	// Closed breakers are discarded once idle, but open ones are kept so that the host stays blocked.
+	now = now.Add(breakerIdleTimeout)
+	bt.breaker("other.example", now)
+	if _, ok := bt.breakers["closed.example"]; ok {
+		t.Error("expected the idle closed breaker to be discarded")
+	}
+	if _, ok := bt.breakers["open.example"]; !ok {
+		t.Error("expected the open breaker to be kept")
+	}
+	if _, ok := bt.breakers["other.example"]; !ok {
+		t.Error("expected the new breaker to be kept")
+	}
+}
+
+func TestWithCircuitBreaker_InvalidOptions(t *testing.T) {
+	for _, opt := range []BreakerOption{
+		WithBreakerFailureRatio(0),
+		WithBreakerFailureRatio(1),
+		WithBreakerWindow(0),
+		WithBreakerCooldown(0),
+		WithBreakerProbes(0),
+	} {
+		if _, err := NewClient(WithCircuitBreaker(opt)); err == nil {
+			t.Error("expected an error for an invalid option")
+		}
+	}
+}
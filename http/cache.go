@@ -0,0 +1,539 @@
+package http
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"errors"
+	"io"
+	stdhttp "net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Cache statuses, recorded as http.cache.status.
+const (
+	cacheStatusHit         = "hit"
+	cacheStatusMiss        = "miss"
+	cacheStatusStale       = "stale"
+	cacheStatusRevalidated = "revalidated"
+)
+
+// Outcomes of asynchronous revalidations, recorded as http.cache.revalidation.outcome.
+const (
+	revalidationNotModified = "not_modified"
+	revalidationUpdated     = "updated"
+	revalidationError       = "error"
+)
+
+// maxCachedBodySize caps the size of the response bodies that are cached. Larger responses are passed through.
+const maxCachedBodySize = 1 << 20
+
+// ResponseCache is an in-memory, private HTTP cache for GET requests, following a subset of RFC 9111:
+//   - Responses are stored when they have an explicit Cache-Control max-age, and are not marked no-store or
+//     no-cache. Responses that Vary are stored for the request headers they were made with.
+//   - Fresh responses are served from the cache without contacting the server.
+//   - Stale responses are revalidated with a conditional request (If-None-Match or If-Modified-Since).
+//   - Within the response's stale-while-revalidate window (RFC 5861), a stale response is served
+//     immediately while it is revalidated in the background.
+//   - Within the response's stale-if-error window, a stale response is served if revalidating it fails
+//     with an error or a 500, 502, 503 or 504 response.
+//
+// Requests with a Cache-Control no-store or no-cache directive, or with their own conditional or Range
+// headers, bypass the cache. Responses to requests with an Authorization header are only stored if they are
+// marked public or have an s-maxage (RFC 9111, section 3.5), so that one user's response isn't served to
+// another. Credentials added by WithBasicAuth and the like are added beneath the cache and not seen by it, so
+// a cache should only be shared between clients that authenticate as the same user. A cache is safe for
+// concurrent use.
+type ResponseCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element
+	lru        *list.List
+
+	// revalidating holds the keys being revalidated in the background, so that each is revalidated once.
+	revalidating map[string]bool
+
+	// ctx is cancelled by Close, aborting background revalidations, which are tracked by background.
+	ctx        context.Context
+	cancel     context.CancelFunc
+	background sync.WaitGroup
+	closed     bool
+}
+
+// NewResponseCache returns a ResponseCache holding up to maxEntries responses, evicting the least recently
+// used response when it is full.
+func NewResponseCache(maxEntries int) (*ResponseCache, error) {
+	if maxEntries < 1 {
+		return nil, errors.New("response cache max entries must be at least 1")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ResponseCache{
+		maxEntries:   maxEntries,
+		entries:      make(map[string]*list.Element),
+		lru:          list.New(),
+		revalidating: make(map[string]bool),
+		ctx:          ctx,
+		cancel:       cancel,
+	}, nil
+}
+
+// Close aborts any background revalidations and waits for them to finish, or for ctx to be done. Once
+// closed, the cache keeps serving stored responses but no longer revalidates in the background.
+func (c *ResponseCache) Close(ctx context.Context) error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+	c.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		c.background.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// cacheEntry is a stored response.
+type cacheEntry struct {
+	key        string
+	statusCode int
+	header     stdhttp.Header
+	body       []byte
+
+	// vary holds the values of the request headers the response varies on, keyed by canonical name.
+	vary map[string]string
+
+	// storedAt is when the response was received, and initialAge its age at the time (from Age).
+	storedAt   time.Time
+	initialAge time.Duration
+
+	maxAge               time.Duration
+	staleWhileRevalidate time.Duration
+	staleIfError         time.Duration
+}
+
+// age returns the current age of the stored response.
+func (e *cacheEntry) age(now time.Time) time.Duration {
+	return e.initialAge + now.Sub(e.storedAt)
+}
+
+// matches reports whether the stored response can be used for req, given the headers it varies on.
+func (e *cacheEntry) matches(req *stdhttp.Request) bool {
+	for name, value := range e.vary {
+		if req.Header.Get(name) != value {
+			return false
+		}
+	}
+	return true
+}
+
+// response builds a response to req from the stored response.
+func (e *cacheEntry) response(req *stdhttp.Request, now time.Time) *stdhttp.Response {
+	header := e.header.Clone()
+	header.Set("Age", strconv.Itoa(int(e.age(now).Seconds())))
+	return &stdhttp.Response{
+		Status:        strconv.Itoa(e.statusCode) + " " + stdhttp.StatusText(e.statusCode),
+		StatusCode:    e.statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}
+}
+
+// get returns the stored response for key, if there is one that can be used for req.
+func (c *ResponseCache) get(key string, req *stdhttp.Request) *cacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	e := el.Value.(*cacheEntry)
+	if !e.matches(req) {
+		return nil
+	}
+	c.lru.MoveToFront(el)
+	return e
+}
+
+// put stores e, evicting the least recently used response if the cache is full.
+func (c *ResponseCache) put(e *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[e.key]; ok {
+		el.Value = e
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[e.key] = c.lru.PushFront(e)
+	if c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// startRevalidation reports whether a background revalidation of key should start, marking it as started.
+func (c *ResponseCache) startRevalidation(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed || c.revalidating[key] {
+		return false
+	}
+	c.revalidating[key] = true
+	c.background.Add(1)
+	return true
+}
+
+// endRevalidation marks a background revalidation of key as finished.
+func (c *ResponseCache) endRevalidation(key string) {
+	c.mu.Lock()
+	delete(c.revalidating, key)
+	c.mu.Unlock()
+	c.background.Done()
+}
+
+// WithResponseCache caches responses in cache, as described by ResponseCache. The cache status of each
+// request (hit, miss, stale or revalidated) is recorded as http.cache.status on the span and on the
+// http.client.cache.requests counter, and the outcome of each background revalidation (not_modified,
+// updated or error) on the http.client.cache.revalidations counter.
+//
+// Background revalidations are sent through the client's instrumented transport, so they are measured like
+// any other request. They are not bound to the request that triggered them; call cache.Close on shutdown to
+// abort any that are in flight.
+func WithResponseCache(cache *ResponseCache) ClientOption {
+	return func(c *stdhttp.Client) error {
+		if cache == nil {
+			return errors.New("response cache must not be nil")
+		}
+		it, ok := c.Transport.(*InstrumentedTransport)
+		if !ok {
+			return errors.New("client transport must be *InstrumentedTransport")
+		}
+
+		ct := &cacheTransport{cache: cache, revalidator: it, now: time.Now}
+		wrapTransport(c, func(base stdhttp.RoundTripper) stdhttp.RoundTripper {
+			ct.base = base
+			return ct
+		})
+		return nil
+	}
+}
+
+// cacheBypassKey is the context key marking background revalidations, which must not be served from the cache.
+type cacheBypassKey struct{}
+
+// cacheTransport is a RoundTripper that serves responses from a ResponseCache.
+type cacheTransport struct {
+	base  stdhttp.RoundTripper
+	cache *ResponseCache
+
+	// revalidator sends background revalidations through the whole instrumented transport.
+	revalidator stdhttp.RoundTripper
+	now         func() time.Time
+
+	mRequests      metric.Int64Counter
+	mRevalidations metric.Int64Counter
+}
+
+func (t *cacheTransport) unwrap() stdhttp.RoundTripper {
+	return t.base
+}
+
+func (t *cacheTransport) setBase(base stdhttp.RoundTripper) {
+	t.base = base
+}
+
+func (t *cacheTransport) instrument(meter metric.Meter) error {
+	var err error
+	t.mRequests, err = meter.Int64Counter("http.client.cache.requests")
+	if err != nil {
+		return err
+	}
+	t.mRevalidations, err = meter.Int64Counter("http.client.cache.revalidations")
+	return err
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *cacheTransport) RoundTrip(req *stdhttp.Request) (*stdhttp.Response, error) {
+	if !cacheable(req) {
+		return t.base.RoundTrip(req)
+	}
+
+	key := req.URL.String()
+	e := t.cache.get(key, req)
+	if e == nil {
+		t.record(req, cacheStatusMiss)
+		resp, err := t.base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		return t.store(key, req, resp), nil
+	}
+
+	now := t.now()
+	age := e.age(now)
+	switch {
+	case age < e.maxAge:
+		t.record(req, cacheStatusHit)
+		return e.response(req, now), nil
+	case age < e.maxAge+e.staleWhileRevalidate:
+		t.record(req, cacheStatusStale)
+		t.revalidateAsync(key, req, e)
+		return e.response(req, now), nil
+	}
+
+	resp, err := t.base.RoundTrip(conditionalRequest(req.Context(), req, e))
+	if (err != nil || isServerFailure(resp.StatusCode)) && age < e.maxAge+e.staleIfError {
+		if resp != nil {
+			drainAndClose(resp)
+		}
+		t.record(req, cacheStatusStale)
+		return e.response(req, now), nil
+	}
+	if err != nil {
+		t.record(req, cacheStatusMiss)
+		return nil, err
+	}
+	if resp.StatusCode == stdhttp.StatusNotModified {
+		drainAndClose(resp)
+		t.record(req, cacheStatusRevalidated)
+		return t.refresh(e, resp).response(req, t.now()), nil
+	}
+	t.record(req, cacheStatusMiss)
+	return t.store(key, req, resp), nil
+}
+
+// revalidateAsync revalidates e in the background, unless it is already being revalidated.
+func (t *cacheTransport) revalidateAsync(key string, req *stdhttp.Request, e *cacheEntry) {
+	if !t.cache.startRevalidation(key) {
+		return
+	}
+	ctx := context.WithValue(t.cache.ctx, cacheBypassKey{}, true)
+	rreq := conditionalRequest(ctx, req, e)
+
+	go func() {
+		defer t.cache.endRevalidation(key)
+
+		outcome := revalidationError
+		resp, err := t.revalidator.RoundTrip(rreq)
+		switch {
+		case err != nil:
+		case resp.StatusCode == stdhttp.StatusNotModified:
+			drainAndClose(resp)
+			t.refresh(e, resp)
+			outcome = revalidationNotModified
+		case isServerFailure(resp.StatusCode):
+			drainAndClose(resp)
+		default:
+			resp = t.store(key, rreq, resp)
+			drainAndClose(resp)
+			outcome = revalidationUpdated
+		}
+		if t.mRevalidations != nil {
+			t.mRevalidations.Add(ctx, 1, metric.WithAttributes(
+				attribute.String("http.cache.revalidation.outcome", outcome),
+			))
+		}
+	}()
+}
+
+// record records the cache status of req on its span and the requests counter.
+func (t *cacheTransport) record(req *stdhttp.Request, status string) {
+	attr := attribute.String("http.cache.status", status)
+	trace.SpanFromContext(req.Context()).SetAttributes(attr)
+	if t.mRequests != nil {
+		t.mRequests.Add(req.Context(), 1, metric.WithAttributes(attr))
+	}
+}
+
+// store caches resp if it is storable, returning a response with an equivalent body for the caller.
+func (t *cacheTransport) store(key string, req *stdhttp.Request, resp *stdhttp.Response) *stdhttp.Response {
+	e := newCacheEntry(key, req, resp, t.now())
+	if e == nil {
+		return resp
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCachedBodySize+1))
+	if err != nil || len(body) > maxCachedBodySize {
+		resp.Body = &struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), errReader{err}, resp.Body), resp.Body}
+		return resp
+	}
+	_ = resp.Body.Close()
+
+	e.body = body
+	t.cache.put(e)
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp
+}
+
+// refresh updates the stored response e with the headers of a 304 Not Modified response, returning the
+// updated entry.
+func (t *cacheTransport) refresh(e *cacheEntry, notModified *stdhttp.Response) *cacheEntry {
+	header := e.header.Clone()
+	for name, values := range notModified.Header {
+		header[name] = values
+	}
+
+	refreshed := *e
+	refreshed.header = header
+	refreshed.storedAt = t.now()
+	refreshed.initialAge = ageHeader(header)
+	cc := parseCacheControl(header)
+	refreshed.maxAge, _ = cc.duration("max-age")
+	refreshed.staleWhileRevalidate, _ = cc.duration("stale-while-revalidate")
+	refreshed.staleIfError, _ = cc.duration("stale-if-error")
+	t.cache.put(&refreshed)
+	return &refreshed
+}
+
+// newCacheEntry returns an entry for resp without its body, or nil if resp may not be stored.
+func newCacheEntry(key string, req *stdhttp.Request, resp *stdhttp.Response, now time.Time) *cacheEntry {
+	switch resp.StatusCode {
+	case stdhttp.StatusOK, stdhttp.StatusNonAuthoritativeInfo, stdhttp.StatusNoContent,
+		stdhttp.StatusMovedPermanently, stdhttp.StatusPermanentRedirect, stdhttp.StatusNotFound,
+		stdhttp.StatusGone:
+	default:
+		return nil
+	}
+
+	cc := parseCacheControl(resp.Header)
+	if cc.has("no-store") || cc.has("no-cache") {
+		return nil
+	}
+	if req.Header.Get("Authorization") != "" && !cc.has("public") && !cc.has("s-maxage") {
+		return nil
+	}
+	maxAge, ok := cc.duration("max-age")
+	if !ok {
+		return nil
+	}
+
+	vary := make(map[string]string)
+	for _, v := range resp.Header.Values("Vary") {
+		for name := range strings.SplitSeq(v, ",") {
+			name = strings.TrimSpace(name)
+			if name == "*" {
+				return nil
+			}
+			if name != "" {
+				vary[stdhttp.CanonicalHeaderKey(name)] = req.Header.Get(name)
+			}
+		}
+	}
+
+	e := &cacheEntry{
+		key:        key,
+		statusCode: resp.StatusCode,
+		header:     resp.Header.Clone(),
+		vary:       vary,
+		storedAt:   now,
+		initialAge: ageHeader(resp.Header),
+		maxAge:     maxAge,
+	}
+	e.staleWhileRevalidate, _ = cc.duration("stale-while-revalidate")
+	e.staleIfError, _ = cc.duration("stale-if-error")
+	return e
+}
+
+// cacheable reports whether req may be served from the cache.
+func cacheable(req *stdhttp.Request) bool {
+	if req.Method != stdhttp.MethodGet || req.Context().Value(cacheBypassKey{}) != nil {
+		return false
+	}
+	for _, name := range []string{"If-None-Match", "If-Modified-Since", "If-Match", "If-Unmodified-Since", "Range"} {
+		if req.Header.Get(name) != "" {
+			return false
+		}
+	}
+	cc := parseCacheControl(req.Header)
+	return !cc.has("no-store") && !cc.has("no-cache")
+}
+
+// conditionalRequest returns a copy of req, with ctx, that revalidates the stored response e.
+func conditionalRequest(ctx context.Context, req *stdhttp.Request, e *cacheEntry) *stdhttp.Request {
+	creq := req.Clone(ctx)
+	if etag := e.header.Get("ETag"); etag != "" {
+		creq.Header.Set("If-None-Match", etag)
+	}
+	if lastModified := e.header.Get("Last-Modified"); lastModified != "" {
+		creq.Header.Set("If-Modified-Since", lastModified)
+	}
+	return creq
+}
+
+// isServerFailure reports whether the status code allows a stale response to be served under stale-if-error.
+func isServerFailure(code int) bool {
+	switch code {
+	case stdhttp.StatusInternalServerError, stdhttp.StatusBadGateway, stdhttp.StatusServiceUnavailable,
+		stdhttp.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// ageHeader returns the value of the Age header, or zero if it is missing or invalid.
+func ageHeader(h stdhttp.Header) time.Duration {
+	age, _ := deltaSeconds(h.Get("Age"))
+	return age
+}
+
+// maxDeltaSeconds is the largest delta-seconds value, to which larger values are clamped, as RFC 9111
+// (section 1.2.2) requires; it also keeps the duration from overflowing.
+const maxDeltaSeconds = 1 << 31
+
+// deltaSeconds parses a delta-seconds value (a non-negative whole number of seconds), and reports whether it
+// is valid. Values too large to represent are clamped to maxDeltaSeconds rather than rejected.
+func deltaSeconds(v string) (time.Duration, bool) {
+	seconds, err := strconv.ParseUint(v, 10, 64)
+	if err != nil && !errors.Is(err, strconv.ErrRange) {
+		return 0, false
+	}
+	return time.Duration(min(seconds, maxDeltaSeconds)) * time.Second, true
+}
+
+// cacheControl holds the directives of a Cache-Control header, keyed by lowercase name.
+type cacheControl map[string]string
+
+// parseCacheControl parses the Cache-Control directives in h.
+func parseCacheControl(h stdhttp.Header) cacheControl {
+	cc := make(cacheControl)
+	for _, v := range h.Values("Cache-Control") {
+		for directive := range strings.SplitSeq(v, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name != "" {
+				cc[strings.ToLower(name)] = strings.Trim(value, `"`)
+			}
+		}
+	}
+	return cc
+}
+
+func (cc cacheControl) has(name string) bool {
+	_, ok := cc[name]
+	return ok
+}
+
+// duration returns the value of a delta-seconds directive, and whether it is present and valid.
+func (cc cacheControl) duration(name string) (time.Duration, bool) {
+	return deltaSeconds(cc[name])
+}
@@ -0,0 +1,411 @@
+package http
+
+import (
+	"context"
+	"io"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// newCacheTestClient returns a client caching in a new cache, and a function that advances its clock.
+func newCacheTestClient(t *testing.T, opts ...ClientOption) (*stdhttp.Client, *ResponseCache, func(time.Duration)) {
+	t.Helper()
+	cache, err := NewResponseCache(10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = cache.Close(context.Background()) })
+
+	c, err := NewClient(append(opts, WithResponseCache(cache))...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ct := c.Transport.(*InstrumentedTransport).Base.(*cacheTransport)
+	var offset atomic.Int64
+	ct.now = func() time.Time { return time.Now().Add(time.Duration(offset.Load())) }
+	return c, cache, func(d time.Duration) { offset.Add(int64(d)) }
+}
+
+func getBody(t *testing.T, c *stdhttp.Client, url string) (int, string) {
+	t.Helper()
+	resp, err := c.Get(url)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func sumCounterWithAttr(t *testing.T, reader sdkmetric.Reader, name string, attr attribute.KeyValue) int64 {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	var total int64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if sum, ok := m.Data.(metricdata.Sum[int64]); ok && m.Name == name {
+				for _, dp := range sum.DataPoints {
+					if hasAttr(dp.Attributes.ToSlice(), attr) {
+						total += dp.Value
+					}
+				}
+			}
+		}
+	}
+	return total
+}
+
+func TestWithResponseCache_Fresh(t *testing.T) {
+	var hits atomic.Int64
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		hits.Add(1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = io.WriteString(w, "cached")
+	}))
+	defer ts.Close()
+
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	c, _, _ := newCacheTestClient(t, WithClientMeterProvider(mp))
+
+	for range 2 {
+		if code, body := getBody(t, c, ts.URL); code != stdhttp.StatusOK || body != "cached" {
+			t.Errorf("unexpected response: %d %q", code, body)
+		}
+	}
+	if got := hits.Load(); got != 1 {
+		t.Errorf("expected the second request to be served from the cache, got %d server hits", got)
+	}
+	if got := sumCounterWithAttr(t, reader, "http.client.cache.requests",
+		attribute.String("http.cache.status", "hit")); got != 1 {
+		t.Errorf("expected 1 cache hit, got %d", got)
+	}
+}
+
+func TestWithResponseCache_NotStored(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		vary   string
+	}{
+		{name: "no-store", header: "max-age=60, no-store"},
+		{name: "no-cache", header: "max-age=60, no-cache"},
+		{name: "no max-age", header: "public"},
+		{name: "vary all", header: "max-age=60", vary: "*"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits atomic.Int64
+			ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+				hits.Add(1)
+				w.Header().Set("Cache-Control", tt.header)
+				if tt.vary != "" {
+					w.Header().Set("Vary", tt.vary)
+				}
+			}))
+			defer ts.Close()
+
+			c, _, _ := newCacheTestClient(t)
+			for range 2 {
+				getBody(t, c, ts.URL)
+			}
+			if got := hits.Load(); got != 2 {
+				t.Errorf("expected the response not to be cached, got %d server hits", got)
+			}
+		})
+	}
+}
+
+func TestWithResponseCache_Authorization(t *testing.T) {
+	var hits atomic.Int64
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		hits.Add(1)
+		w.Header().Set("Cache-Control", r.URL.Query().Get("cc"))
+		_, _ = io.WriteString(w, r.Header.Get("Authorization"))
+	}))
+	defer ts.Close()
+	c, _, _ := newCacheTestClient(t)
+
+	get := func(url, authorization string) string {
+		t.Helper()
+		req, _ := stdhttp.NewRequest(stdhttp.MethodGet, url, nil)
+		req.Header.Set("Authorization", authorization)
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	// A response for one user isn't served to another.
+	private := ts.URL + "?cc=max-age%3D60"
+	if got := get(private, "Bearer alice"); got != "Bearer alice" {
+		t.Errorf("expected alice's response, got %q", got)
+	}
+	if got := get(private, "Bearer bob"); got != "Bearer bob" {
+		t.Errorf("expected bob's response, got %q", got)
+	}
+	if hits.Load() != 2 {
+		t.Errorf("expected both requests to reach the server, got %d", hits.Load())
+	}
+
+	// Unless the response says it may be.
+	hits.Store(0)
+	public := ts.URL + "?cc=public,max-age%3D60"
+	get(public, "Bearer alice")
+	if got := get(public, "Bearer bob"); got != "Bearer alice" {
+		t.Errorf("expected the public response to be served from the cache, got %q", got)
+	}
+	if hits.Load() != 1 {
+		t.Errorf("expected 1 request to reach the server, got %d", hits.Load())
+	}
+}
+
+func TestWithResponseCache_Vary(t *testing.T) {
+	var hits atomic.Int64
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		hits.Add(1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept-Language")
+		_, _ = io.WriteString(w, r.Header.Get("Accept-Language"))
+	}))
+	defer ts.Close()
+
+	c, _, _ := newCacheTestClient(t)
+	for _, lang := range []string{"en", "en", "de"} {
+		req, _ := stdhttp.NewRequest(stdhttp.MethodGet, ts.URL, nil)
+		req.Header.Set("Accept-Language", lang)
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if string(body) != lang {
+			t.Errorf("expected the %q response, got %q", lang, body)
+		}
+	}
+	if got := hits.Load(); got != 2 {
+		t.Errorf("expected 2 server hits, got %d", got)
+	}
+}
+
+func TestWithResponseCache_Revalidate(t *testing.T) {
+	var hits atomic.Int64
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		hits.Add(1)
+		w.Header().Set("Cache-Control", "max-age=1")
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(stdhttp.StatusNotModified)
+			return
+		}
+		_, _ = io.WriteString(w, "v1")
+	}))
+	defer ts.Close()
+
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	c, _, advance := newCacheTestClient(t, WithClientMeterProvider(mp))
+
+	getBody(t, c, ts.URL)
+	advance(2 * time.Second)
+	if code, body := getBody(t, c, ts.URL); code != stdhttp.StatusOK || body != "v1" {
+		t.Errorf("expected the revalidated response, got %d %q", code, body)
+	}
+	if got := sumCounterWithAttr(t, reader, "http.client.cache.requests",
+		attribute.String("http.cache.status", "revalidated")); got != 1 {
+		t.Errorf("expected 1 revalidated request, got %d", got)
+	}
+
+	// The revalidation refreshed the stored response.
+	getBody(t, c, ts.URL)
+	if got := hits.Load(); got != 2 {
+		t.Errorf("expected 2 server hits, got %d", got)
+	}
+}
+
+func TestWithResponseCache_StaleWhileRevalidate(t *testing.T) {
+	var version atomic.Int64
+	version.Store(1)
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		w.Header().Set("Cache-Control", "max-age=1, stale-while-revalidate=60")
+		if version.Load() == 1 {
+			_, _ = io.WriteString(w, "v1")
+			return
+		}
+		_, _ = io.WriteString(w, "v2")
+	}))
+	defer ts.Close()
+
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	c, _, advance := newCacheTestClient(t, WithClientMeterProvider(mp))
+
+	getBody(t, c, ts.URL)
+	version.Store(2)
+	advance(2 * time.Second)
+
+	if _, body := getBody(t, c, ts.URL); body != "v1" {
+		t.Errorf("expected the stale response to be served immediately, got %q", body)
+	}
+	deadline := time.Now().Add(time.Second)
+	for sumCounter(t, reader, "http.client.cache.revalidations") == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if _, body := getBody(t, c, ts.URL); body != "v2" {
+		t.Errorf("expected the background revalidation to have updated the response, got %q", body)
+	}
+
+	if got := sumCounterWithAttr(t, reader, "http.client.cache.requests",
+		attribute.String("http.cache.status", "stale")); got != 1 {
+		t.Errorf("expected 1 stale request, got %d", got)
+	}
+	if got := sumCounterWithAttr(t, reader, "http.client.cache.revalidations",
+		attribute.String("http.cache.revalidation.outcome", "updated")); got != 1 {
+		t.Errorf("expected 1 updating revalidation, got %d", got)
+	}
+
+	// The background revalidation went through the instrumented transport, as well as the three requests.
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	var measured uint64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if h, ok := m.Data.(metricdata.Histogram[float64]); ok && m.Name == "http.client.request.duration" {
+				for _, dp := range h.DataPoints {
+					measured += dp.Count
+				}
+			}
+		}
+	}
+	if measured != 4 {
+		t.Errorf("expected 4 measured requests, got %d", measured)
+	}
+}
+
+func TestWithResponseCache_StaleIfError(t *testing.T) {
+	var failing atomic.Bool
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		if failing.Load() {
+			w.WriteHeader(stdhttp.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Cache-Control", "max-age=1, stale-if-error=60")
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer ts.Close()
+
+	c, _, advance := newCacheTestClient(t)
+	getBody(t, c, ts.URL)
+	failing.Store(true)
+
+	advance(2 * time.Second)
+	if code, body := getBody(t, c, ts.URL); code != stdhttp.StatusOK || body != "ok" {
+		t.Errorf("expected the stale response within stale-if-error, got %d %q", code, body)
+	}
+
+	advance(time.Minute)
+	if code, _ := getBody(t, c, ts.URL); code != stdhttp.StatusServiceUnavailable {
+		t.Errorf("expected the error beyond stale-if-error, got %d", code)
+	}
+}
+
+func TestResponseCache_Eviction(t *testing.T) {
+	cache, err := NewResponseCache(1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	req := httptest.NewRequest(stdhttp.MethodGet, "/", nil)
+	cache.put(&cacheEntry{key: "a"})
+	cache.put(&cacheEntry{key: "b"})
+	if cache.get("a", req) != nil {
+		t.Error("expected the least recently used response to be evicted")
+	}
+	if cache.get("b", req) == nil {
+		t.Error("expected the most recent response to be kept")
+	}
+
+	if _, err := NewResponseCache(0); err == nil {
+		t.Error("expected an error for zero max entries")
+	}
+}
+
+func TestParseCacheControl(t *testing.T) {
+	h := stdhttp.Header{"Cache-Control": {`Max-Age=60, no-cache="Set-Cookie"`, "stale-if-error=x"}}
+	cc := parseCacheControl(h)
+
+	if d, ok := cc.duration("max-age"); !ok || d != time.Minute {
+		t.Errorf("expected max-age of a minute, got %v %v", d, ok)
+	}
+	if !cc.has("no-cache") {
+		t.Error("expected no-cache")
+	}
+	if _, ok := cc.duration("stale-if-error"); ok {
+		t.Error("expected an invalid stale-if-error to be ignored")
+	}
+
+	// Values too large to represent are clamped, rather than overflowing to a negative duration.
+	cc = parseCacheControl(stdhttp.Header{"Cache-Control": {"max-age=9223372036854775807"}})
+	if d, ok := cc.duration("max-age"); !ok || d != maxDeltaSeconds*time.Second {
+		t.Errorf("expected max-age to be clamped, got %v %v", d, ok)
+	}
+	if age := ageHeader(stdhttp.Header{"Age": {"99999999999999999999999"}}); age != maxDeltaSeconds*time.Second {
+		t.Errorf("expected Age to be clamped, got %v", age)
+	}
+}
+
+func TestResponseCache_Close(t *testing.T) {
+	release := make(chan struct{})
+	revalidating := make(chan struct{}, 1)
+	var requests atomic.Int64
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		if requests.Add(1) > 1 {
+			revalidating <- struct{}{}
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		}
+		w.Header().Set("Cache-Control", "max-age=1, stale-while-revalidate=60")
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	c, cache, advance := newCacheTestClient(t)
+	getBody(t, c, ts.URL)
+	advance(2 * time.Second)
+	getBody(t, c, ts.URL)
+	<-revalidating
+
+	// Close aborts the hanging background revalidation rather than waiting on it.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := cache.Close(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Once closed, stale responses are still served, but not revalidated.
+	getBody(t, c, ts.URL)
+	if got := requests.Load(); got != 2 {
+		t.Errorf("expected no revalidation after close, got %d requests", got)
+	}
+}
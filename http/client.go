@@ -2,13 +2,20 @@ package http
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
+	"fmt"
 	"net"
 	stdhttp "net/http"
+	"net/url"
+	"reflect"
 	"time"
 
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -29,18 +36,75 @@ var defaultClientOptions = []ClientOption{
 
 const instrumentationName = "github.com/andrewhowdencom/stdlib/http"
 
+// wrappingTransport is implemented by the RoundTrippers this package layers between the
+// InstrumentedTransport and the underlying *http.Transport (e.g. retries).
+type wrappingTransport interface {
+	stdhttp.RoundTripper
+	unwrap() stdhttp.RoundTripper
+	setBase(base stdhttp.RoundTripper)
+}
+
+// instrumentable is implemented by wrapping transports that record their own metrics. Their instruments
+// are created once all options have been applied, so that they use the final meter.
+type instrumentable interface {
+	instrument(meter metric.Meter) error
+}
+
 // getTransport returns the underlying *http.Transport from the client.
-// It handles both direct *http.Transport and wrapped *InstrumentedTransport.
+// It handles direct *http.Transport, a wrapping *InstrumentedTransport, and any of this package's
+// wrapping transports layered in between.
 func getTransport(c *stdhttp.Client) (*stdhttp.Transport, error) {
-	if t, ok := c.Transport.(*stdhttp.Transport); ok {
-		return t, nil
+	rt := c.Transport
+	if it, ok := rt.(*InstrumentedTransport); ok {
+		rt = it.Base
 	}
-	if it, ok := c.Transport.(*InstrumentedTransport); ok {
-		if t, ok := it.Base.(*stdhttp.Transport); ok {
+	return baseTransport(rt)
+}
+
+// baseTransport unwraps this package's wrapping transports to find the underlying *http.Transport.
+func baseTransport(rt stdhttp.RoundTripper) (*stdhttp.Transport, error) {
+	for {
+		switch t := rt.(type) {
+		case *stdhttp.Transport:
 			return t, nil
+		case wrappingTransport:
+			rt = t.unwrap()
+		default:
+			return nil, errors.New("transport is not *http.Transport")
 		}
 	}
-	return nil, errors.New("transport is not *http.Transport")
+}
+
+// wrapTransport layers a wrapping transport over the client's current base transport, beneath the
+// InstrumentedTransport if there is one.
+func wrapTransport(c *stdhttp.Client, wrap func(stdhttp.RoundTripper) stdhttp.RoundTripper) {
+	if it, ok := c.Transport.(*InstrumentedTransport); ok {
+		it.Base = wrap(it.Base)
+		return
+	}
+	c.Transport = wrap(c.Transport)
+}
+
+// wrapInnermost layers a wrapping transport directly over the underlying *http.Transport, beneath any of this
+// package's other wrapping transports, so that it sees each attempt rather than each logical request.
+func wrapInnermost(c *stdhttp.Client, wrap func(stdhttp.RoundTripper) stdhttp.RoundTripper) {
+	var parent wrappingTransport
+	for rt := c.Transport; ; {
+		if it, ok := rt.(*InstrumentedTransport); ok {
+			rt = it.Base
+			continue
+		}
+		w, ok := rt.(wrappingTransport)
+		if !ok {
+			break
+		}
+		parent, rt = w, w.unwrap()
+	}
+	if parent == nil {
+		wrapTransport(c, wrap)
+		return
+	}
+	parent.setBase(wrap(parent.unwrap()))
 }
 
 // WithClientTracerProvider configures the client with a specific tracer provider.
@@ -63,13 +127,174 @@ func WithClientMeterProvider(mp metric.MeterProvider) ClientOption {
 			return errors.New("client transport must be *InstrumentedTransport")
 		}
 		it.Meter = mp.Meter(instrumentationName)
-		var err error
-		it.mWaitTime, err = it.Meter.Float64Histogram("http.client.connection.wait_time", metric.WithUnit("s"))
-		if err != nil {
-			return err
+		return nil
+	}
+}
+
+// WithClientContentTypeAttributes records the response Content-Type on the client span as
+// http.response.header.content-type. The request Content-Type is already recorded, along with the other
+// request headers, by the httptrace hooks as http.request.header.content-type.
+func WithClientContentTypeAttributes() ClientOption {
+	return func(c *stdhttp.Client) error {
+		it, ok := c.Transport.(*InstrumentedTransport)
+		if !ok {
+			return errors.New("client transport must be *InstrumentedTransport")
 		}
-		it.mActiveRequests, err = it.Meter.Int64UpDownCounter("http.client.active_requests")
-		return err
+		it.contentTypeAttrs = true
+		return nil
+	}
+}
+
+// WithClientBodySizeAttributes records the request and response body sizes on the client span as
+// http.request.body.size and http.response.body.size. The request size is taken from its Content-Length. The
+// response size is too if it is known; otherwise the body is counted as it is read, and recorded once it has
+// been read to the end or closed.
+func WithClientBodySizeAttributes() ClientOption {
+	return func(c *stdhttp.Client) error {
+		it, ok := c.Transport.(*InstrumentedTransport)
+		if !ok {
+			return errors.New("client transport must be *InstrumentedTransport")
+		}
+		it.bodySizeAttrs = true
+		return nil
+	}
+}
+
+// WithSlowConnectionWaitThreshold sets how long a request may wait for a connection before a
+// "http.connection.slow_wait" event is added to its span, explaining whether the connection was reused and,
+// if not, why the pool had none to offer. Defaults to 100ms.
+func WithSlowConnectionWaitThreshold(d time.Duration) ClientOption {
+	return func(c *stdhttp.Client) error {
+		it, ok := c.Transport.(*InstrumentedTransport)
+		if !ok {
+			return errors.New("client transport must be *InstrumentedTransport")
+		}
+		if d <= 0 {
+			return errors.New("slow connection wait threshold must be positive")
+		}
+		it.slowWaitThreshold = d
+		return nil
+	}
+}
+
+// WithLogicalRequestSpan starts a span covering the whole logical request when resilience features
+// (WithRetry, WithCircuitBreaker, WithClientRateLimit or WithMaxConcurrentRequests) are in use, with a child
+// client span for each attempt. The logical span records the total number of attempts as
+// http.request.attempts, whether a circuit breaker rejected an attempt as http.client.circuit.short_circuited,
+// and the total time attempts waited for client-side limits as http.client.throttle.wait_time. Clients without
+// resilience features are unaffected.
+func WithLogicalRequestSpan() ClientOption {
+	return func(c *stdhttp.Client) error {
+		it, ok := c.Transport.(*InstrumentedTransport)
+		if !ok {
+			return errors.New("client transport must be *InstrumentedTransport")
+		}
+		it.logicalSpan = true
+		return nil
+	}
+}
+
+// WithClientSpanNameFormatter names the client spans this package starts with fn, in place of the default
+// "HTTP {method}". These are the spans of WithLogicalRequestSpan (the logical request and each attempt) and of
+// NewReverseProxy's upstream requests; otherwise the client enriches the caller's span, which it doesn't rename.
+func WithClientSpanNameFormatter(fn func(*stdhttp.Request) string) ClientOption {
+	return func(c *stdhttp.Client) error {
+		if fn == nil {
+			return errors.New("span name formatter must not be nil")
+		}
+		it, ok := c.Transport.(*InstrumentedTransport)
+		if !ok {
+			return errors.New("client transport must be *InstrumentedTransport")
+		}
+		it.spanName = fn
+		return nil
+	}
+}
+
+// WithMeasureToBodyClose records http.client.request.duration when the response body is read to the end or
+// closed, rather than when the response headers arrive, so that the metric includes the time taken to
+// transfer the body. This matters for downloads and streaming responses, where the two can differ
+// enormously. The measurement mode is recorded in the http.client.request.duration.mode attribute.
+func WithMeasureToBodyClose() ClientOption {
+	return func(c *stdhttp.Client) error {
+		it, ok := c.Transport.(*InstrumentedTransport)
+		if !ok {
+			return errors.New("client transport must be *InstrumentedTransport")
+		}
+		it.measureToBodyClose = true
+		return nil
+	}
+}
+
+// WithErrorBodySnippet records up to n bytes from the start of 5xx response bodies on the client span as
+// http.response.body.snippet, since the body often explains a failure that the status code alone does not.
+// The bytes read are buffered and replayed, so the caller still receives the full body. n may be at most
+// 1024, and snippets that look like they contain credentials are replaced with "[REDACTED]".
+func WithErrorBodySnippet(n int) ClientOption {
+	return func(c *stdhttp.Client) error {
+		it, ok := c.Transport.(*InstrumentedTransport)
+		if !ok {
+			return errors.New("client transport must be *InstrumentedTransport")
+		}
+		if n < 1 || n > maxBodySnippetSize {
+			return fmt.Errorf("error body snippet size must be between 1 and %d", maxBodySnippetSize)
+		}
+		it.bodySnippetSize = n
+		return nil
+	}
+}
+
+// WithErrorStatusThreshold sets the lowest response status code that marks the client span as an error, which
+// is 400 by default, as the HTTP semantic conventions suggest. Raising it to 500 suits clients for which 4xx
+// responses are expected, such as a 404 when checking whether a resource exists. code must be between 400 and
+// 599.
+func WithErrorStatusThreshold(code int) ClientOption {
+	return func(c *stdhttp.Client) error {
+		it, ok := c.Transport.(*InstrumentedTransport)
+		if !ok {
+			return errors.New("client transport must be *InstrumentedTransport")
+		}
+		if code < 400 || code > 599 {
+			return errors.New("error status threshold must be between 400 and 599")
+		}
+		it.errorStatusThreshold = code
+		return nil
+	}
+}
+
+// WithoutPropagation sets the methods of requests that trace context is not injected into, which are still
+// traced and measured as usual. By default this is only CONNECT, as trace headers on a request to a proxy
+// can confuse it or leak context to it. The methods given replace the default, so to also skip OPTIONS
+// (preflight) requests:
+//
+//	http.WithoutPropagation(stdhttp.MethodConnect, stdhttp.MethodOptions)
+//
+// With no methods, trace context is injected into every request, including CONNECT.
+func WithoutPropagation(methods ...string) ClientOption {
+	return func(c *stdhttp.Client) error {
+		it, ok := c.Transport.(*InstrumentedTransport)
+		if !ok {
+			return errors.New("client transport must be *InstrumentedTransport")
+		}
+		it.skipPropagation = make(map[string]bool, len(methods))
+		for _, m := range methods {
+			it.skipPropagation[m] = true
+		}
+		return nil
+	}
+}
+
+// WithClientRequirePropagator makes NewClient (and InstrumentExistingClient) return ErrNoopPropagator if
+// no text map propagator has been configured, catching a common cause of traces that break between
+// services. By default this is not checked.
+func WithClientRequirePropagator() ClientOption {
+	return func(c *stdhttp.Client) error {
+		it, ok := c.Transport.(*InstrumentedTransport)
+		if !ok {
+			return errors.New("client transport must be *InstrumentedTransport")
+		}
+		it.requirePropagator = true
+		return nil
 	}
 }
 
@@ -81,22 +306,68 @@ func WithTimeout(d time.Duration) ClientOption {
 	}
 }
 
+// defaultDialKeepAlive is the keep-alive period of the client's dialer unless set with WithDialKeepAlive.
+const defaultDialKeepAlive = 30 * time.Second
+
 // WithConnectTimeout sets the connection timeout (Dialer.Timeout).
 func WithConnectTimeout(d time.Duration) ClientOption {
+	return func(c *stdhttp.Client) error {
+		dialer, err := clientDialer(c)
+		if err != nil {
+			return err
+		}
+		dialer.Timeout = d
+		return nil
+	}
+}
+
+// WithDialKeepAlive sets how often TCP keep-alive probes are sent on the client's connections
+// (Dialer.KeepAlive), which detect connections that have silently died. The default is 30s; a negative
+// duration disables the probes.
+func WithDialKeepAlive(d time.Duration) ClientOption {
+	return func(c *stdhttp.Client) error {
+		dialer, err := clientDialer(c)
+		if err != nil {
+			return err
+		}
+		dialer.KeepAlive = d
+		return nil
+	}
+}
+
+// WithDisableKeepAlives stops the client reusing connections (Transport.DisableKeepAlives), so that every
+// request is sent on a new one. This is only worthwhile when connections must not be shared, such as when
+// each request should reach a different backend behind a connection-level load balancer.
+func WithDisableKeepAlives() ClientOption {
 	return func(c *stdhttp.Client) error {
 		t, err := getTransport(c)
 		if err != nil {
 			return err
 		}
-		// Re-create the Dialer with the new timeout, preserving KeepAlive
-		t.DialContext = (&net.Dialer{
-			Timeout:   d,
-			KeepAlive: 30 * time.Second,
-		}).DialContext
+		t.DisableKeepAlives = true
 		return nil
 	}
 }
 
+// clientDialer returns the dialer the client's transport connects with, so that options can configure it
+// without resetting one another's settings. The dialer is kept on the InstrumentedTransport, as a dialer
+// can't be recovered from Transport.DialContext; it replaces any DialContext the transport already had.
+func clientDialer(c *stdhttp.Client) (*net.Dialer, error) {
+	t, err := getTransport(c)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{KeepAlive: defaultDialKeepAlive}
+	if it, ok := c.Transport.(*InstrumentedTransport); ok {
+		if it.dialer == nil {
+			it.dialer = dialer
+		}
+		dialer = it.dialer
+	}
+	t.DialContext = dialer.DialContext
+	return dialer, nil
+}
+
 // WithTLSHandshakeTimeout sets the TLS handshake timeout.
 func WithTLSHandshakeTimeout(d time.Duration) ClientOption {
 	return func(c *stdhttp.Client) error {
@@ -133,6 +404,42 @@ func WithMaxIdleConns(n int) ClientOption {
 	}
 }
 
+// WithMaxConnsPerHost limits the number of connections to each host (Transport.MaxConnsPerHost), counting
+// those dialing, in use and idle. Requests over the limit wait for a connection to be free, which is recorded
+// as a per_host_limit pool miss. Zero, the default, means no limit.
+func WithMaxConnsPerHost(n int) ClientOption {
+	return func(c *stdhttp.Client) error {
+		if n < 0 {
+			return errors.New("max connections per host must not be negative")
+		}
+		t, err := getTransport(c)
+		if err != nil {
+			return err
+		}
+		t.MaxConnsPerHost = n
+		return nil
+	}
+}
+
+// WithMaxIdleConnsPerHost sets the maximum number of idle connections kept for each host
+// (Transport.MaxIdleConnsPerHost). Zero, the default, means net/http's default of 2, which is too few for a
+// client sending many concurrent requests to a single backend: connections beyond it are closed rather than
+// reused. The total across hosts is still capped by WithMaxIdleConns (100 by default), so raise that too if
+// the per-host limit would exceed it.
+func WithMaxIdleConnsPerHost(n int) ClientOption {
+	return func(c *stdhttp.Client) error {
+		if n < 0 {
+			return errors.New("max idle connections per host must not be negative")
+		}
+		t, err := getTransport(c)
+		if err != nil {
+			return err
+		}
+		t.MaxIdleConnsPerHost = n
+		return nil
+	}
+}
+
 // WithIdleConnTimeout sets the idle connection timeout.
 func WithIdleConnTimeout(d time.Duration) ClientOption {
 	return func(c *stdhttp.Client) error {
@@ -157,9 +464,142 @@ func WithExpectContinueTimeout(d time.Duration) ClientOption {
 	}
 }
 
+// WithTLSConfig sets the TLS configuration used for HTTPS requests. The config is copied, so that later
+// options (such as WithRootCAs) don't modify the caller's. HTTP/2 is still negotiated, and the TLS handshake
+// timeout still applies.
+func WithTLSConfig(cfg *tls.Config) ClientOption {
+	return func(c *stdhttp.Client) error {
+		if cfg == nil {
+			return errors.New("TLS config must not be nil")
+		}
+		t, err := getTransport(c)
+		if err != nil {
+			return err
+		}
+		t.TLSClientConfig = cfg.Clone()
+		return nil
+	}
+}
+
+// WithRootCAs sets the certificate authorities that server certificates are verified against, in place of the
+// system pool. It modifies the TLS configuration set with WithTLSConfig, if there is one.
+func WithRootCAs(pool *x509.CertPool) ClientOption {
+	return func(c *stdhttp.Client) error {
+		if pool == nil {
+			return errors.New("root CA pool must not be nil")
+		}
+		cfg, err := clientTLSConfig(c)
+		if err != nil {
+			return err
+		}
+		cfg.RootCAs = pool
+		return nil
+	}
+}
+
+// WithInsecureSkipVerify controls whether server certificates are verified. Skipping verification exposes
+// requests to interception, and is only appropriate for testing. It modifies the TLS configuration set with
+// WithTLSConfig, if there is one.
+func WithInsecureSkipVerify(skip bool) ClientOption {
+	return func(c *stdhttp.Client) error {
+		cfg, err := clientTLSConfig(c)
+		if err != nil {
+			return err
+		}
+		cfg.InsecureSkipVerify = skip //nolint:gosec // Opted into explicitly.
+		return nil
+	}
+}
+
+// WithClientCertificate presents cert to servers that request a client certificate, for mutual TLS. It
+// modifies the TLS configuration set with WithTLSConfig, if there is one.
+func WithClientCertificate(cert tls.Certificate) ClientOption {
+	return func(c *stdhttp.Client) error {
+		if len(cert.Certificate) == 0 {
+			return errors.New("client certificate must not be empty")
+		}
+		cfg, err := clientTLSConfig(c)
+		if err != nil {
+			return err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+		return nil
+	}
+}
+
+// WithClientCertificateFiles is like WithClientCertificate, loading the certificate and its key from
+// PEM-encoded files.
+func WithClientCertificateFiles(certPath, keyPath string) ClientOption {
+	return func(c *stdhttp.Client) error {
+		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		if err != nil {
+			return fmt.Errorf("loading client certificate: %w", err)
+		}
+		return WithClientCertificate(cert)(c)
+	}
+}
+
+// clientTLSConfig returns the client transport's TLS configuration, creating it if there is none.
+func clientTLSConfig(c *stdhttp.Client) (*tls.Config, error) {
+	t, err := getTransport(c)
+	if err != nil {
+		return nil, err
+	}
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = &tls.Config{}
+	}
+	return t.TLSClientConfig, nil
+}
+
+// WithProxyURL sends every request through the proxy at u, regardless of the environment.
+func WithProxyURL(u *url.URL) ClientOption {
+	return func(c *stdhttp.Client) error {
+		if u == nil {
+			return errors.New("proxy URL must not be nil")
+		}
+		return WithProxyFunc(stdhttp.ProxyURL(u))(c)
+	}
+}
+
+// WithNoProxy disables proxying, regardless of the environment.
+func WithNoProxy() ClientOption {
+	return func(c *stdhttp.Client) error {
+		t, err := getTransport(c)
+		if err != nil {
+			return err
+		}
+		t.Proxy = nil
+		return nil
+	}
+}
+
+// WithProxyFunc replaces how the proxy for each request is chosen (by default, http.ProxyFromEnvironment).
+// A nil URL means the request is not proxied.
+func WithProxyFunc(fn func(*stdhttp.Request) (*url.URL, error)) ClientOption {
+	return func(c *stdhttp.Client) error {
+		if fn == nil {
+			return errors.New("proxy func must not be nil")
+		}
+		t, err := getTransport(c)
+		if err != nil {
+			return err
+		}
+		t.Proxy = fn
+		return nil
+	}
+}
+
 // NewClient returns a new http.Client with sane defaults for internal traffic.
 // Defaults are defined in defaultClientOptions.
 func NewClient(opts ...ClientOption) (*stdhttp.Client, error) {
+	return NewClientWithDefaults(defaultClientOptions, opts...)
+}
+
+// NewClientWithDefaults is like NewClient, but applies the given defaults in place of the package defaults.
+// The defaults are fully replaced, not augmented: any setting they don't cover keeps the net/http zero
+// value, and a nil slice applies no defaults at all. This allows tests and benchmarks to inject their own
+// baseline for a single client without mutating package state.
+func NewClientWithDefaults(defaults []ClientOption, opts ...ClientOption) (*stdhttp.Client, error) {
 	// Initialize Transport with base values that are not timeouts
 	t := &stdhttp.Transport{
 		Proxy:             stdhttp.ProxyFromEnvironment,
@@ -174,7 +614,7 @@ func NewClient(opts ...ClientOption) (*stdhttp.Client, error) {
 	}
 
 	// Apply defaults
-	for _, opt := range defaultClientOptions {
+	for _, opt := range defaults {
 		if err := opt(c); err != nil {
 			return nil, err
 		}
@@ -187,49 +627,185 @@ func NewClient(opts ...ClientOption) (*stdhttp.Client, error) {
 		}
 	}
 
-	configureClientInstrumentation(c)
+	if err := configureClientInstrumentation(c); err != nil {
+		return nil, err
+	}
 	return c, nil
 }
 
-func configureClientInstrumentation(c *stdhttp.Client) {
-	t, err := getTransport(c)
-	if err != nil || t == nil {
-		return
+// configureClientInstrumentation creates the client's instruments with the final meter and wires them into
+// the transport. It may be called more than once for the same client: the dialer and redirect policy are
+// only wrapped if they aren't already, and the instruments are replaced.
+func configureClientInstrumentation(c *stdhttp.Client) error {
+	it, ok := c.Transport.(*InstrumentedTransport)
+	if !ok {
+		return nil
+	}
+	if it.requirePropagator {
+		if err := checkPropagator(it.propagator); err != nil {
+			return err
+		}
+	}
+	if it.Meter == nil {
+		it.Meter = otel.GetMeterProvider().Meter(instrumentationName)
 	}
 
-	// Wrap DialContext
-	originalDial := t.DialContext
-	if originalDial == nil {
-		panic("DialContext must be set")
+	// Instrument any wrapping transports with the final meter.
+	for rt := it.Base; ; {
+		w, ok := rt.(wrappingTransport)
+		if !ok {
+			break
+		}
+		if i, ok := w.(instrumentable); ok {
+			if err := i.instrument(it.Meter); err != nil {
+				return err
+			}
+		}
+		rt = w.unwrap()
 	}
 
-	it, ok := c.Transport.(*InstrumentedTransport)
-	if !ok {
-		return
+	if err := it.createInstruments(); err != nil {
+		return err
+	}
+	var err error
+	it.mRedirects, err = it.Meter.Int64Counter("http.client.redirects")
+	if err != nil {
+		return err
+	}
+	if !sameFunc(c.CheckRedirect, it.checkRedirect) {
+		it.checkRedirect = instrumentedCheckRedirect(c.CheckRedirect, it)
+		c.CheckRedirect = it.checkRedirect
 	}
 
-	openConns, err := it.Meter.Int64UpDownCounter("http.client.open_connections")
+	t, err := getTransport(c)
+	if err != nil || t == nil {
+		return nil
+	}
+
+	it.mOpenConns, err = it.Meter.Int64UpDownCounter("http.client.open_connections")
 	if err != nil {
-		return
+		return err
 	}
 
-	t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
-		conn, err := originalDial(ctx, network, addr)
-		if err == nil {
-			openConns.Add(ctx, 1)
-			return &trackedConn{Conn: conn, counter: openConns, ctx: ctx}, nil
+	// Wrap DialContext, unless another client sharing this transport already has. Its connections are then
+	// counted by whichever client was instrumented first.
+	if !isConnTracker(t.DialContext) {
+		originalDial := t.DialContext
+		if originalDial == nil {
+			// Mirror net/http, which falls back to Dial and then to a zero net.Dialer.
+			if dial := t.Dial; dial != nil { //nolint:staticcheck // Transports built elsewhere may still set Dial.
+				originalDial = func(_ context.Context, network, addr string) (net.Conn, error) {
+					return dial(network, addr)
+				}
+			} else {
+				originalDial = (&net.Dialer{}).DialContext
+			}
 		}
-		return conn, err
+		t.DialContext = (&connTracker{dial: originalDial, conns: it.mOpenConns}).DialContext
+	}
+	return nil
+}
+
+// connTracker wraps a transport's dialer so that its connections are counted in http.client.open_connections.
+type connTracker struct {
+	dial  func(ctx context.Context, network, addr string) (net.Conn, error)
+	conns metric.Int64UpDownCounter
+}
+
+// DialContext dials through the wrapped dialer and tracks the connection it returns.
+func (d *connTracker) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	conn, err := d.dial(ctx, network, addr)
+	if err == nil {
+		return newTrackedConn(ctx, conn, addr, d.conns), nil
 	}
+	return conn, err
 }
 
-type trackedConn struct {
-	net.Conn
-	counter metric.Int64UpDownCounter
-	ctx     context.Context
+// connTrackerDial is the code pointer shared by every connTracker's DialContext method value.
+var connTrackerDial = reflect.ValueOf((&connTracker{}).DialContext).Pointer()
+
+// isConnTracker reports whether dial is a connTracker's DialContext, i.e. whether the transport's dialer is
+// already tracked by this package.
+func isConnTracker(dial func(ctx context.Context, network, addr string) (net.Conn, error)) bool {
+	return dial != nil && reflect.ValueOf(dial).Pointer() == connTrackerDial
 }
 
-func (c *trackedConn) Close() error {
-	c.counter.Add(c.ctx, -1)
-	return c.Conn.Close()
+// sameFunc reports whether two functions share the same code. This is used to detect whether a function
+// is a wrapper this package installed; it cannot tell apart two closures created by the same literal.
+func sameFunc(a, b any) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.IsNil() || vb.IsNil() {
+		return false
+	}
+	return va.Pointer() == vb.Pointer()
+}
+
+// InstrumentExistingClient adds this package's telemetry to a client constructed elsewhere (e.g. by
+// another library), applying the given options without any of the defaults NewClient applies.
+//
+// If the client's transport is already an *InstrumentedTransport it is reused; otherwise it is wrapped in
+// one. A nil transport is replaced by a clone of http.DefaultTransport, so the shared default is never
+// modified. Calling InstrumentExistingClient again on the same client is safe: the instruments are
+// recreated (e.g. to use a different meter provider) without double-counting. It must not be called while
+// the client is in use.
+func InstrumentExistingClient(c *stdhttp.Client, opts ...ClientOption) error {
+	switch t := c.Transport.(type) {
+	case *InstrumentedTransport:
+	case nil:
+		dt, ok := stdhttp.DefaultTransport.(*stdhttp.Transport)
+		if !ok {
+			return errors.New("http.DefaultTransport is not *http.Transport")
+		}
+		c.Transport = &InstrumentedTransport{Base: dt.Clone()}
+	default:
+		c.Transport = &InstrumentedTransport{Base: t}
+	}
+
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return err
+		}
+	}
+
+	return configureClientInstrumentation(c)
+}
+
+// maxDefaultRedirects mirrors the limit applied by net/http when Client.CheckRedirect is nil.
+const maxDefaultRedirects = 10
+
+// instrumentedCheckRedirect wraps a redirect policy so that each redirect hop that is followed is
+// recorded as an event on the active span and counted in the redirects metric. When policy is nil
+// the net/http default (stop after 10 redirects) is applied.
+func instrumentedCheckRedirect(
+	policy func(*stdhttp.Request, []*stdhttp.Request) error,
+	it *InstrumentedTransport,
+) func(*stdhttp.Request, []*stdhttp.Request) error {
+	return func(req *stdhttp.Request, via []*stdhttp.Request) error {
+		var err error
+		if policy != nil {
+			err = policy(req, via)
+		} else if len(via) >= maxDefaultRedirects {
+			err = fmt.Errorf("stopped after %d redirects", maxDefaultRedirects)
+		}
+		if err != nil {
+			return err
+		}
+
+		ctx := req.Context()
+		attrs := []attribute.KeyValue{
+			semconv.HTTPRequestMethodKey.String(req.Method),
+		}
+		if req.Response != nil {
+			attrs = append(attrs, semconv.HTTPResponseStatusCodeKey.Int(req.Response.StatusCode))
+		}
+		it.mRedirects.Add(ctx, 1, metric.WithAttributes(attrs...))
+
+		if span := trace.SpanFromContext(ctx); span.IsRecording() {
+			span.AddEvent("http.redirect", trace.WithAttributes(append(attrs,
+				semconv.ServerAddressKey.String(req.URL.Hostname()),
+				attribute.Int("http.redirect.count", len(via)),
+			)...))
+		}
+		return nil
+	}
 }
@@ -1,8 +1,27 @@
 package http
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 )
 
 func TestNewClient_Defaults(t *testing.T) {
@@ -58,6 +77,33 @@ func TestNewClient_Options(t *testing.T) {
 	}
 }
 
+func TestNewClientWithDefaults(t *testing.T) {
+	c, err := NewClientWithDefaults([]ClientOption{WithTimeout(10 * time.Second)}, WithMaxIdleConns(5))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if c.Timeout != 10*time.Second {
+		t.Errorf("expected custom default timeout 10s, got %v", c.Timeout)
+	}
+
+	tr, err := getTransport(c)
+	if err != nil {
+		t.Fatalf("expected transport to be *http.Transport: %v", err)
+	}
+	// The package defaults are replaced, not augmented.
+	if tr.ResponseHeaderTimeout != 0 {
+		t.Errorf("expected ResponseHeaderTimeout to be unset, got %v", tr.ResponseHeaderTimeout)
+	}
+	if tr.MaxIdleConns != 5 {
+		t.Errorf("expected options to apply over the custom defaults, got MaxIdleConns %d", tr.MaxIdleConns)
+	}
+
+	if len(defaultClientOptions) == 0 {
+		t.Error("expected package defaults to be untouched")
+	}
+}
+
 func TestNewClient_MaxIdleConns(t *testing.T) {
 	c, err := NewClient()
 	if err != nil {
@@ -86,3 +132,447 @@ func TestNewClient_MaxIdleConns(t *testing.T) {
 		t.Errorf("expected MaxIdleConns 50, got %d", tr2.MaxIdleConns)
 	}
 }
+
+func TestWithMaxConnsPerHost(t *testing.T) {
+	c, err := NewClient(WithMaxConnsPerHost(10), WithMaxIdleConnsPerHost(5))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := c.Transport.(*InstrumentedTransport); !ok {
+		t.Fatalf("expected transport to be *InstrumentedTransport, got %T", c.Transport)
+	}
+	tr, err := getTransport(c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tr.MaxConnsPerHost != 10 {
+		t.Errorf("expected MaxConnsPerHost 10, got %d", tr.MaxConnsPerHost)
+	}
+	if tr.MaxIdleConnsPerHost != 5 {
+		t.Errorf("expected MaxIdleConnsPerHost 5, got %d", tr.MaxIdleConnsPerHost)
+	}
+	if tr.MaxIdleConns != 100 {
+		t.Errorf("expected default MaxIdleConns 100 to be kept, got %d", tr.MaxIdleConns)
+	}
+}
+
+func TestWithMaxConnsPerHost_Wrapped(t *testing.T) {
+	// The transport is found beneath resilience layers as well as the InstrumentedTransport.
+	c, err := NewClient(WithRetry(3), WithMaxConnsPerHost(10), WithMaxIdleConnsPerHost(5))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tr, err := getTransport(c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tr.MaxConnsPerHost != 10 || tr.MaxIdleConnsPerHost != 5 {
+		t.Errorf("expected per-host limits 10 and 5, got %d and %d", tr.MaxConnsPerHost, tr.MaxIdleConnsPerHost)
+	}
+
+	existing := &stdhttp.Client{Transport: &stdhttp.Transport{}}
+	if err := InstrumentExistingClient(existing, WithMaxConnsPerHost(3)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := existing.Transport.(*InstrumentedTransport).Base.(*stdhttp.Transport).MaxConnsPerHost; got != 3 {
+		t.Errorf("expected MaxConnsPerHost 3, got %d", got)
+	}
+}
+
+func TestWithMaxConnsPerHost_Negative(t *testing.T) {
+	if _, err := NewClient(WithMaxConnsPerHost(-1)); err == nil {
+		t.Error("expected an error for negative max connections per host")
+	}
+	if _, err := NewClient(WithMaxIdleConnsPerHost(-1)); err == nil {
+		t.Error("expected an error for negative max idle connections per host")
+	}
+}
+
+func TestWithDialKeepAlive(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          []ClientOption
+		wantTimeout   time.Duration
+		wantKeepAlive time.Duration
+	}{
+		{
+			name:          "default",
+			wantTimeout:   500 * time.Millisecond,
+			wantKeepAlive: 30 * time.Second,
+		},
+		{
+			name:          "connect timeout after keep-alive",
+			opts:          []ClientOption{WithDialKeepAlive(time.Minute), WithConnectTimeout(time.Second)},
+			wantTimeout:   time.Second,
+			wantKeepAlive: time.Minute,
+		},
+		{
+			name:          "keep-alive after connect timeout",
+			opts:          []ClientOption{WithConnectTimeout(time.Second), WithDialKeepAlive(time.Minute)},
+			wantTimeout:   time.Second,
+			wantKeepAlive: time.Minute,
+		},
+		{
+			name:          "disabled",
+			opts:          []ClientOption{WithDialKeepAlive(-1)},
+			wantTimeout:   500 * time.Millisecond,
+			wantKeepAlive: -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewClient(tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			dialer := c.Transport.(*InstrumentedTransport).dialer
+			if dialer.Timeout != tt.wantTimeout {
+				t.Errorf("expected Timeout %v, got %v", tt.wantTimeout, dialer.Timeout)
+			}
+			if dialer.KeepAlive != tt.wantKeepAlive {
+				t.Errorf("expected KeepAlive %v, got %v", tt.wantKeepAlive, dialer.KeepAlive)
+			}
+		})
+	}
+}
+
+func TestWithDialKeepAlive_Dials(t *testing.T) {
+	srv := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, _ *stdhttp.Request) {
+		w.WriteHeader(stdhttp.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(WithConnectTimeout(time.Second), WithDialKeepAlive(time.Minute))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != stdhttp.StatusNoContent {
+		t.Errorf("expected status 204, got %d", resp.StatusCode)
+	}
+}
+
+func TestWithDisableKeepAlives(t *testing.T) {
+	var conns atomic.Int32
+	srv := httptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, _ *stdhttp.Request) {
+		w.WriteHeader(stdhttp.StatusNoContent)
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state stdhttp.ConnState) {
+		if state == stdhttp.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	c, err := NewClient(WithDisableKeepAlives())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for range 2 {
+		resp, err := c.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}
+	if got := conns.Load(); got != 2 {
+		t.Errorf("expected a connection per request, got %d connections", got)
+	}
+}
+
+func TestInstrumentExistingClient(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		if r.URL.Path == "/redirect" {
+			stdhttp.Redirect(w, r, "/", stdhttp.StatusFound)
+		}
+	}))
+	defer ts.Close()
+
+	c := &stdhttp.Client{Transport: &stdhttp.Transport{}}
+
+	// Instrumenting twice must not double-wrap the dialer or the redirect policy.
+	for range 2 {
+		if err := InstrumentExistingClient(c, WithClientMeterProvider(mp)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if _, ok := c.Transport.(*InstrumentedTransport); !ok {
+		t.Fatalf("expected transport to be wrapped, got %T", c.Transport)
+	}
+
+	resp, err := c.Get(ts.URL + "/redirect")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if got := sumCounter(t, reader, "http.client.redirects"); got != 1 {
+		t.Errorf("expected 1 redirect recorded, got %d", got)
+	}
+	if got := sumCounter(t, reader, "http.client.open_connections"); got != 1 {
+		t.Errorf("expected 1 open connection recorded, got %d", got)
+	}
+}
+
+func TestInstrumentExistingClient_SharedTransport(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {}))
+	defer ts.Close()
+
+	// Two clients sharing one transport must not each wrap its dialer, or every connection is counted twice.
+	tr := &stdhttp.Transport{}
+	defer tr.CloseIdleConnections()
+	a, b := &stdhttp.Client{Transport: tr}, &stdhttp.Client{Transport: tr}
+	for _, c := range []*stdhttp.Client{a, b} {
+		if err := InstrumentExistingClient(c, WithClientMeterProvider(mp)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	for _, c := range []*stdhttp.Client{a, b} {
+		resp, err := c.Get(ts.URL)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}
+
+	if got := sumCounter(t, reader, "http.client.open_connections"); got != 1 {
+		t.Errorf("expected 1 open connection recorded, got %d", got)
+	}
+}
+
+func TestInstrumentExistingClient_NilTransport(t *testing.T) {
+	c := &stdhttp.Client{}
+	if err := InstrumentExistingClient(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tr, err := getTransport(c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tr == stdhttp.DefaultTransport {
+		t.Error("expected http.DefaultTransport to be cloned rather than modified")
+	}
+}
+
+func TestWithProxy(t *testing.T) {
+	// The proxy receives requests in absolute form, for the origin it is asked to reach.
+	requested := make(chan string, 1)
+	proxy := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		requested <- r.URL.String()
+	}))
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+
+	// Wrapped in a retry transport, to check the options reach the underlying transport.
+	c, err := NewClient(WithRetry(2), WithProxyURL(proxyURL))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp, err := c.Get("http://origin.invalid/path")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+	if got := <-requested; got != "http://origin.invalid/path" {
+		t.Errorf("expected the request to go through the proxy, got %s", got)
+	}
+
+	var called bool
+	c, err = NewClient(WithProxyFunc(func(r *stdhttp.Request) (*url.URL, error) {
+		called = true
+		return nil, nil
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tr, _ := getTransport(c)
+	if _, err := tr.Proxy(httptest.NewRequest("GET", "/", nil)); err != nil || !called {
+		t.Error("expected the proxy func to be used")
+	}
+
+	c, err = NewClient(WithProxyURL(proxyURL), WithNoProxy())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tr, _ := getTransport(c); tr.Proxy != nil {
+		t.Error("expected proxying to be disabled")
+	}
+
+	for _, opt := range []ClientOption{WithProxyURL(nil), WithProxyFunc(nil)} {
+		if _, err := NewClient(opt); err == nil {
+			t.Error("expected an error for a nil proxy")
+		}
+	}
+}
+
+func TestWithTLSConfig(t *testing.T) {
+	ts := httptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+
+	// The server's certificate isn't trusted by default.
+	c, err := NewClient()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.Get(ts.URL); err == nil {
+		t.Error("expected an untrusted certificate to be rejected")
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	c, err = NewClient(WithTLSConfig(cfg), WithRootCAs(pool))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Errorf("expected HTTP/2 to still be negotiated, got %s", resp.Proto)
+	}
+	if cfg.RootCAs != nil {
+		t.Error("expected the caller's config not to be modified")
+	}
+	tr, _ := getTransport(c)
+	if tr.TLSClientConfig.MinVersion != tls.VersionTLS12 || tr.TLSHandshakeTimeout == 0 {
+		t.Error("expected the config and the handshake timeout to be kept")
+	}
+
+	c, err = NewClient(WithInsecureSkipVerify(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp, err = c.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	for _, opt := range []ClientOption{WithTLSConfig(nil), WithRootCAs(nil)} {
+		if _, err := NewClient(opt); err == nil {
+			t.Error("expected an error for a nil option")
+		}
+	}
+}
+
+// writeCertificate creates a self-signed certificate for name (and 127.0.0.1), usable by either a client or
+// a server, writing it and its key as PEM files in dir.
+func writeCertificate(t *testing.T, dir, name string) (cert *x509.Certificate, certPath, keyPath string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cert, err = x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	certPath, keyPath = filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	for path, block := range map[string]*pem.Block{
+		certPath: {Type: "CERTIFICATE", Bytes: der},
+		keyPath:  {Type: "EC PRIVATE KEY", Bytes: keyDER},
+	} {
+		if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	return cert, certPath, keyPath
+}
+
+func TestWithClientCertificate(t *testing.T) {
+	clientCert, certPath, keyPath := writeCertificate(t, t.TempDir(), "client")
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+
+	ts := httptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		if len(r.TLS.PeerCertificates) == 0 || r.TLS.PeerCertificates[0].Subject.CommonName != "client" {
+			t.Error("expected the client certificate to be presented")
+		}
+	}))
+	ts.TLS = &tls.Config{ClientCAs: clientCAs, ClientAuth: tls.RequireAndVerifyClientCert}
+	ts.StartTLS()
+	defer ts.Close()
+	serverCAs := x509.NewCertPool()
+	serverCAs.AddCert(ts.Certificate())
+
+	// Without a certificate, the handshake fails.
+	c, err := NewClient(WithRootCAs(serverCAs))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.Get(ts.URL); err == nil {
+		t.Error("expected the handshake to fail without a client certificate")
+	}
+
+	pair, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for name, opt := range map[string]ClientOption{
+		"certificate": WithClientCertificate(pair),
+		"files":       WithClientCertificateFiles(certPath, keyPath),
+	} {
+		t.Run(name, func(t *testing.T) {
+			c, err := NewClient(WithRootCAs(serverCAs), opt)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			resp, err := c.Get(ts.URL)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			_ = resp.Body.Close()
+		})
+	}
+
+	if _, err := NewClient(WithClientCertificateFiles(certPath, "missing.key")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a wrapped error for a missing key, got %v", err)
+	}
+	if _, err := NewClient(WithClientCertificate(tls.Certificate{})); err == nil {
+		t.Error("expected an error for an empty certificate")
+	}
+}
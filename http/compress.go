@@ -0,0 +1,270 @@
+package http
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"mime"
+	stdhttp "net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CompressionOption configures the response compression enabled by WithResponseCompression.
+type CompressionOption func(*compression) error
+
+// defaultCompressionMinSize is the smallest response compressed when no minimum is supplied. Smaller responses
+// gain little, and may even grow.
+const defaultCompressionMinSize = 1024
+
+// compression holds the configuration of response compression, and a pool of gzip writers at its level.
+type compression struct {
+	minSize int
+	level   int
+	writers sync.Pool
+}
+
+// WithCompressionMinSize replaces the size below which responses are not compressed (by default, 1KiB).
+func WithCompressionMinSize(n int) CompressionOption {
+	return func(c *compression) error {
+		if n < 0 {
+			return errors.New("compression min size must not be negative")
+		}
+		c.minSize = n
+		return nil
+	}
+}
+
+// WithCompressionLevel replaces the gzip compression level (by default, gzip.DefaultCompression).
+func WithCompressionLevel(level int) CompressionOption {
+	return func(c *compression) error {
+		if _, err := gzip.NewWriterLevel(io.Discard, level); err != nil {
+			return err
+		}
+		c.level = level
+		return nil
+	}
+}
+
+// WithResponseCompression gzip-compresses responses for clients that accept it (with Accept-Encoding: gzip),
+// setting Content-Encoding. Responses are compressed when their content type is compressible (not, e.g., an
+// image, video or archive) and they are at least the minimum size, and Vary: Accept-Encoding is added to every
+// response. Responses that already have a Content-Encoding, partial (206) responses, and responses to HEAD
+// requests are left as they are.
+//
+// The response is buffered until the minimum size is reached (or its Content-Length shows it will be), so
+// that small responses can be sent as they are. Flushing ends the buffering: a flushed response is streamed,
+// compressed if its content type is compressible, and each flush sends what has been compressed so far.
+//
+// The status code and body size recorded by the instrumentation are those sent to the client, so the body size
+// is the compressed size.
+func WithResponseCompression(opts ...CompressionOption) ServerOption {
+	return func(s *Server) error {
+		c := &compression{minSize: defaultCompressionMinSize, level: gzip.DefaultCompression}
+		for _, opt := range opts {
+			if err := opt(c); err != nil {
+				return err
+			}
+		}
+		s.compression = c
+		return nil
+	}
+}
+
+// getWriter returns a pooled gzip writer writing to w.
+func (c *compression) getWriter(w io.Writer) *gzip.Writer {
+	if gz, ok := c.writers.Get().(*gzip.Writer); ok {
+		gz.Reset(w)
+		return gz
+	}
+	// The level was validated by WithCompressionLevel.
+	gz, _ := gzip.NewWriterLevel(w, c.level)
+	return gz
+}
+
+// wrap prepares the response to r for compression, returning the writer the handler should use and a function
+// to call once it has returned. If r does not accept gzip, w is returned as it is.
+func (c *compression) wrap(w stdhttp.ResponseWriter, r *stdhttp.Request) (stdhttp.ResponseWriter, func()) {
+	w.Header().Add("Vary", "Accept-Encoding")
+	if r.Method == stdhttp.MethodHead || !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+		return w, func() {}
+	}
+	cw := &compressWriter{ResponseWriter: w, c: c}
+	return cw, cw.finish
+}
+
+// acceptsGzip reports whether an Accept-Encoding header accepts gzip.
+func acceptsGzip(header string) bool {
+	for _, coding := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(coding, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "gzip" && name != "*" {
+			continue
+		}
+		q, ok := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !ok {
+			return true
+		}
+		weight, err := strconv.ParseFloat(q, 64)
+		return err == nil && weight > 0
+	}
+	return false
+}
+
+// isCompressible reports whether a content type is worth compressing. Most media and archive formats are
+// compressed already.
+func isCompressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case mediaType == "image/svg+xml":
+		return true
+	case strings.HasPrefix(mediaType, "image/"), strings.HasPrefix(mediaType, "video/"),
+		strings.HasPrefix(mediaType, "audio/"), strings.HasPrefix(mediaType, "font/woff"):
+		return false
+	}
+	switch mediaType {
+	case "application/octet-stream", "application/zip", "application/gzip", "application/x-gzip",
+		"application/zstd", "application/x-bzip2", "application/x-xz", "application/x-7z-compressed",
+		"application/vnd.rar", "application/x-rar-compressed", "application/pdf":
+		return false
+	}
+	return true
+}
+
+// compressWriter compresses a response, as described by WithResponseCompression. Until it has decided whether
+// to compress, it holds back the status code and buffers the body.
+type compressWriter struct {
+	stdhttp.ResponseWriter
+	c *compression
+
+	// status is the status code held back until the decision is made, or zero if none has been written.
+	status  int
+	buf     []byte
+	decided bool
+	gz      *gzip.Writer
+}
+
+func (w *compressWriter) WriteHeader(statusCode int) {
+	// Informational responses are sent as they are, and anything after the decision is passed on.
+	if w.decided || statusCode < 200 {
+		w.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	if w.status != 0 {
+		return
+	}
+	w.status = statusCode
+	if !bodyAllowedForStatus(statusCode) {
+		w.decide(false)
+	} else if n, err := strconv.Atoi(w.Header().Get("Content-Length")); err == nil {
+		w.decide(n >= w.c.minSize)
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.decided {
+		if w.gz != nil {
+			return w.gz.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.c.minSize {
+		w.decide(true)
+		if err := w.writeBuffered(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush implements http.Flusher.
+func (w *compressWriter) Flush() {
+	_ = w.FlushError()
+}
+
+// FlushError flushes the response, as used by http.ResponseController. A response that hasn't yet reached the
+// minimum size is compressed anyway, as it is being streamed.
+func (w *compressWriter) FlushError() error {
+	if !w.decided {
+		w.decide(true)
+		if err := w.writeBuffered(); err != nil {
+			return err
+		}
+	}
+	if w.gz != nil {
+		if err := w.gz.Flush(); err != nil {
+			return err
+		}
+	}
+	return stdhttp.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying ResponseWriter, allowing http.ResponseController to reach it.
+func (w *compressWriter) Unwrap() stdhttp.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// decide decides whether to compress the response, given whether it is large enough, and sends the status
+// code that was held back.
+func (w *compressWriter) decide(large bool) {
+	w.decided = true
+	h := w.Header()
+	contentType := h.Get("Content-Type")
+	if _, ok := h["Content-Type"]; !ok && len(w.buf) > 0 {
+		contentType = stdhttp.DetectContentType(w.buf)
+	}
+	if large && w.status != stdhttp.StatusPartialContent && h.Get("Content-Encoding") == "" &&
+		h.Get("Content-Range") == "" && isCompressible(contentType) {
+		// net/http would otherwise sniff the compressed body.
+		if _, ok := h["Content-Type"]; !ok {
+			h.Set("Content-Type", contentType)
+		}
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", "gzip")
+		w.gz = w.c.getWriter(w.ResponseWriter)
+	}
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+// writeBuffered writes out the body buffered before the decision.
+func (w *compressWriter) writeBuffered() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	buf := w.buf
+	w.buf = nil
+	var err error
+	if w.gz != nil {
+		_, err = w.gz.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// finish sends what remains of the response once the handler has returned. A response still buffered is
+// smaller than the minimum size, so is sent uncompressed.
+func (w *compressWriter) finish() {
+	if !w.decided {
+		w.decide(false)
+		_ = w.writeBuffered()
+	}
+	if w.gz != nil {
+		_ = w.gz.Close()
+		w.gz.Reset(io.Discard)
+		w.c.writers.Put(w.gz)
+		w.gz = nil
+	}
+}
+
+// bodyAllowedForStatus reports whether a response with the given status may have a body, per RFC 9110.
+func bodyAllowedForStatus(status int) bool {
+	return status != stdhttp.StatusNoContent && status != stdhttp.StatusNotModified && status >= 200
+}
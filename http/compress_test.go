@@ -0,0 +1,161 @@
+package http
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"io"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+func TestWithResponseCompression(t *testing.T) {
+	large := strings.Repeat("compressible text ", 100)
+	handler := stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		switch r.URL.Path {
+		case "/large":
+			w.WriteHeader(stdhttp.StatusCreated)
+			_, _ = io.WriteString(w, large)
+		case "/small":
+			_, _ = io.WriteString(w, "small")
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = io.WriteString(w, large)
+		case "/encoded":
+			w.Header().Set("Content-Encoding", "br")
+			_, _ = io.WriteString(w, large)
+		case "/length":
+			w.Header().Set("Content-Length", strconv.Itoa(len(large)))
+			_, _ = io.WriteString(w, large)
+		case "/no-content":
+			w.WriteHeader(stdhttp.StatusNoContent)
+		}
+	})
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+	srv, err := NewServer(":0", handler, WithServerTracerProvider(tp), WithResponseCompression())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv.server.Handler)
+	defer ts.Close()
+
+	for _, tc := range []struct {
+		path           string
+		acceptEncoding string
+		wantStatus     int
+		wantGzip       bool
+		wantBody       string
+	}{
+		{path: "/large", acceptEncoding: "gzip", wantStatus: 201, wantGzip: true, wantBody: large},
+		{path: "/large", acceptEncoding: "br, gzip;q=0.5", wantStatus: 201, wantGzip: true, wantBody: large},
+		{path: "/large", acceptEncoding: "gzip;q=0", wantStatus: 201, wantBody: large},
+		{path: "/large", wantStatus: 201, wantBody: large},
+		{path: "/small", acceptEncoding: "gzip", wantStatus: 200, wantBody: "small"},
+		{path: "/image", acceptEncoding: "gzip", wantStatus: 200, wantBody: large},
+		{path: "/encoded", acceptEncoding: "gzip", wantStatus: 200, wantBody: large},
+		{path: "/length", acceptEncoding: "gzip", wantStatus: 200, wantGzip: true, wantBody: large},
+		{path: "/no-content", acceptEncoding: "gzip", wantStatus: 204},
+	} {
+		t.Run(tc.path+" "+tc.acceptEncoding, func(t *testing.T) {
+			exporter.Reset()
+			req, _ := stdhttp.NewRequest("GET", ts.URL+tc.path, nil)
+			// Setting Accept-Encoding stops the transport from decoding the response itself.
+			req.Header.Set("Accept-Encoding", tc.acceptEncoding)
+			resp, err := stdhttp.DefaultTransport.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer func() { _ = resp.Body.Close() }()
+
+			if resp.StatusCode != tc.wantStatus {
+				t.Errorf("expected status %d, got %d", tc.wantStatus, resp.StatusCode)
+			}
+			if got := resp.Header.Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("expected Vary: Accept-Encoding, got %q", got)
+			}
+			body := resp.Body
+			if gzipped := resp.Header.Get("Content-Encoding") == "gzip"; gzipped != tc.wantGzip {
+				t.Fatalf("expected gzip %v, got Content-Encoding %q", tc.wantGzip, resp.Header.Get("Content-Encoding"))
+			} else if gzipped {
+				if body, err = gzip.NewReader(resp.Body); err != nil {
+					t.Fatalf("expected a gzip body: %v", err)
+				}
+				if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+					t.Errorf("expected the uncompressed body's content type, got %q", ct)
+				}
+			}
+			if got, _ := io.ReadAll(body); string(got) != tc.wantBody {
+				t.Errorf("expected the original body, got %d bytes", len(got))
+			}
+
+			status := semconv.HTTPResponseStatusCode(tc.wantStatus)
+			if !hasAttr(exporter.GetSpans()[0].Attributes, status) {
+				t.Errorf("expected the span to record status %d", tc.wantStatus)
+			}
+		})
+	}
+}
+
+func TestWithResponseCompression_Flush(t *testing.T) {
+	next := make(chan struct{})
+	handler := stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: first\n\n")
+		if err := stdhttp.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("unexpected flush error: %v", err)
+		}
+		<-next
+		_, _ = io.WriteString(w, "data: second\n\n")
+	})
+
+	srv, err := NewServer(":0", handler, WithResponseCompression())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv.server.Handler)
+	defer ts.Close()
+
+	req, _ := stdhttp.NewRequestWithContext(context.Background(), "GET", ts.URL, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := stdhttp.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected a flushed stream to be compressed, got %q", resp.Header.Get("Content-Encoding"))
+	}
+
+	// The first event is readable before the handler has written the second.
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatalf("expected a gzip body: %v", err)
+	}
+	events := bufio.NewReader(gz)
+	if line, err := events.ReadString('\n'); err != nil || line != "data: first\n" {
+		t.Fatalf("expected the first event, got %q (%v)", line, err)
+	}
+	close(next)
+	rest, _ := io.ReadAll(events)
+	if string(rest) != "\ndata: second\n\n" {
+		t.Errorf("expected the second event, got %q", rest)
+	}
+}
+
+func TestWithResponseCompression_Invalid(t *testing.T) {
+	if _, err := NewServer(":0", nil, WithResponseCompression(WithCompressionMinSize(-1))); err == nil {
+		t.Error("expected an error for a negative min size")
+	}
+	if _, err := NewServer(":0", nil, WithResponseCompression(WithCompressionLevel(42))); err == nil {
+		t.Error("expected an error for an invalid level")
+	}
+}
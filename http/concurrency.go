@@ -0,0 +1,107 @@
+package http
+
+import (
+	"context"
+	"errors"
+	stdhttp "net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/semaphore"
+)
+
+// Reasons a request is throttled, recorded on the http.client.throttled span event.
+const (
+	throttleRateLimit        = "rate_limit"
+	throttleConcurrencyLimit = "concurrency_limit"
+)
+
+// WithMaxConcurrentRequests limits the client to n requests in flight at once, to avoid overwhelming a
+// fragile dependency. A request is in flight from when it is sent until its response body is closed (or, for
+// responses from WithRawResponse, until the response headers arrive). Requests over the limit wait for
+// another to finish, for as long as their context allows, rather than failing. Every attempt counts,
+// including retries.
+//
+// Requests that had to wait are counted in http.client.concurrency_limit.throttled, and the wait is recorded
+// as an http.client.throttled event on the request span.
+func WithMaxConcurrentRequests(n int) ClientOption {
+	return func(c *stdhttp.Client) error {
+		if n < 1 {
+			return errors.New("max concurrent requests must be at least 1")
+		}
+
+		cl := &concurrencyLimitTransport{slots: semaphore.NewWeighted(int64(n))}
+		wrapInnermost(c, func(base stdhttp.RoundTripper) stdhttp.RoundTripper {
+			cl.base = base
+			return cl
+		})
+		return nil
+	}
+}
+
+// concurrencyLimitTransport is a RoundTripper that waits for a free slot before each request.
+type concurrencyLimitTransport struct {
+	base stdhttp.RoundTripper
+
+	// slots has a unit of weight for each request that may be in flight.
+	slots *semaphore.Weighted
+
+	mThrottled metric.Int64Counter
+}
+
+func (t *concurrencyLimitTransport) unwrap() stdhttp.RoundTripper {
+	return t.base
+}
+
+func (t *concurrencyLimitTransport) setBase(base stdhttp.RoundTripper) {
+	t.base = base
+}
+
+func (t *concurrencyLimitTransport) resilience() {}
+
+func (t *concurrencyLimitTransport) instrument(meter metric.Meter) error {
+	var err error
+	t.mThrottled, err = meter.Int64Counter("http.client.concurrency_limit.throttled")
+	return err
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *concurrencyLimitTransport) RoundTrip(req *stdhttp.Request) (*stdhttp.Response, error) {
+	if !t.slots.TryAcquire(1) {
+		ctx := req.Context()
+		if t.mThrottled != nil {
+			t.mThrottled.Add(ctx, 1, metric.WithAttributes(clientRequestAttrs(req)...))
+		}
+		start := time.Now()
+		err := t.slots.Acquire(ctx, 1)
+		recordThrottled(ctx, throttleConcurrencyLimit, time.Since(start))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	release := sync.OnceFunc(func() { t.slots.Release(1) })
+	resp, err := t.base.RoundTrip(req)
+	if resp == nil || resp.StatusCode == stdhttp.StatusSwitchingProtocols || isRawResponse(req) {
+		release()
+		return resp, err
+	}
+	resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: release}
+	return resp, err
+}
+
+// recordThrottled records on the request span that the request waited to be sent because of a client-side
+// limit, so that slowness caused by local throttling can be told apart from a slow dependency. The wait is also
+// added to the logical request, if there is one.
+func recordThrottled(ctx context.Context, reason string, wait time.Duration) {
+	if lr := logicalRequestFromContext(ctx); lr != nil {
+		lr.throttleWait.Add(int64(wait))
+	}
+	trace.SpanFromContext(ctx).AddEvent("http.client.throttled", trace.WithAttributes(
+		attribute.String("http.client.throttle.reason", reason),
+		attribute.Float64("http.client.throttle.wait_time", wait.Seconds()),
+	))
+}
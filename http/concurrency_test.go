@@ -0,0 +1,122 @@
+package http
+
+import (
+	"context"
+	"errors"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestWithMaxConcurrentRequests(t *testing.T) {
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {}))
+	defer ts.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	c, err := NewClient(WithClientTracerProvider(tp), WithClientMeterProvider(mp), WithMaxConcurrentRequests(1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Waits are recorded on the request span, which is the caller's.
+	ctx, span := tp.Tracer("test").Start(context.Background(), "parent")
+	get := func() (*stdhttp.Response, error) {
+		req, _ := stdhttp.NewRequestWithContext(ctx, stdhttp.MethodGet, ts.URL, nil)
+		return c.Do(req)
+	}
+
+	// The first request is in flight until its body is closed.
+	first, err := get()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		resp, err := get()
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		done <- err
+	}()
+	select {
+	case <-done:
+		t.Fatal("expected the second request to wait for the first")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	_ = first.Body.Close()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the second request to be sent once the first finished")
+	}
+
+	if got := sumCounter(t, reader, "http.client.concurrency_limit.throttled"); got != 1 {
+		t.Errorf("expected 1 throttled request, got %d", got)
+	}
+	span.End()
+	if got := throttledEvents(exporter.GetSpans(), "concurrency_limit"); got != 1 {
+		t.Errorf("expected 1 throttled span event, got %d", got)
+	}
+
+	if _, err := NewClient(WithMaxConcurrentRequests(0)); err == nil {
+		t.Error("expected an error for a limit below 1")
+	}
+}
+
+func TestWithMaxConcurrentRequests_ContextCancelled(t *testing.T) {
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {}))
+	defer ts.Close()
+
+	c, err := NewClient(WithMaxConcurrentRequests(1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	first, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = first.Body.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, _ := stdhttp.NewRequestWithContext(ctx, stdhttp.MethodGet, ts.URL, nil)
+	if _, err := c.Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the wait to end with the context, got %v", err)
+	}
+
+	// The abandoned wait doesn't take a slot.
+	_ = first.Body.Close()
+	resp, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+}
+
+// throttledEvents counts the http.client.throttled span events with the given reason.
+func throttledEvents(spans tracetest.SpanStubs, reason string) int {
+	var n int
+	for _, s := range spans {
+		for _, e := range s.Events {
+			if e.Name == "http.client.throttled" &&
+				hasAttr(e.Attributes, attribute.String("http.client.throttle.reason", reason)) {
+				n++
+			}
+		}
+	}
+	return n
+}
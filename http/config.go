@@ -0,0 +1,157 @@
+package http
+
+import (
+	stdhttp "net/http"
+	"time"
+)
+
+// ClientConfig is a struct alternative to ClientOptions, for clients configured from a file or the
+// environment. Zero values mean the package default is kept.
+type ClientConfig struct {
+	// Timeout is the overall request timeout. See WithTimeout.
+	Timeout time.Duration
+
+	// ConnectTimeout is the timeout for establishing a connection. See WithConnectTimeout.
+	ConnectTimeout time.Duration
+
+	// TLSHandshakeTimeout is the timeout for the TLS handshake. See WithTLSHandshakeTimeout.
+	TLSHandshakeTimeout time.Duration
+
+	// ResponseHeaderTimeout is the timeout for receiving response headers. See WithResponseHeaderTimeout.
+	ResponseHeaderTimeout time.Duration
+
+	// ExpectContinueTimeout is the timeout for a 100-continue response. See WithExpectContinueTimeout.
+	ExpectContinueTimeout time.Duration
+
+	// MaxIdleConns is the maximum number of idle connections. See WithMaxIdleConns.
+	MaxIdleConns int
+
+	// IdleConnTimeout is how long an idle connection is kept. See WithIdleConnTimeout.
+	IdleConnTimeout time.Duration
+}
+
+// options translates the config into the equivalent options, skipping zero values.
+func (cfg ClientConfig) options() []ClientOption {
+	var opts []ClientOption
+	if cfg.Timeout != 0 {
+		opts = append(opts, WithTimeout(cfg.Timeout))
+	}
+	if cfg.ConnectTimeout != 0 {
+		opts = append(opts, WithConnectTimeout(cfg.ConnectTimeout))
+	}
+	if cfg.TLSHandshakeTimeout != 0 {
+		opts = append(opts, WithTLSHandshakeTimeout(cfg.TLSHandshakeTimeout))
+	}
+	if cfg.ResponseHeaderTimeout != 0 {
+		opts = append(opts, WithResponseHeaderTimeout(cfg.ResponseHeaderTimeout))
+	}
+	if cfg.ExpectContinueTimeout != 0 {
+		opts = append(opts, WithExpectContinueTimeout(cfg.ExpectContinueTimeout))
+	}
+	if cfg.MaxIdleConns != 0 {
+		opts = append(opts, WithMaxIdleConns(cfg.MaxIdleConns))
+	}
+	if cfg.IdleConnTimeout != 0 {
+		opts = append(opts, WithIdleConnTimeout(cfg.IdleConnTimeout))
+	}
+	return opts
+}
+
+// NewClientFromConfig returns a new client configured from cfg. Any opts are applied after the config, for
+// settings it does not cover (such as providers).
+func NewClientFromConfig(cfg ClientConfig, opts ...ClientOption) (*stdhttp.Client, error) {
+	return NewClient(append(cfg.options(), opts...)...)
+}
+
+// ServerConfig is a struct alternative to ServerOptions, for servers configured from a file or the
+// environment. Zero values mean the package default is kept.
+type ServerConfig struct {
+	// ReadTimeout is the timeout for reading the request. See WithReadTimeout.
+	ReadTimeout time.Duration
+
+	// WriteTimeout is the timeout for writing the response. See WithWriteTimeout.
+	WriteTimeout time.Duration
+
+	// IdleTimeout is how long an idle keep-alive connection is kept. See WithIdleTimeout.
+	IdleTimeout time.Duration
+
+	// ReadHeaderTimeout is the timeout for reading the request headers. See WithReadHeaderTimeout.
+	ReadHeaderTimeout time.Duration
+
+	// MaxHeaderBytes is the maximum size of the request headers, in bytes. See WithMaxHeaderBytes.
+	MaxHeaderBytes int
+
+	// BodyReadTimeout is the timeout for reading the request body. See WithBodyReadTimeout.
+	BodyReadTimeout time.Duration
+
+	// MaxRequestBodySize is the maximum size of request bodies, in bytes. See WithMaxRequestBodySize.
+	MaxRequestBodySize int64
+
+	// MaxOpenConnections is the maximum number of open connections. See WithMaxOpenConnections.
+	MaxOpenConnections int
+
+	// RejectOnShutdown rejects new requests once shutdown begins. See WithRejectOnShutdown.
+	RejectOnShutdown bool
+
+	// HTTP2MaxConcurrentStreams is the maximum number of concurrent streams per HTTP/2 connection. See
+	// WithHTTP2MaxConcurrentStreams.
+	HTTP2MaxConcurrentStreams int
+
+	// ShutdownTimeout bounds graceful shutdown. See WithShutdownTimeout.
+	ShutdownTimeout time.Duration
+
+	// TLSCertFile and TLSKeyFile are the PEM-encoded certificate and key to serve HTTPS with. See
+	// WithServerTLS.
+	TLSCertFile string
+	TLSKeyFile  string
+}
+
+// options translates the config into the equivalent options, skipping zero values.
+func (cfg ServerConfig) options() []ServerOption {
+	var opts []ServerOption
+	if cfg.ReadTimeout != 0 {
+		opts = append(opts, WithReadTimeout(cfg.ReadTimeout))
+	}
+	if cfg.WriteTimeout != 0 {
+		opts = append(opts, WithWriteTimeout(cfg.WriteTimeout))
+	}
+	if cfg.IdleTimeout != 0 {
+		opts = append(opts, WithIdleTimeout(cfg.IdleTimeout))
+	}
+	if cfg.ReadHeaderTimeout != 0 {
+		opts = append(opts, WithReadHeaderTimeout(cfg.ReadHeaderTimeout))
+	}
+	if cfg.MaxHeaderBytes != 0 {
+		opts = append(opts, WithMaxHeaderBytes(cfg.MaxHeaderBytes))
+	}
+	if cfg.BodyReadTimeout != 0 {
+		opts = append(opts, WithBodyReadTimeout(cfg.BodyReadTimeout))
+	}
+	if cfg.MaxRequestBodySize != 0 {
+		opts = append(opts, WithMaxRequestBodySize(cfg.MaxRequestBodySize))
+	}
+	if cfg.MaxOpenConnections != 0 {
+		opts = append(opts, WithMaxOpenConnections(cfg.MaxOpenConnections))
+	}
+	if cfg.RejectOnShutdown {
+		opts = append(opts, WithRejectOnShutdown())
+	}
+	if cfg.HTTP2MaxConcurrentStreams != 0 {
+		opts = append(opts, WithHTTP2MaxConcurrentStreams(cfg.HTTP2MaxConcurrentStreams))
+	}
+	if cfg.ShutdownTimeout != 0 {
+		opts = append(opts, WithShutdownTimeout(cfg.ShutdownTimeout))
+	}
+	if cfg.TLSCertFile != "" || cfg.TLSKeyFile != "" {
+		opts = append(opts, WithServerTLS(cfg.TLSCertFile, cfg.TLSKeyFile))
+	}
+	return opts
+}
+
+// NewServerFromConfig returns a new server configured from cfg. Any opts are applied after the config, for
+// settings it does not cover (such as providers).
+func NewServerFromConfig(
+	addr string, handler stdhttp.Handler, cfg ServerConfig, opts ...ServerOption,
+) (*Server, error) {
+	return NewServer(addr, handler, append(cfg.options(), opts...)...)
+}
@@ -0,0 +1,102 @@
+package http
+
+import (
+	stdhttp "net/http"
+	"testing"
+	"time"
+)
+
+func TestNewClientFromConfig(t *testing.T) {
+	fromConfig, err := NewClientFromConfig(ClientConfig{
+		Timeout:               5 * time.Second,
+		TLSHandshakeTimeout:   time.Second,
+		ResponseHeaderTimeout: 3 * time.Second,
+		ExpectContinueTimeout: 2 * time.Second,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fromOptions, err := NewClient(
+		WithTimeout(5*time.Second),
+		WithTLSHandshakeTimeout(time.Second),
+		WithResponseHeaderTimeout(3*time.Second),
+		WithExpectContinueTimeout(2*time.Second),
+		WithMaxIdleConns(10),
+		WithIdleConnTimeout(time.Minute),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if fromConfig.Timeout != fromOptions.Timeout {
+		t.Errorf("expected timeout %v, got %v", fromOptions.Timeout, fromConfig.Timeout)
+	}
+	got, _ := getTransport(fromConfig)
+	want, _ := getTransport(fromOptions)
+	if got.TLSHandshakeTimeout != want.TLSHandshakeTimeout ||
+		got.ResponseHeaderTimeout != want.ResponseHeaderTimeout ||
+		got.ExpectContinueTimeout != want.ExpectContinueTimeout ||
+		got.MaxIdleConns != want.MaxIdleConns ||
+		got.IdleConnTimeout != want.IdleConnTimeout {
+		t.Errorf("expected transport to match the equivalent options")
+	}
+}
+
+func TestNewClientFromConfig_ZeroValueKeepsDefaults(t *testing.T) {
+	fromConfig, err := NewClientFromConfig(ClientConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defaults, err := NewClient()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if fromConfig.Timeout != defaults.Timeout {
+		t.Errorf("expected default timeout %v, got %v", defaults.Timeout, fromConfig.Timeout)
+	}
+	got, _ := getTransport(fromConfig)
+	want, _ := getTransport(defaults)
+	if got.ResponseHeaderTimeout != want.ResponseHeaderTimeout || got.MaxIdleConns != want.MaxIdleConns {
+		t.Errorf("expected transport defaults to be kept")
+	}
+}
+
+func TestNewServerFromConfig(t *testing.T) {
+	fromConfig, err := NewServerFromConfig(":0", stdhttp.NotFoundHandler(), ServerConfig{
+		ReadTimeout:        5 * time.Second,
+		MaxHeaderBytes:     1 << 10,
+		BodyReadTimeout:    time.Second,
+		MaxOpenConnections: 10,
+		RejectOnShutdown:   true,
+		ShutdownTimeout:    time.Second,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fromOptions, err := NewServer(":0", stdhttp.NotFoundHandler(),
+		WithReadTimeout(5*time.Second),
+		WithMaxHeaderBytes(1<<10),
+		WithBodyReadTimeout(time.Second),
+		WithMaxOpenConnections(10),
+		WithRejectOnShutdown(),
+		WithShutdownTimeout(time.Second),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if fromConfig.server.ReadTimeout != fromOptions.server.ReadTimeout ||
+		fromConfig.server.WriteTimeout != fromOptions.server.WriteTimeout ||
+		fromConfig.server.IdleTimeout != fromOptions.server.IdleTimeout ||
+		fromConfig.server.ReadHeaderTimeout != fromOptions.server.ReadHeaderTimeout ||
+		fromConfig.server.MaxHeaderBytes != fromOptions.server.MaxHeaderBytes ||
+		fromConfig.bodyReadTimeout != fromOptions.bodyReadTimeout ||
+		fromConfig.maxOpenConns != fromOptions.maxOpenConns ||
+		fromConfig.rejectOnShutdown != fromOptions.rejectOnShutdown ||
+		fromConfig.shutdownTimeout != fromOptions.shutdownTimeout {
+		t.Errorf("expected server to match the equivalent options")
+	}
+}
@@ -0,0 +1,82 @@
+package http
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// connInfoKey is the context key for the connInfo of the connection a request arrived on.
+type connInfoKey struct{}
+
+// connInfo tracks when a connection was accepted and when it last became active, so that the time a
+// request spends waiting to be handled can be measured.
+type connInfo struct {
+	acceptedAt time.Time
+
+	// activeAt is when the connection last became active (started reading a request), in Unix nanoseconds,
+	// or zero once it has been used for a measurement.
+	activeAt atomic.Int64
+
+	// requests is the number of requests that have started on the connection.
+	requests atomic.Int64
+
+	// streams and peakStreams are the current and peak number of HTTP/2 requests being handled.
+	streams     atomic.Int64
+	peakStreams atomic.Int64
+
+	// awaitingHandler is set when the connection becomes active, and cleared when a handler starts. A
+	// connection that closes while it is set read (part of) a request that was never handled.
+	awaitingHandler atomic.Bool
+}
+
+// connInfoFromContext returns the connInfo stored in ctx, or nil.
+func connInfoFromContext(ctx context.Context) *connInfo {
+	ci, _ := ctx.Value(connInfoKey{}).(*connInfo)
+	return ci
+}
+
+// queueStart returns when the request now starting began waiting to be handled, or the zero time if it
+// cannot be known. The first request on a connection has waited since the connection was accepted; later
+// ones since the connection became active again. Requests multiplexed on an already-active HTTP/2
+// connection have no such timestamp.
+func (ci *connInfo) queueStart() time.Time {
+	active := ci.activeAt.Swap(0)
+	if ci.requests.Add(1) == 1 {
+		return ci.acceptedAt
+	}
+	if active == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, active)
+}
+
+// startStream records an HTTP/2 request starting on the connection, returning the number now being handled.
+func (ci *connInfo) startStream() int64 {
+	n := ci.streams.Add(1)
+	for {
+		peak := ci.peakStreams.Load()
+		if n <= peak || ci.peakStreams.CompareAndSwap(peak, n) {
+			return n
+		}
+	}
+}
+
+// endStream records an HTTP/2 request finishing on the connection.
+func (ci *connInfo) endStream() {
+	ci.streams.Add(-1)
+}
+
+// connContext stamps each accepted connection with a connInfo, before calling any registered hook.
+func (s *Server) connContext(ctx context.Context, c net.Conn) context.Context {
+	if s.queueTimeMetrics || s.http2MaxStreams > 0 || s.mMalformedRequests != nil {
+		ci := &connInfo{acceptedAt: time.Now()}
+		s.conns.Store(c, ci)
+		ctx = context.WithValue(ctx, connInfoKey{}, ci)
+	}
+	for _, hook := range s.connContextHooks {
+		ctx = hook(ctx, c)
+	}
+	return ctx
+}
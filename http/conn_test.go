@@ -0,0 +1,179 @@
+package http
+
+import (
+	"context"
+	"net"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+type connTestKey struct{}
+
+func TestServer_QueueTimeMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	hooked := make(chan bool, 10)
+	handler := stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		hooked <- r.Context().Value(connTestKey{}) != nil
+	})
+	s, err := NewServer(":0", handler,
+		WithServerMeterProvider(mp),
+		WithQueueTimeMetrics(),
+		WithConnContext(func(ctx context.Context, _ net.Conn) context.Context {
+			return context.WithValue(ctx, connTestKey{}, true)
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	go func() { _ = s.server.Serve(l) }()
+	defer func() { _ = s.shutdown(context.Background()) }()
+
+	// Two requests on the same kept-alive connection are both measured.
+	client := &stdhttp.Client{Timeout: time.Second}
+	for range 2 {
+		resp, err := client.Get("http://" + l.Addr().String())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		_ = resp.Body.Close()
+		if !<-hooked {
+			t.Error("expected the conn context hook to have run")
+		}
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	var count uint64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if h, ok := m.Data.(metricdata.Histogram[float64]); ok && m.Name == "http.server.request.queue_time" {
+				for _, dp := range h.DataPoints {
+					count += dp.Count
+				}
+			}
+		}
+	}
+	if count != 2 {
+		t.Errorf("expected 2 queue time recordings, got %d", count)
+	}
+}
+
+func TestConnInfo_QueueStart(t *testing.T) {
+	accepted := time.Now().Add(-time.Second)
+	ci := &connInfo{acceptedAt: accepted}
+
+	if got := ci.queueStart(); !got.Equal(accepted) {
+		t.Errorf("expected the first request to queue from accept, got %v", got)
+	}
+
+	// A second stream on an already-active connection has no timestamp to measure from.
+	if got := ci.queueStart(); !got.IsZero() {
+		t.Errorf("expected no queue start without activation, got %v", got)
+	}
+
+	active := time.Now()
+	ci.activeAt.Store(active.UnixNano())
+	if got := ci.queueStart(); !got.Equal(time.Unix(0, active.UnixNano())) {
+		t.Errorf("expected a kept-alive request to queue from activation, got %v", got)
+	}
+}
+
+func TestServer_HTTP2MaxConcurrentStreams(t *testing.T) {
+	if _, err := NewServer(":0", stdhttp.NotFoundHandler(), WithHTTP2MaxConcurrentStreams(0)); err == nil {
+		t.Error("expected an error for a zero stream limit")
+	}
+
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	handler := stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {})
+	s, err := NewServer(":0", handler, WithServerMeterProvider(mp), WithHTTP2MaxConcurrentStreams(1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.server.HTTP2 == nil || s.server.HTTP2.MaxConcurrentStreams != 1 {
+		t.Fatalf("expected the HTTP/2 stream limit to be configured, got %+v", s.server.HTTP2)
+	}
+
+	ts := httptest.NewUnstartedServer(s.server.Handler)
+	ts.Config.HTTP2 = s.server.HTTP2
+	ts.Config.ConnContext = s.connContext
+	ts.Config.ConnState = s.connState
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+
+	resp, err := ts.Client().Get(ts.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("expected an HTTP/2 response, got %s", resp.Proto)
+	}
+	ts.Close()
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	var atLimit int64
+	var peaks uint64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				if m.Name == "http.server.http2.streams_at_limit" {
+					for _, dp := range data.DataPoints {
+						atLimit += dp.Value
+					}
+				}
+			case metricdata.Histogram[int64]:
+				if m.Name == "http.server.http2.peak_concurrent_streams" {
+					for _, dp := range data.DataPoints {
+						peaks += dp.Count
+					}
+				}
+			}
+		}
+	}
+	if atLimit != 1 {
+		t.Errorf("expected 1 request at the stream limit, got %d", atLimit)
+	}
+	if peaks != 1 {
+		t.Errorf("expected 1 peak stream recording, got %d", peaks)
+	}
+}
+
+func TestConnInfo_Streams(t *testing.T) {
+	ci := &connInfo{}
+
+	if n := ci.startStream(); n != 1 {
+		t.Errorf("expected 1 stream, got %d", n)
+	}
+	if n := ci.startStream(); n != 2 {
+		t.Errorf("expected 2 streams, got %d", n)
+	}
+	ci.endStream()
+	ci.endStream()
+	if n := ci.startStream(); n != 1 {
+		t.Errorf("expected 1 stream, got %d", n)
+	}
+
+	if peak := ci.peakStreams.Load(); peak != 2 {
+		t.Errorf("expected a peak of 2 streams, got %d", peak)
+	}
+}
@@ -0,0 +1,187 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	stdhttp "net/http"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WithConnectionSpans starts a child span of the request span covering the establishment of each new
+// connection: DNS resolution, connecting and the TLS handshake, each recorded as events on it. Requests that
+// reuse a pooled connection have no connection span. This makes connection churn and slow connection setup
+// visible in traces, at the cost of an extra span per new connection.
+//
+// net/http may establish a connection for one request and then hand it to another, or finish establishing it
+// after the request that started it has given up; the span belongs to the request that started it.
+func WithConnectionSpans() ClientOption {
+	return func(c *stdhttp.Client) error {
+		it, ok := c.Transport.(*InstrumentedTransport)
+		if !ok {
+			return errors.New("client transport must be *InstrumentedTransport")
+		}
+		it.connectionSpans = true
+		return nil
+	}
+}
+
+// connectionSpan is the span covering the establishment of a connection. It is started lazily by the first
+// connection setup event, so that requests using a pooled connection have none. net/http may call the hooks
+// from the goroutine establishing the connection, concurrently with and after the request, so it is safe for
+// concurrent use and ignores events once ended.
+type connectionSpan struct {
+	tracer trace.Tracer
+	ctx    context.Context
+
+	mu       sync.Mutex
+	hostPort string
+	span     trace.Span
+	ended    bool
+}
+
+// event records an event on the span, starting it if needed. It returns the span, or nil if it has ended.
+func (cs *connectionSpan) event(name string, attrs ...attribute.KeyValue) trace.Span {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if cs.ended {
+		return nil
+	}
+	if cs.span == nil {
+		host, port := splitHostPort(cs.hostPort)
+		spanAttrs := []attribute.KeyValue{semconv.ServerAddressKey.String(host)}
+		if p, err := strconv.Atoi(port); err == nil {
+			spanAttrs = append(spanAttrs, semconv.ServerPortKey.Int(p))
+		}
+		_, cs.span = cs.tracer.Start(cs.ctx, "HTTP connect",
+			trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(spanAttrs...))
+	}
+	cs.span.AddEvent(name, trace.WithAttributes(attrs...))
+	return cs.span
+}
+
+// fail records err on the span, if it has started and not yet ended.
+func (cs *connectionSpan) fail(name string, err error, attrs ...attribute.KeyValue) {
+	span := cs.event(name, append(attrs, semconv.ErrorTypeKey.String(errorType(err)))...)
+	if span != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// end ends the span, if it was started, recording err if it is set.
+func (cs *connectionSpan) end(err error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if cs.ended || cs.span == nil {
+		return
+	}
+	cs.ended = true
+	if err != nil {
+		cs.span.RecordError(err)
+		cs.span.SetStatus(codes.Error, err.Error())
+		cs.span.SetAttributes(semconv.ErrorTypeKey.String(errorType(err)))
+	}
+	cs.span.End()
+}
+
+// hook wraps the connection setup hooks of ct to record them on the span.
+func (cs *connectionSpan) hook(ct *httptrace.ClientTrace) {
+	originalGetConn := ct.GetConn
+	ct.GetConn = func(hostPort string) {
+		cs.mu.Lock()
+		cs.hostPort = hostPort
+		cs.mu.Unlock()
+		if originalGetConn != nil {
+			originalGetConn(hostPort)
+		}
+	}
+
+	originalDNSStart := ct.DNSStart
+	ct.DNSStart = func(info httptrace.DNSStartInfo) {
+		cs.event("dns.start", semconv.ServerAddressKey.String(info.Host))
+		if originalDNSStart != nil {
+			originalDNSStart(info)
+		}
+	}
+
+	originalDNSDone := ct.DNSDone
+	ct.DNSDone = func(info httptrace.DNSDoneInfo) {
+		if info.Err != nil {
+			cs.fail("dns.done", info.Err)
+		} else {
+			cs.event("dns.done", attribute.Int("dns.addresses", len(info.Addrs)))
+		}
+		if originalDNSDone != nil {
+			originalDNSDone(info)
+		}
+	}
+
+	originalConnectStart := ct.ConnectStart
+	ct.ConnectStart = func(network, addr string) {
+		cs.event("connect.start", connectAttrs(network, addr)...)
+		if originalConnectStart != nil {
+			originalConnectStart(network, addr)
+		}
+	}
+
+	originalConnectDone := ct.ConnectDone
+	ct.ConnectDone = func(network, addr string, err error) {
+		if err != nil {
+			cs.fail("connect.done", err, connectAttrs(network, addr)...)
+		} else {
+			cs.event("connect.done", connectAttrs(network, addr)...)
+		}
+		if originalConnectDone != nil {
+			originalConnectDone(network, addr, err)
+		}
+	}
+
+	originalTLSHandshakeStart := ct.TLSHandshakeStart
+	ct.TLSHandshakeStart = func() {
+		cs.event("tls.handshake.start")
+		if originalTLSHandshakeStart != nil {
+			originalTLSHandshakeStart()
+		}
+	}
+
+	originalTLSHandshakeDone := ct.TLSHandshakeDone
+	ct.TLSHandshakeDone = func(state tls.ConnectionState, err error) {
+		if err != nil {
+			cs.fail("tls.handshake.done", err)
+		} else if span := cs.event("tls.handshake.done"); span != nil {
+			span.SetAttributes(tlsAttrs(&state)...)
+		}
+		if originalTLSHandshakeDone != nil {
+			originalTLSHandshakeDone(state, err)
+		}
+	}
+
+	originalGotConn := ct.GotConn
+	ct.GotConn = func(info httptrace.GotConnInfo) {
+		cs.end(nil)
+		if originalGotConn != nil {
+			originalGotConn(info)
+		}
+	}
+}
+
+// connectAttrs describes the address being connected to.
+func connectAttrs(network, addr string) []attribute.KeyValue {
+	host, port := splitHostPort(addr)
+	attrs := []attribute.KeyValue{
+		semconv.NetworkTransportKey.String(network),
+		semconv.NetworkPeerAddress(host),
+	}
+	if p, err := strconv.Atoi(port); err == nil {
+		attrs = append(attrs, semconv.NetworkPeerPort(p))
+	}
+	return attrs
+}
@@ -0,0 +1,110 @@
+package http
+
+import (
+	"context"
+	"net"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func connectSpans(exporter *tracetest.InMemoryExporter) []tracetest.SpanStub {
+	var spans []tracetest.SpanStub
+	for _, s := range exporter.GetSpans() {
+		if s.Name == "HTTP connect" {
+			spans = append(spans, s)
+		}
+	}
+	return spans
+}
+
+func TestWithConnectionSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+
+	ts := httptest.NewTLSServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {}))
+	defer ts.Close()
+
+	client, err := NewClient(WithClientTracerProvider(tp), WithConnectionSpans())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	transport, err := getTransport(client)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	transport.TLSClientConfig = ts.Client().Transport.(*stdhttp.Transport).TLSClientConfig
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent-span")
+	// The second request reuses the pooled connection, so has no connection span.
+	for range 2 {
+		req, _ := stdhttp.NewRequestWithContext(ctx, stdhttp.MethodGet, ts.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		drainAndClose(resp)
+	}
+	parent.End()
+
+	spans := connectSpans(exporter)
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 connection span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Error("expected the connection span to be a child of the request span")
+	}
+	var events []string
+	for _, e := range span.Events {
+		events = append(events, e.Name)
+	}
+	want := []string{"connect.start", "connect.done", "tls.handshake.start", "tls.handshake.done"}
+	if !slices.Equal(events, want) {
+		t.Errorf("expected events %v, got %v", want, events)
+	}
+}
+
+func TestWithConnectionSpans_ConnectFailure(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+
+	// Listen and close again, to find a port that refuses connections.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	addr := l.Addr().String()
+	_ = l.Close()
+
+	client, err := NewClient(WithClientTracerProvider(tp), WithConnectionSpans())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent-span")
+	req, _ := stdhttp.NewRequestWithContext(ctx, stdhttp.MethodGet, "http://"+addr, nil)
+	if _, err := client.Do(req); err == nil {
+		t.Fatal("expected the request to fail")
+	}
+	parent.End()
+
+	spans := connectSpans(exporter)
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 connection span, got %d", len(spans))
+	}
+	if spans[0].Status.Code != codes.Error {
+		t.Errorf("expected the connection span to record the failure, got %v", spans[0].Status.Code)
+	}
+}
+
+func TestWithConnectionSpans_RequiresInstrumentedTransport(t *testing.T) {
+	c := &stdhttp.Client{Transport: &stdhttp.Transport{}}
+	if err := WithConnectionSpans()(c); err == nil {
+		t.Error("expected an error for an uninstrumented transport")
+	}
+}
@@ -0,0 +1,36 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	stdhttp "net/http"
+	"net/http/cookiejar"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// WithCookieJar stores cookies set by responses in jar, and sends them with later requests to which they
+// apply (Client.Jar). Cookies are redacted if their headers are captured on spans.
+func WithCookieJar(jar stdhttp.CookieJar) ClientOption {
+	return func(c *stdhttp.Client) error {
+		if jar == nil {
+			return errors.New("cookie jar must not be nil")
+		}
+		c.Jar = jar
+		return nil
+	}
+}
+
+// WithInMemoryCookieJar is like WithCookieJar with a new in-memory jar from net/http/cookiejar, for flows
+// that depend on session cookies. The jar uses the public suffix list, so that a server can't set cookies for
+// a whole public suffix such as co.uk. Cookies are lost when the client is.
+func WithInMemoryCookieJar() ClientOption {
+	return func(c *stdhttp.Client) error {
+		jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+		if err != nil {
+			return fmt.Errorf("creating cookie jar: %w", err)
+		}
+		c.Jar = jar
+		return nil
+	}
+}
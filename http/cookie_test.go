@@ -0,0 +1,67 @@
+package http
+
+import (
+	stdhttp "net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestWithInMemoryCookieJar(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received []string
+	)
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		var value string
+		if cookie, err := r.Cookie("session"); err == nil {
+			value = cookie.Value
+		}
+		mu.Lock()
+		received = append(received, value)
+		mu.Unlock()
+		stdhttp.SetCookie(w, &stdhttp.Cookie{Name: "session", Value: "abc", Path: "/"})
+	}))
+	defer ts.Close()
+
+	c, err := NewClient(WithInMemoryCookieJar())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := c.Transport.(*InstrumentedTransport); !ok {
+		t.Fatalf("expected transport to be *InstrumentedTransport, got %T", c.Transport)
+	}
+
+	for range 2 {
+		resp, err := c.Get(ts.URL)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		_ = resp.Body.Close()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 2 || received[0] != "" || received[1] != "abc" {
+		t.Errorf("expected the cookie to be sent with the second request only, got %q", received)
+	}
+}
+
+func TestWithCookieJar(t *testing.T) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c, err := NewClient(WithCookieJar(jar))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Jar != jar {
+		t.Error("expected the client to use the jar")
+	}
+
+	if _, err := NewClient(WithCookieJar(nil)); err == nil {
+		t.Error("expected an error for a nil jar")
+	}
+}
@@ -0,0 +1,205 @@
+package http
+
+import (
+	"context"
+	"errors"
+	stdhttp "net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// CORSConfig configures cross-origin resource sharing, enabled with WithCORS.
+type CORSConfig struct {
+	// AllowedOrigins are the origins allowed to make cross-origin requests, such as "https://example.com". An
+	// origin may have a wildcard subdomain, such as "https://*.example.com", and "*" allows any origin.
+	AllowedOrigins []string
+
+	// AllowedMethods are the methods allowed in cross-origin requests. If empty, GET, HEAD and POST are
+	// allowed.
+	AllowedMethods []string
+
+	// AllowedHeaders are the request headers allowed in cross-origin requests, beyond those browsers always
+	// allow. "*" allows any header.
+	AllowedHeaders []string
+
+	// ExposedHeaders are the response headers that scripts are allowed to read, beyond those browsers always
+	// expose.
+	ExposedHeaders []string
+
+	// AllowCredentials allows requests with credentials, such as cookies. Browsers don't allow credentials
+	// with a "*" origin, so it can't be combined with one.
+	AllowCredentials bool
+
+	// MaxAge is how long browsers may cache the result of a preflight request. If zero, browsers use their
+	// default (5 seconds).
+	MaxAge time.Duration
+}
+
+// defaultCORSMethods are the methods allowed when CORSConfig.AllowedMethods is empty.
+var defaultCORSMethods = []string{stdhttp.MethodGet, stdhttp.MethodHead, stdhttp.MethodPost}
+
+// WithCORS allows cross-origin requests from browsers, as configured by cfg. Allowed requests get
+// Access-Control-* response headers; other requests are served without them, so browsers block scripts from
+// reading the responses.
+//
+// Preflight requests (OPTIONS requests with an Access-Control-Request-Method header) are answered with a 204
+// ahead of the handler and its instrumentation, so they record no spans. They are counted in
+// http.server.cors.preflight_requests instead, with http.cors.allowed recording whether they were allowed.
+func WithCORS(cfg CORSConfig) ServerOption {
+	return func(s *Server) error {
+		if len(cfg.AllowedOrigins) == 0 {
+			return errors.New("CORS allowed origins must not be empty")
+		}
+		if cfg.MaxAge < 0 {
+			return errors.New("CORS max age must not be negative")
+		}
+
+		p := &corsPolicy{
+			methods:     cfg.AllowedMethods,
+			credentials: cfg.AllowCredentials,
+			exposed:     strings.Join(cfg.ExposedHeaders, ", "),
+		}
+		for _, origin := range cfg.AllowedOrigins {
+			switch {
+			case origin == "*":
+				p.anyOrigin = true
+			case strings.Count(origin, "*") > 1 || (strings.Contains(origin, "*") && !strings.Contains(origin, "://*.")):
+				return errors.New("CORS origin wildcards must be a subdomain, as in https://*.example.com")
+			default:
+				p.origins = append(p.origins, strings.ToLower(origin))
+			}
+		}
+		if p.anyOrigin && p.credentials {
+			return errors.New("CORS credentials can't be allowed for any origin")
+		}
+		if len(p.methods) == 0 {
+			p.methods = defaultCORSMethods
+		}
+		for _, h := range cfg.AllowedHeaders {
+			if h == "*" {
+				p.anyHeader = true
+				continue
+			}
+			p.headers = append(p.headers, stdhttp.CanonicalHeaderKey(h))
+		}
+		if cfg.MaxAge > 0 {
+			p.maxAge = strconv.Itoa(int(cfg.MaxAge.Seconds()))
+		}
+		s.cors = p
+		return nil
+	}
+}
+
+// corsPolicy is the parsed form of a CORSConfig.
+type corsPolicy struct {
+	anyOrigin   bool
+	origins     []string
+	methods     []string
+	anyHeader   bool
+	headers     []string
+	exposed     string
+	credentials bool
+	maxAge      string
+}
+
+// allowOrigin reports whether requests from origin are allowed.
+func (p *corsPolicy) allowOrigin(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	for _, allowed := range p.origins {
+		if prefix, suffix, ok := strings.Cut(allowed, "*"); ok {
+			// The wildcard matches one or more subdomain labels, but not the bare domain.
+			if len(origin) > len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) &&
+				strings.HasSuffix(origin, suffix) {
+				return true
+			}
+		} else if origin == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// allowHeaders reports whether the comma-separated request headers are all allowed.
+func (p *corsPolicy) allowHeaders(requested string) bool {
+	if p.anyHeader {
+		return true
+	}
+	for _, h := range strings.Split(requested, ",") {
+		if h = strings.TrimSpace(h); h != "" && !slices.Contains(p.headers, stdhttp.CanonicalHeaderKey(h)) {
+			return false
+		}
+	}
+	return true
+}
+
+// corsHandler applies a CORS policy, answering preflight requests and passing others on to next.
+type corsHandler struct {
+	next       stdhttp.Handler
+	policy     *corsPolicy
+	mPreflight metric.Int64Counter
+}
+
+func (h *corsHandler) ServeHTTP(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	origin := r.Header.Get("Origin")
+	requestMethod := r.Header.Get("Access-Control-Request-Method")
+	if r.Method == stdhttp.MethodOptions && origin != "" && requestMethod != "" {
+		h.preflight(w, r, origin, requestMethod)
+		return
+	}
+
+	w.Header().Add("Vary", "Origin")
+	if origin != "" && h.policy.allowOrigin(origin) {
+		h.allow(w.Header(), origin)
+		if h.policy.exposed != "" {
+			w.Header().Set("Access-Control-Expose-Headers", h.policy.exposed)
+		}
+	}
+	h.next.ServeHTTP(w, r)
+}
+
+// preflight answers a preflight request, with the Access-Control-* headers only if it is allowed.
+func (h *corsHandler) preflight(w stdhttp.ResponseWriter, r *stdhttp.Request, origin, requestMethod string) {
+	header := w.Header()
+	header.Add("Vary", "Origin")
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+
+	requestHeaders := r.Header.Get("Access-Control-Request-Headers")
+	allowed := h.policy.allowOrigin(origin) && slices.Contains(h.policy.methods, requestMethod) &&
+		h.policy.allowHeaders(requestHeaders)
+	if allowed {
+		h.allow(header, origin)
+		header.Set("Access-Control-Allow-Methods", strings.Join(h.policy.methods, ", "))
+		if requestHeaders != "" {
+			// The requested headers are all allowed, so they are echoed back, which also covers "*".
+			header.Set("Access-Control-Allow-Headers", requestHeaders)
+		}
+		if h.policy.maxAge != "" {
+			header.Set("Access-Control-Max-Age", h.policy.maxAge)
+		}
+	}
+	if h.mPreflight != nil {
+		h.mPreflight.Add(context.Background(), 1, metric.WithAttributes(attribute.Bool("http.cors.allowed", allowed)))
+	}
+	w.WriteHeader(stdhttp.StatusNoContent)
+}
+
+// allow sets the headers that allow origin to read the response.
+func (h *corsHandler) allow(header stdhttp.Header, origin string) {
+	if h.policy.anyOrigin {
+		header.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	header.Set("Access-Control-Allow-Origin", origin)
+	if h.policy.credentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
@@ -0,0 +1,154 @@
+package http
+
+import (
+	stdhttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestWithCORS(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	var served int
+	s, err := NewServer(":0", stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		served++
+		w.Header().Set("X-Request-Id", "abc")
+	}), WithServerTracerProvider(tp), WithServerMeterProvider(mp), WithCORS(CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.example.org"},
+		AllowedMethods:   []string{stdhttp.MethodGet, stdhttp.MethodPut},
+		AllowedHeaders:   []string{"Content-Type", "x-tenant"},
+		ExposedHeaders:   []string{"X-Request-Id"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	serve := func(method, origin string, header stdhttp.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/", nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		rec := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("allowed origin", func(t *testing.T) {
+		for _, origin := range []string{"https://app.example.com", "https://eu.api.example.org"} {
+			rec := serve(stdhttp.MethodGet, origin, nil)
+			h := rec.Header()
+			if got := h.Get("Access-Control-Allow-Origin"); got != origin {
+				t.Errorf("expected the origin %q to be allowed, got %q", origin, got)
+			}
+			if h.Get("Access-Control-Allow-Credentials") != "true" {
+				t.Error("expected credentials to be allowed")
+			}
+			if h.Get("Access-Control-Expose-Headers") != "X-Request-Id" {
+				t.Errorf("expected the exposed headers, got %q", h.Get("Access-Control-Expose-Headers"))
+			}
+			if h.Get("Vary") != "Origin" {
+				t.Errorf("expected Vary: Origin, got %q", h.Get("Vary"))
+			}
+		}
+	})
+
+	t.Run("disallowed origin", func(t *testing.T) {
+		for _, origin := range []string{"https://evil.example", "https://example.org", "http://app.example.com"} {
+			rec := serve(stdhttp.MethodGet, origin, nil)
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+				t.Errorf("expected the origin %q not to be allowed, got %q", origin, got)
+			}
+			if rec.Code != stdhttp.StatusOK {
+				t.Errorf("expected the request to be served, got %d", rec.Code)
+			}
+		}
+	})
+
+	t.Run("preflight", func(t *testing.T) {
+		served = 0
+		exporter.Reset()
+
+		rec := serve(stdhttp.MethodOptions, "https://app.example.com", stdhttp.Header{
+			"Access-Control-Request-Method":  {stdhttp.MethodPut},
+			"Access-Control-Request-Headers": {"content-type, X-Tenant"},
+		})
+		h := rec.Header()
+		if rec.Code != stdhttp.StatusNoContent {
+			t.Errorf("expected a 204, got %d", rec.Code)
+		}
+		if h.Get("Access-Control-Allow-Origin") != "https://app.example.com" ||
+			h.Get("Access-Control-Allow-Methods") != "GET, PUT" ||
+			h.Get("Access-Control-Allow-Headers") != "content-type, X-Tenant" ||
+			h.Get("Access-Control-Max-Age") != "600" {
+			t.Errorf("expected the preflight to be allowed, got %v", h)
+		}
+
+		// Preflights for methods or headers that aren't allowed get no CORS headers.
+		for _, header := range []stdhttp.Header{
+			{"Access-Control-Request-Method": {stdhttp.MethodDelete}},
+			{"Access-Control-Request-Method": {stdhttp.MethodGet}, "Access-Control-Request-Headers": {"X-Other"}},
+		} {
+			rec := serve(stdhttp.MethodOptions, "https://app.example.com", header)
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+				t.Errorf("expected the preflight %v not to be allowed, got %q", header, got)
+			}
+		}
+
+		if served != 0 {
+			t.Errorf("expected preflights not to reach the handler, got %d", served)
+		}
+		if spans := exporter.GetSpans(); len(spans) != 0 {
+			t.Errorf("expected preflights to record no spans, got %d", len(spans))
+		}
+		if got := sumCounter(t, reader, "http.server.cors.preflight_requests"); got != 3 {
+			t.Errorf("expected 3 preflights counted, got %d", got)
+		}
+	})
+}
+
+func TestWithCORS_AnyOrigin(t *testing.T) {
+	s, err := NewServer(":0", stdhttp.NotFoundHandler(), WithCORS(CORSConfig{
+		AllowedOrigins: []string{"*"},
+		AllowedHeaders: []string{"*"},
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := httptest.NewRequest(stdhttp.MethodOptions, "/", nil)
+	req.Header.Set("Origin", "https://anywhere.example")
+	req.Header.Set("Access-Control-Request-Method", stdhttp.MethodPost)
+	req.Header.Set("Access-Control-Request-Headers", "X-Anything")
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("expected any origin to be allowed, got %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Headers"); got != "X-Anything" {
+		t.Errorf("expected any header to be allowed, got %q", got)
+	}
+
+	for _, cfg := range []CORSConfig{
+		{},
+		{AllowedOrigins: []string{"*"}, AllowCredentials: true},
+		{AllowedOrigins: []string{"https://*"}},
+		{AllowedOrigins: []string{"https://app.*.com"}},
+		{AllowedOrigins: []string{"https://example.com"}, MaxAge: -time.Second},
+	} {
+		if _, err := NewServer(":0", nil, WithCORS(cfg)); err == nil {
+			t.Errorf("expected an error for %+v", cfg)
+		}
+	}
+}
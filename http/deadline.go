@@ -0,0 +1,145 @@
+package http
+
+import (
+	"errors"
+	stdhttp "net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrStreamDeadline is returned by writes and flushes of a streaming response once it is too close to its
+// write deadline to continue safely. See WithStreamingWriteDeadline.
+var ErrStreamDeadline = errors.New("http: streaming response reached its write deadline")
+
+// streamDeadline configures how the write deadline of streaming responses is managed.
+type streamDeadline struct {
+	extension    time.Duration
+	maxExtension time.Duration
+}
+
+// WithStreamingWriteDeadline manages the write deadline of long (streaming) responses, so that they end cleanly
+// rather than being cut off mid-stream by WriteTimeout, which corrupts the response. It only has an effect when
+// a WriteTimeout is set, and measures it from when the handler starts.
+//
+// Whenever the handler writes or flushes within extension of the write deadline, the deadline is pushed back
+// to extension from now, up to maxExtension beyond the WriteTimeout, and an http.server.write_deadline.extended
+// event is recorded on the span. Once the deadline can't be pushed back further and is within extension,
+// writes and flushes fail with ErrStreamDeadline rather than being attempted, and an
+// http.server.write_deadline.reached event is recorded: the handler should stop and return, so that the
+// response is ended properly. If the response headers have not been written yet, "Connection: close" is set.
+//
+// Streaming handlers may flush either with http.Flusher or with http.ResponseController; both are managed.
+// Handlers that block for longer than extension between writes can still run into the deadline.
+func WithStreamingWriteDeadline(extension, maxExtension time.Duration) ServerOption {
+	return func(s *Server) error {
+		if extension <= 0 {
+			return errors.New("write deadline extension must be positive")
+		}
+		if maxExtension < 0 {
+			return errors.New("max write deadline extension must not be negative")
+		}
+		s.streamDeadline = &streamDeadline{extension: extension, maxExtension: maxExtension}
+		return nil
+	}
+}
+
+// deadlineWriter manages the write deadline of a response, as described by WithStreamingWriteDeadline.
+type deadlineWriter struct {
+	stdhttp.ResponseWriter
+	rc   *stdhttp.ResponseController
+	span trace.Span
+	now  func() time.Time
+
+	extension   time.Duration
+	deadline    time.Time
+	maxDeadline time.Time
+	wroteHeader bool
+	stopped     bool
+}
+
+// newDeadlineWriter wraps w, setting its write deadline to timeout from now.
+func newDeadlineWriter(
+	w stdhttp.ResponseWriter, span trace.Span, cfg *streamDeadline, timeout time.Duration,
+) *deadlineWriter {
+	now := time.Now()
+	dw := &deadlineWriter{
+		ResponseWriter: w,
+		rc:             stdhttp.NewResponseController(w),
+		span:           span,
+		now:            time.Now,
+		extension:      cfg.extension,
+		deadline:       now.Add(timeout),
+		maxDeadline:    now.Add(timeout + cfg.maxExtension),
+	}
+	_ = dw.rc.SetWriteDeadline(dw.deadline)
+	return dw
+}
+
+func (w *deadlineWriter) WriteHeader(statusCode int) {
+	if statusCode >= 200 {
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *deadlineWriter) Write(b []byte) (int, error) {
+	if !w.check() {
+		return 0, ErrStreamDeadline
+	}
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher.
+func (w *deadlineWriter) Flush() {
+	_ = w.FlushError()
+}
+
+// FlushError flushes the response, as used by http.ResponseController.
+func (w *deadlineWriter) FlushError() error {
+	if !w.check() {
+		return ErrStreamDeadline
+	}
+	w.wroteHeader = true
+	return w.rc.Flush()
+}
+
+// Unwrap returns the underlying ResponseWriter, allowing http.ResponseController to reach it.
+func (w *deadlineWriter) Unwrap() stdhttp.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// check extends the write deadline if it is near and can be, and reports whether writing may continue.
+func (w *deadlineWriter) check() bool {
+	if w.stopped {
+		return false
+	}
+	now := w.now()
+	if w.deadline.Sub(now) >= w.extension {
+		return true
+	}
+
+	if w.deadline.Before(w.maxDeadline) {
+		w.deadline = now.Add(w.extension)
+		if w.deadline.After(w.maxDeadline) {
+			w.deadline = w.maxDeadline
+		}
+		if err := w.rc.SetWriteDeadline(w.deadline); err == nil {
+			w.span.AddEvent("http.server.write_deadline.extended", trace.WithAttributes(
+				attribute.String("http.server.write_deadline", w.deadline.Format(time.RFC3339Nano)),
+			))
+		}
+		if w.deadline.Sub(now) >= w.extension {
+			return true
+		}
+	}
+
+	w.stopped = true
+	if !w.wroteHeader {
+		w.Header().Set("Connection", "close")
+	}
+	w.span.AddEvent("http.server.write_deadline.reached")
+	return false
+}
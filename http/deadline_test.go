@@ -0,0 +1,109 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestWithStreamingWriteDeadline(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+
+	stopped := make(chan error, 1)
+	s, err := NewServer(":0", stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		for {
+			if _, err := io.WriteString(w, "tick\n"); err != nil {
+				stopped <- err
+				return
+			}
+			w.(stdhttp.Flusher).Flush()
+			time.Sleep(10 * time.Millisecond)
+		}
+	}),
+		WithServerTracerProvider(tp),
+		WithWriteTimeout(200*time.Millisecond),
+		WithStreamingWriteDeadline(100*time.Millisecond, 200*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	go func() { _ = s.serve(l) }()
+	defer func() { _ = s.shutdown(context.Background()) }()
+
+	start := time.Now()
+	resp, err := stdhttp.Get("http://" + l.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The stream ends cleanly rather than being cut off.
+	if _, err := io.ReadAll(resp.Body); err != nil {
+		t.Errorf("expected the response to end cleanly, got %v", err)
+	}
+	_ = resp.Body.Close()
+	elapsed := time.Since(start)
+
+	if err := <-stopped; !errors.Is(err, ErrStreamDeadline) {
+		t.Errorf("expected the handler to be stopped with ErrStreamDeadline, got %v", err)
+	}
+	if elapsed < 250*time.Millisecond {
+		t.Errorf("expected the stream to outlast the write timeout, took %v", elapsed)
+	}
+
+	events := map[string]bool{}
+	for _, e := range exporter.GetSpans()[0].Events {
+		events[e.Name] = true
+	}
+	for _, name := range []string{"http.server.write_deadline.extended", "http.server.write_deadline.reached"} {
+		if !events[name] {
+			t.Errorf("expected a %s event", name)
+		}
+	}
+}
+
+func TestDeadlineWriter_Check(t *testing.T) {
+	now := time.Now()
+	rec := httptest.NewRecorder()
+	_, span := noop.NewTracerProvider().Tracer("test").Start(context.Background(), "test")
+	cfg := &streamDeadline{extension: time.Second, maxExtension: 2 * time.Second}
+	w := newDeadlineWriter(rec, span, cfg, 5*time.Second)
+	w.now = func() time.Time { return now }
+
+	// Far from the deadline, nothing changes.
+	deadline := w.deadline
+	if !w.check() || !w.deadline.Equal(deadline) {
+		t.Fatal("expected writing to continue without an extension")
+	}
+
+	// Near the deadline, it is extended.
+	now = deadline.Add(-500 * time.Millisecond)
+	if !w.check() || !w.deadline.Equal(now.Add(time.Second)) {
+		t.Fatalf("expected the deadline to be extended, got %v", w.deadline.Sub(now))
+	}
+
+	// Near the maximum deadline, writing stops.
+	now = w.maxDeadline.Add(-500 * time.Millisecond)
+	if w.check() {
+		t.Fatal("expected writing to stop near the maximum deadline")
+	}
+	if _, err := w.Write([]byte("x")); !errors.Is(err, ErrStreamDeadline) {
+		t.Errorf("expected ErrStreamDeadline, got %v", err)
+	}
+	if got := rec.Header().Get("Connection"); got != "close" {
+		t.Errorf("expected the connection to be closed, got %q", got)
+	}
+}
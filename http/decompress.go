@@ -0,0 +1,183 @@
+package http
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	stdhttp "net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// acceptEncoding is the Accept-Encoding sent when decompression is automatic, listing the encodings that
+// decompressionTransport decodes.
+const acceptEncoding = "gzip, deflate, br"
+
+// WithAutomaticDecompression controls whether compressed responses are decoded for the caller.
+//
+// By default, the underlying http.Transport asks for gzip and decodes it, but only if the request didn't set
+// Accept-Encoding itself. When enabled, responses with a Content-Encoding of gzip, deflate or br are decoded
+// whether or not the request set Accept-Encoding (which is set to "gzip, deflate, br" if it didn't), and the
+// encoding is recorded as http.response.content_encoding on the span. Decoded responses have their
+// Content-Encoding and Content-Length removed, and Response.Uncompressed set. Other encodings, such as zstd,
+// are returned as they were sent.
+//
+// When disabled, compression is never requested, and responses are always returned as they were sent.
+func WithAutomaticDecompression(enabled bool) ClientOption {
+	return func(c *stdhttp.Client) error {
+		t, err := getTransport(c)
+		if err != nil {
+			return err
+		}
+		// Decompression is done by the wrapper, or not at all.
+		t.DisableCompression = true
+
+		if dt := findDecompressionTransport(c); dt != nil {
+			dt.enabled = enabled
+			return nil
+		}
+		wrapTransport(c, func(base stdhttp.RoundTripper) stdhttp.RoundTripper {
+			return &decompressionTransport{base: base, enabled: enabled}
+		})
+		return nil
+	}
+}
+
+// findDecompressionTransport returns the client's decompressionTransport, if it has one.
+func findDecompressionTransport(c *stdhttp.Client) *decompressionTransport {
+	rt := c.Transport
+	if it, ok := rt.(*InstrumentedTransport); ok {
+		rt = it.Base
+	}
+	for {
+		switch t := rt.(type) {
+		case *decompressionTransport:
+			return t
+		case wrappingTransport:
+			rt = t.unwrap()
+		default:
+			return nil
+		}
+	}
+}
+
+// decompressionTransport is a RoundTripper that requests and decodes compressed responses.
+type decompressionTransport struct {
+	base    stdhttp.RoundTripper
+	enabled bool
+}
+
+func (t *decompressionTransport) unwrap() stdhttp.RoundTripper {
+	return t.base
+}
+
+func (t *decompressionTransport) setBase(base stdhttp.RoundTripper) {
+	t.base = base
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *decompressionTransport) RoundTrip(req *stdhttp.Request) (*stdhttp.Response, error) {
+	if !t.enabled {
+		return t.base.RoundTrip(req)
+	}
+	if req.Header.Get("Accept-Encoding") == "" && req.Header.Get("Range") == "" {
+		// Ranges of a compressed representation can't be decoded on their own, so compression is only
+		// requested for whole responses.
+		next := *req
+		next.Header = req.Header.Clone()
+		if next.Header == nil {
+			next.Header = make(stdhttp.Header)
+		}
+		next.Header.Set("Accept-Encoding", acceptEncoding)
+		req = &next
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.Body == nil || req.Method == stdhttp.MethodHead ||
+		isRawResponse(req) {
+		return resp, err
+	}
+
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	var newReader func(io.Reader) (io.ReadCloser, error)
+	switch encoding {
+	case "gzip", "x-gzip":
+		newReader = func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) }
+	case "deflate":
+		newReader = newDeflateReader
+	case "br":
+		newReader = newBrotliReader
+	default:
+		return resp, nil
+	}
+
+	trace.SpanFromContext(req.Context()).SetAttributes(attribute.String("http.response.content_encoding", encoding))
+	resp.Body = &decodingBody{body: resp.Body, newReader: newReader}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return resp, nil
+}
+
+// newDeflateReader decodes a deflate response. The deflate content coding is zlib-wrapped, but some servers
+// send raw deflate data instead, so that is accepted too.
+func newDeflateReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// newBrotliReader decodes a br response. Brotli has no header to read up front, so the body is checked for
+// data first, leaving an empty body to fail with io.EOF as the other decoders do.
+func newBrotliReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	if _, err := br.Peek(1); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(brotli.NewReader(br)), nil
+}
+
+// decodingBody decodes a compressed body as it is read. The decoder is created on the first read, as it
+// reads a header from the body, so that an empty body reads as empty rather than failing. Closing it closes
+// the underlying body. It is not safe for concurrent use, as bodies are not.
+type decodingBody struct {
+	body      io.ReadCloser
+	newReader func(io.Reader) (io.ReadCloser, error)
+	decoder   io.ReadCloser
+	err       error
+}
+
+func (b *decodingBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	if b.decoder == nil {
+		// An empty body fails with io.EOF, which reads as the end of the (empty) decoded body.
+		decoder, err := b.newReader(b.body)
+		if err != nil {
+			b.err = err
+			return 0, err
+		}
+		b.decoder = decoder
+	}
+	return b.decoder.Read(p)
+}
+
+func (b *decodingBody) Close() error {
+	if b.decoder != nil {
+		_ = b.decoder.Close()
+	}
+	return b.body.Close()
+}
@@ -0,0 +1,162 @@
+package http
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// trackingBody records whether it was closed.
+type trackingBody struct {
+	io.Reader
+	closed bool
+}
+
+func (b *trackingBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestWithAutomaticDecompression(t *testing.T) {
+	const payload = "hello, compressed world"
+	compress := func(encoding string) []byte {
+		var buf bytes.Buffer
+		var w io.WriteCloser
+		switch encoding {
+		case "gzip":
+			w = gzip.NewWriter(&buf)
+		case "deflate":
+			w = zlib.NewWriter(&buf)
+		case "raw-deflate":
+			w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+		case "br":
+			w = brotli.NewWriter(&buf)
+		}
+		_, _ = w.Write([]byte(payload))
+		_ = w.Close()
+		return buf.Bytes()
+	}
+
+	var gotAcceptEncoding string
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		gotAcceptEncoding = r.Header.Get("Accept-Encoding")
+		switch encoding := r.URL.Query().Get("encoding"); encoding {
+		case "":
+			_, _ = w.Write([]byte(payload))
+		case "empty":
+			w.Header().Set("Content-Encoding", "gzip")
+		case "empty-br":
+			w.Header().Set("Content-Encoding", "br")
+		case "raw-deflate":
+			w.Header().Set("Content-Encoding", "deflate")
+			_, _ = w.Write(compress(encoding))
+		default:
+			w.Header().Set("Content-Encoding", encoding)
+			_, _ = w.Write(compress(encoding))
+		}
+	}))
+	defer ts.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+	c, err := NewClient(WithAutomaticDecompression(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, tc := range []struct {
+		encoding, want, wantAttr string
+	}{
+		{encoding: "gzip", want: payload, wantAttr: "gzip"},
+		{encoding: "deflate", want: payload, wantAttr: "deflate"},
+		{encoding: "raw-deflate", want: payload, wantAttr: "deflate"},
+		{encoding: "br", want: payload, wantAttr: "br"},
+		{encoding: "empty", want: "", wantAttr: "gzip"},
+		{encoding: "empty-br", want: "", wantAttr: "br"},
+		{encoding: "", want: payload},
+	} {
+		t.Run(tc.encoding, func(t *testing.T) {
+			exporter.Reset()
+			ctx, span := tp.Tracer("test").Start(context.Background(), "parent-span")
+			req, _ := stdhttp.NewRequestWithContext(ctx, "GET", ts.URL+"?encoding="+tc.encoding, nil)
+			resp, err := c.Do(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			body, err := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			span.End()
+			if err != nil {
+				t.Fatalf("reading the body failed: %v", err)
+			}
+
+			if gotAcceptEncoding != acceptEncoding {
+				t.Errorf("expected Accept-Encoding %q, got %q", acceptEncoding, gotAcceptEncoding)
+			}
+			if string(body) != tc.want {
+				t.Errorf("expected body %q, got %q", tc.want, body)
+			}
+			if tc.wantAttr == "" {
+				return
+			}
+			if ce := resp.Header.Get("Content-Encoding"); ce != "" || !resp.Uncompressed {
+				t.Errorf("expected a decoded response, got Content-Encoding %q", ce)
+			}
+			attr := attribute.String("http.response.content_encoding", tc.wantAttr)
+			if !hasAttr(exporter.GetSpans()[0].Attributes, attr) {
+				t.Errorf("expected %s on the span, got %v", tc.wantAttr, exporter.GetSpans()[0].Attributes)
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		c, err := NewClient(WithAutomaticDecompression(true), WithAutomaticDecompression(false))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp, err := c.Get(ts.URL + "?encoding=gzip")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if gotAcceptEncoding != "" {
+			t.Errorf("expected no Accept-Encoding, got %q", gotAcceptEncoding)
+		}
+		if !bytes.Equal(body, compress("gzip")) || resp.Header.Get("Content-Encoding") != "gzip" {
+			t.Error("expected the response as it was sent")
+		}
+	})
+}
+
+func TestDecodingBody_Close(t *testing.T) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, _ = w.Write([]byte("data"))
+	_ = w.Close()
+
+	underlying := &trackingBody{Reader: &buf}
+	b := &decodingBody{body: underlying, newReader: func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	}}
+	if _, err := b.Read(make([]byte, 1)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !underlying.closed {
+		t.Error("expected Close to close the underlying body")
+	}
+}
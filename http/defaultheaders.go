@@ -0,0 +1,69 @@
+package http
+
+import (
+	"errors"
+	stdhttp "net/http"
+)
+
+// WithDefaultHeaders adds the given headers (e.g. X-Env or X-Service) to every request that doesn't already
+// set them, leaving headers set on the request untouched.
+//
+// The defaults are added beneath the instrumentation, just before the request reaches the underlying
+// transport: trace context is injected first, and the defaults don't replace it, but nor are they visible to
+// WithClientCaptureRequestHeaders.
+func WithDefaultHeaders(h stdhttp.Header) ClientOption {
+	return func(c *stdhttp.Client) error {
+		if len(h) == 0 {
+			return errors.New("default headers must not be empty")
+		}
+		defaults := make(stdhttp.Header, len(h))
+		for key, values := range h {
+			if len(values) == 0 {
+				return errors.New("default headers must have a value")
+			}
+			defaults[stdhttp.CanonicalHeaderKey(key)] = append([]string(nil), values...)
+		}
+		wrapTransport(c, func(base stdhttp.RoundTripper) stdhttp.RoundTripper {
+			return &defaultHeadersTransport{base: base, headers: defaults}
+		})
+		return nil
+	}
+}
+
+// defaultHeadersTransport is a RoundTripper that adds default headers to requests that don't set them.
+type defaultHeadersTransport struct {
+	base    stdhttp.RoundTripper
+	headers stdhttp.Header
+}
+
+func (t *defaultHeadersTransport) unwrap() stdhttp.RoundTripper {
+	return t.base
+}
+
+func (t *defaultHeadersTransport) setBase(base stdhttp.RoundTripper) {
+	t.base = base
+}
+
+// RoundTrip implements http.RoundTripper. The request is copied before headers are added, as a RoundTripper
+// must not modify it.
+func (t *defaultHeadersTransport) RoundTrip(req *stdhttp.Request) (*stdhttp.Response, error) {
+	var next *stdhttp.Request
+	for key, values := range t.headers {
+		if _, ok := req.Header[key]; ok {
+			continue
+		}
+		if next == nil {
+			copied := *req
+			copied.Header = req.Header.Clone()
+			if copied.Header == nil {
+				copied.Header = make(stdhttp.Header, len(t.headers))
+			}
+			next = &copied
+		}
+		next.Header[key] = values
+	}
+	if next == nil {
+		return t.base.RoundTrip(req)
+	}
+	return t.base.RoundTrip(next)
+}
@@ -0,0 +1,60 @@
+package http
+
+import (
+	"context"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestWithDefaultHeaders(t *testing.T) {
+	var got stdhttp.Header
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		got = r.Header.Clone()
+	}))
+	defer ts.Close()
+
+	c, err := NewClient(
+		WithClientPropagator(propagation.TraceContext{}),
+		WithDefaultHeaders(stdhttp.Header{"x-env": {"prod"}, "X-Service": {"billing"}}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tp := trace.NewTracerProvider()
+	ctx, span := tp.Tracer("test").Start(context.Background(), "parent-span")
+	defer span.End()
+	req, _ := stdhttp.NewRequestWithContext(ctx, "GET", ts.URL, nil)
+	req.Header.Set("X-Service", "override")
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if v := got.Get("X-Env"); v != "prod" {
+		t.Errorf("expected the default X-Env, got %q", v)
+	}
+	if v := got.Get("X-Service"); v != "override" {
+		t.Errorf("expected the request's X-Service to be kept, got %q", v)
+	}
+	if got.Get("Traceparent") == "" {
+		t.Error("expected trace context to still be injected")
+	}
+	if req.Header.Get("X-Env") != "" {
+		t.Error("expected the caller's request not to be modified")
+	}
+}
+
+func TestWithDefaultHeaders_Invalid(t *testing.T) {
+	if _, err := NewClient(WithDefaultHeaders(nil)); err == nil {
+		t.Error("expected an error for no headers")
+	}
+	if _, err := NewClient(WithDefaultHeaders(stdhttp.Header{"X-Env": nil})); err == nil {
+		t.Error("expected an error for a header without a value")
+	}
+}
@@ -0,0 +1,115 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	stdhttp "net/http"
+	"time"
+)
+
+// drainPollInterval is how often Drain checks whether the in-flight requests have completed, and
+// drainReportInterval how often it reports how many remain.
+const (
+	drainPollInterval   = 10 * time.Millisecond
+	drainReportInterval = time.Second
+)
+
+// Drain stops the server accepting new connections and waits for the requests in flight to complete,
+// reporting how many remain to the server's ErrorLog every second. It returns once none remain, or with the
+// context's error once ctx is done. The server reports itself as not ready (see Ready) from the moment Drain is
+// called.
+//
+// Connections are closed as their requests complete. Drain does not wait for background tasks, or stop the
+// server: shut it down afterwards, e.g. by cancelling the context passed to RunContext.
+func (s *Server) Drain(ctx context.Context) error {
+	s.draining.Store(true)
+	if err := s.stopAccepting(); err != nil {
+		return fmt.Errorf("closing listener: %w", err)
+	}
+
+	poll := time.NewTicker(drainPollInterval)
+	defer poll.Stop()
+	report := time.NewTicker(drainReportInterval)
+	defer report.Stop()
+	for {
+		if s.activeRequests.Load() == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("draining %d requests: %w", s.activeRequests.Load(), ctx.Err())
+		case <-report.C:
+			s.logf("http: draining, %d requests in flight", s.activeRequests.Load())
+		case <-poll.C:
+		}
+	}
+}
+
+// ActiveRequests returns the number of requests currently being handled.
+func (s *Server) ActiveRequests() int64 {
+	return s.activeRequests.Load()
+}
+
+// Ready reports whether the server should receive traffic: it is listening, has not been marked not ready with
+// SetReady, and is neither draining nor shutting down. It becomes false once Drain is called or shutdown begins,
+// so that load balancers stop routing to it before its connections close.
+func (s *Server) Ready() bool {
+	s.listenerMu.Lock()
+	listening := s.listener != nil
+	s.listenerMu.Unlock()
+	return listening && !s.notReady.Load() && !s.draining.Load() && !s.shuttingDown.Load()
+}
+
+// SetReady marks the server as ready or not, for readiness that depends on the application (such as a warm
+// cache, or a connection to a dependency). Servers are ready by default.
+func (s *Server) SetReady(ready bool) {
+	s.notReady.Store(!ready)
+}
+
+// ReadinessHandler returns a handler for readiness checks, which responds 200 while the server is ready and
+// 503 otherwise. Serve it from a separate server (such as an admin server) or with WithHealthEndpoints.
+func (s *Server) ReadinessHandler() stdhttp.Handler {
+	return stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		if !s.Ready() {
+			stdhttp.Error(w, "draining", stdhttp.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok\n"))
+	})
+}
+
+// WithHealthEndpoints serves a liveness check at livePath, which always responds 200, and a readiness check at
+// readyPath, which responds as ReadinessHandler does. Either path may be empty to omit it. The checks are
+// answered ahead of the handler and its instrumentation, so they record no spans or metrics.
+//
+// Once the server is draining it stops accepting connections, so a load balancer may see the readiness check
+// fail to connect rather than respond 503; either takes the server out of rotation.
+func WithHealthEndpoints(livePath, readyPath string) ServerOption {
+	return func(s *Server) error {
+		if livePath != "" && livePath == readyPath {
+			return errors.New("liveness and readiness paths must differ")
+		}
+		s.livePath, s.readyPath = livePath, readyPath
+		return nil
+	}
+}
+
+// healthHandler answers health checks, passing other requests on to next.
+type healthHandler struct {
+	next      stdhttp.Handler
+	livePath  string
+	readyPath string
+	ready     stdhttp.Handler
+}
+
+func (h *healthHandler) ServeHTTP(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	switch {
+	case h.livePath != "" && r.URL.Path == h.livePath:
+		_, _ = w.Write([]byte("ok\n"))
+	case h.readyPath != "" && r.URL.Path == h.readyPath:
+		h.ready.ServeHTTP(w, r)
+	default:
+		h.next.ServeHTTP(w, r)
+	}
+}
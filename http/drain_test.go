@@ -0,0 +1,144 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestServer_Drain(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	s, err := NewServer(":0", stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		close(started)
+		<-release
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	addr := l.Addr().String()
+	go func() { _ = s.serve(l) }()
+	defer func() { _ = s.shutdown(context.Background()) }()
+
+	inFlight := make(chan error, 1)
+	go func() {
+		resp, err := stdhttp.Get("http://" + addr)
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		inFlight <- err
+	}()
+	<-started
+
+	readiness := httptest.NewRecorder()
+	s.ReadinessHandler().ServeHTTP(readiness, httptest.NewRequest("GET", "/ready", nil))
+	if !s.Ready() || readiness.Code != stdhttp.StatusOK {
+		t.Errorf("expected the server to be ready, got %d", readiness.Code)
+	}
+	if got := s.ActiveRequests(); got != 1 {
+		t.Errorf("expected 1 active request, got %d", got)
+	}
+
+	// The drain gives up once its context is done, while the request is still in flight.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	readiness = httptest.NewRecorder()
+	s.ReadinessHandler().ServeHTTP(readiness, httptest.NewRequest("GET", "/ready", nil))
+	if s.Ready() || readiness.Code != stdhttp.StatusServiceUnavailable {
+		t.Errorf("expected the server not to be ready once draining, got %d", readiness.Code)
+	}
+	if conn, err := net.Dial("tcp", addr); err == nil {
+		_ = conn.Close()
+		t.Error("expected new connections to be refused")
+	}
+
+	// Once the request completes, the drain finishes.
+	close(release)
+	if err := s.Drain(context.Background()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := <-inFlight; err != nil {
+		t.Errorf("expected the in-flight request to complete, got %v", err)
+	}
+	if got := s.ActiveRequests(); got != 0 {
+		t.Errorf("expected no active requests, got %d", got)
+	}
+}
+
+func TestWithHealthEndpoints(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+	s, err := NewServer(":0", stdhttp.NotFoundHandler(),
+		WithServerTracerProvider(tp), WithHealthEndpoints("/healthz", "/readyz"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	check := func(path string) int {
+		rec := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec.Code
+	}
+
+	// Not ready until the server is listening.
+	if got := check("/healthz"); got != stdhttp.StatusOK {
+		t.Errorf("expected liveness to be 200, got %d", got)
+	}
+	if got := check("/readyz"); got != stdhttp.StatusServiceUnavailable {
+		t.Errorf("expected readiness to be 503 before listening, got %d", got)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	go func() { _ = s.serve(l) }()
+	defer func() { _ = s.shutdown(context.Background()) }()
+	listeningAddr(t, s)
+	if got := check("/readyz"); got != stdhttp.StatusOK {
+		t.Errorf("expected readiness to be 200 once listening, got %d", got)
+	}
+
+	s.SetReady(false)
+	if got := check("/readyz"); got != stdhttp.StatusServiceUnavailable {
+		t.Errorf("expected readiness to be 503 when marked not ready, got %d", got)
+	}
+	s.SetReady(true)
+
+	if err := s.Drain(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := check("/readyz"); got != stdhttp.StatusServiceUnavailable {
+		t.Errorf("expected readiness to be 503 once draining, got %d", got)
+	}
+	if got := check("/healthz"); got != stdhttp.StatusOK {
+		t.Errorf("expected liveness to stay 200, got %d", got)
+	}
+
+	// Only requests other than health checks are instrumented.
+	if got := check("/other"); got != stdhttp.StatusNotFound {
+		t.Errorf("expected other requests to reach the handler, got %d", got)
+	}
+	if spans := exporter.GetSpans(); len(spans) != 1 {
+		t.Errorf("expected only the other request to record a span, got %d", len(spans))
+	}
+
+	if _, err := NewServer(":0", nil, WithHealthEndpoints("/health", "/health")); err == nil {
+		t.Error("expected an error for the same path twice")
+	}
+}
@@ -0,0 +1,101 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+)
+
+// Values of the error.type attribute.
+const (
+	// errorTypeCanceled means the request's context was canceled.
+	errorTypeCanceled = "canceled"
+
+	// errorTypeTimeout means a deadline passed: the request's context deadline, Client.Timeout, or an I/O
+	// timeout on the connection.
+	errorTypeTimeout = "timeout"
+
+	// errorTypeDNS means the host name could not be resolved.
+	errorTypeDNS = "dns"
+
+	// errorTypeTLS means the TLS handshake failed, e.g. because the certificate was not trusted.
+	errorTypeTLS = "tls"
+
+	// errorTypeConnectionRefused means nothing was listening at the address.
+	errorTypeConnectionRefused = "connection_refused"
+
+	// errorTypeConnect means a connection could not be established for another reason.
+	errorTypeConnect = "connect"
+
+	// errorTypeConnectionReset means an established connection was reset by the peer.
+	errorTypeConnectionReset = "connection_reset"
+
+	// errorTypeUnexpectedEOF means the connection was closed part way through a response.
+	errorTypeUnexpectedEOF = "unexpected_eof"
+
+	// errorTypeCircuitOpen means the request was rejected by an open circuit breaker.
+	errorTypeCircuitOpen = "circuit_open"
+
+	// errorTypeOther is the semantic conventions' fallback for errors that are not otherwise classified.
+	errorTypeOther = "_OTHER"
+)
+
+// errorType classifies a client error for the error.type attribute, so that failures can be broken down by
+// cause. The first matching rule wins:
+//
+//	ErrCircuitOpen                                      circuit_open
+//	context.Canceled                                    canceled
+//	*net.DNSError                                       dns
+//	TLS alert, record or certificate errors             tls
+//	context.DeadlineExceeded, net.Error timeouts        timeout
+//	ECONNREFUSED                                        connection_refused
+//	any other *net.OpError when dialing                 connect
+//	ECONNRESET                                          connection_reset
+//	io.ErrUnexpectedEOF                                 unexpected_eof
+//	anything else                                       _OTHER
+func errorType(err error) string {
+	var (
+		opErr  *net.OpError
+		dnsErr *net.DNSError
+		netErr net.Error
+	)
+	switch {
+	case errors.Is(err, ErrCircuitOpen):
+		return errorTypeCircuitOpen
+	case errors.Is(err, context.Canceled):
+		return errorTypeCanceled
+	case errors.As(err, &dnsErr):
+		return errorTypeDNS
+	case isTLSError(err):
+		return errorTypeTLS
+	case errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()):
+		return errorTypeTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return errorTypeConnectionRefused
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		return errorTypeConnect
+	case errors.Is(err, syscall.ECONNRESET):
+		return errorTypeConnectionReset
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return errorTypeUnexpectedEOF
+	}
+	return errorTypeOther
+}
+
+// isTLSError reports whether err comes from a failed TLS handshake.
+func isTLSError(err error) bool {
+	var (
+		alertErr     tls.AlertError
+		recordErr    tls.RecordHeaderError
+		verifyErr    *tls.CertificateVerificationError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+	return errors.As(err, &alertErr) || errors.As(err, &recordErr) || errors.As(err, &verifyErr) ||
+		errors.As(err, &authorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &invalidErr)
+}
@@ -0,0 +1,135 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+func TestErrorType(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "connection refused",
+			err:  &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)},
+			want: errorTypeConnectionRefused,
+		},
+		{
+			name: "connect",
+			err:  &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.EHOSTUNREACH)},
+			want: errorTypeConnect,
+		},
+		{
+			name: "dns",
+			err:  &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", IsNotFound: true}},
+			want: errorTypeDNS,
+		},
+		{
+			name: "tls",
+			err:  &tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}},
+			want: errorTypeTLS,
+		},
+		{name: "deadline", err: fmt.Errorf("get: %w", context.DeadlineExceeded), want: errorTypeTimeout},
+		{
+			name: "i/o timeout",
+			err:  &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded},
+			want: errorTypeTimeout,
+		},
+		{name: "canceled", err: fmt.Errorf("get: %w", context.Canceled), want: errorTypeCanceled},
+		{
+			name: "reset mid-body",
+			err:  &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)},
+			want: errorTypeConnectionReset,
+		},
+		{name: "unexpected EOF", err: fmt.Errorf("reading body: %w", io.ErrUnexpectedEOF), want: errorTypeUnexpectedEOF},
+		{name: "circuit open", err: fmt.Errorf("get: %w", ErrCircuitOpen), want: errorTypeCircuitOpen},
+		{name: "other", err: errors.New("boom"), want: errorTypeOther},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := errorType(tc.err); got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestErrorType_Recorded(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer slow.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	for _, tc := range []struct {
+		name    string
+		url     string
+		timeout time.Duration
+		want    string
+	}{
+		{name: "deadline exceeded", url: slow.URL, timeout: 50 * time.Millisecond, want: errorTypeTimeout},
+		{name: "dial error", url: closed.URL, timeout: time.Second, want: errorTypeConnectionRefused},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+			reader := sdkmetric.NewManualReader()
+			mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+			client, err := NewClient(WithClientMeterProvider(mp))
+			if err != nil {
+				t.Fatalf("NewClient failed: %v", err)
+			}
+
+			ctx, span := tp.Tracer("test").Start(context.Background(), "parent-span")
+			ctx, cancel := context.WithTimeout(ctx, tc.timeout)
+			defer cancel()
+			req, _ := http.NewRequestWithContext(ctx, "GET", tc.url, nil)
+			if _, err := client.Do(req); err == nil {
+				t.Fatal("Expected the request to fail")
+			}
+			span.End()
+
+			want := semconv.ErrorTypeKey.String(tc.want)
+			if !hasAttr(exporter.GetSpans()[0].Attributes, want) {
+				t.Errorf("Expected %s on the span, got %v", want.Value.Emit(), exporter.GetSpans()[0].Attributes)
+			}
+
+			var rm metricdata.ResourceMetrics
+			if err := reader.Collect(context.Background(), &rm); err != nil {
+				t.Fatalf("Collect failed: %v", err)
+			}
+			var found bool
+			for _, sm := range rm.ScopeMetrics {
+				for _, m := range sm.Metrics {
+					if h, ok := m.Data.(metricdata.Histogram[float64]); ok && m.Name == "http.client.request.duration" {
+						for _, p := range h.DataPoints {
+							found = found || hasAttr(p.Attributes.ToSlice(), want)
+						}
+					}
+				}
+			}
+			if !found {
+				t.Errorf("Expected a duration data point with %s", want.Value.Emit())
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package http
+
+import (
+	"fmt"
+	"net"
+	stdhttp "net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// WithTrustedProxies sets the proxies (as CIDRs, e.g. "10.0.0.0/8", or single addresses) whose forwarding
+// headers are trusted. Requests arriving from a trusted proxy have their client address taken from the
+// Forwarded header (RFC 7239) or, if there is none, X-Forwarded-For, rather than from the connection, which is
+// still recorded as network.peer.address, and their url.scheme from the same hop's proto parameter or
+// X-Forwarded-Proto. By default no proxy is trusted and forwarding headers are ignored, since any client can
+// set them.
+func WithTrustedProxies(cidrs ...string) ServerOption {
+	return func(s *Server) error {
+		for _, cidr := range cidrs {
+			prefix, err := parsePrefix(cidr)
+			if err != nil {
+				return fmt.Errorf("invalid trusted proxy %q: %w", cidr, err)
+			}
+			s.clientAddress.trustedProxies = append(s.clientAddress.trustedProxies, prefix)
+		}
+		return nil
+	}
+}
+
+// WithoutClientAddress stops client.address and network.peer.address being recorded on server spans, for
+// privacy.
+func WithoutClientAddress() ServerOption {
+	return func(s *Server) error {
+		s.clientAddress.omit = true
+		return nil
+	}
+}
+
+// WithClientPort records the client's port as client.port on server spans, alongside client.address. The
+// port is only known for direct connections, and is not recorded for forwarded requests.
+func WithClientPort() ServerOption {
+	return func(s *Server) error {
+		s.clientAddress.port = true
+		return nil
+	}
+}
+
+// parsePrefix parses a CIDR, or a single address as a prefix covering only that address.
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		return prefix.Masked(), err
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// clientAddressSource determines the client address recorded for a request.
+type clientAddressSource struct {
+	// trustedProxies are the proxies whose forwarding headers are believed.
+	trustedProxies []netip.Prefix
+
+	// omit disables recording the client address.
+	omit bool
+
+	// port enables recording the client port.
+	port bool
+}
+
+// attrs returns the client.address (and, if configured, client.port) attributes for the request, and the
+// network.peer.address and network.peer.port of the connection it arrived on, which is the proxy's for a
+// forwarded request.
+func (c clientAddressSource) attrs(r *stdhttp.Request) []attribute.KeyValue {
+	if c.omit {
+		return nil
+	}
+
+	var attrs []attribute.KeyValue
+	if host, port := c.hostPort(r); host != "" {
+		attrs = append(attrs, semconv.ClientAddress(host))
+		if p, err := strconv.Atoi(port); c.port && err == nil {
+			attrs = append(attrs, semconv.ClientPort(p))
+		}
+	}
+	if peer, port := splitHostPort(r.RemoteAddr); peer != "" {
+		attrs = append(attrs, semconv.NetworkPeerAddress(peer))
+		if p, err := strconv.Atoi(port); err == nil {
+			attrs = append(attrs, semconv.NetworkPeerPort(p))
+		}
+	}
+	return attrs
+}
+
+// host returns the client's address, whether or not it is recorded.
+func (c clientAddressSource) host(r *stdhttp.Request) string {
+	host, _ := c.hostPort(r)
+	return host
+}
+
+// hostPort returns the client's address and port, taking the address from the forwarding headers if the
+// request came from a trusted proxy, in which case the port is not known.
+func (c clientAddressSource) hostPort(r *stdhttp.Request) (host, port string) {
+	host, port = splitHostPort(r.RemoteAddr)
+	if forwarded := c.forwardedFor(host, r.Header); forwarded != "" {
+		return forwarded, ""
+	}
+	return host, port
+}
+
+// scheme returns the scheme the client used: "https" for a TLS connection, otherwise the scheme a trusted proxy
+// recorded for the hop that the client address is taken from, or "http". Forwarded schemes other than http and
+// https are ignored, to keep the attribute's cardinality down.
+func (c clientAddressSource) scheme(r *stdhttp.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	peer, _ := splitHostPort(r.RemoteAddr)
+	if hop, ok := c.clientHop(peer, r.Header); ok {
+		if proto := strings.ToLower(hop.proto); proto == "http" || proto == "https" {
+			return proto
+		}
+	}
+	return "http"
+}
+
+// forwardedFor returns the client address from the forwarding headers if the request came from a trusted
+// proxy.
+func (c clientAddressSource) forwardedFor(peer string, h stdhttp.Header) string {
+	hop, _ := c.clientHop(peer, h)
+	host, _ := splitHostPort(hop.addr)
+	return host
+}
+
+// clientHop returns the hop of the forwarding headers that describes the client, if the request came from a
+// trusted proxy. The hops are walked from the right, skipping trusted proxies, so that hops prepended by the
+// client itself are ignored.
+func (c clientAddressSource) clientHop(peer string, h stdhttp.Header) (forwardedHop, bool) {
+	if !c.trusted(peer) {
+		return forwardedHop{}, false
+	}
+
+	hops := forwardedHops(h)
+	for i := len(hops) - 1; i >= 0; i-- {
+		if host, _ := splitHostPort(hops[i].addr); i == 0 || !c.trusted(host) {
+			return hops[i], true
+		}
+	}
+	return forwardedHop{}, false
+}
+
+// forwardedHop is a hop recorded in the forwarding headers: the address the request was forwarded for, and
+// the scheme it was received with. Either may be empty, if the proxy didn't record it.
+type forwardedHop struct {
+	addr, proto string
+}
+
+// forwardedHops returns the hops a request was forwarded through, the client's first, from the elements of
+// the Forwarded header (RFC 7239) or, if there is none, from X-Forwarded-For and X-Forwarded-Proto. Each proxy
+// appends to the latter two, so their values are matched up from the right.
+func forwardedHops(h stdhttp.Header) []forwardedHop {
+	var hops []forwardedHop
+	if values := h.Values("Forwarded"); len(values) > 0 {
+		for _, v := range values {
+			for _, element := range strings.Split(v, ",") {
+				var hop forwardedHop
+				for _, pair := range strings.Split(element, ";") {
+					key, value, _ := strings.Cut(strings.TrimSpace(pair), "=")
+					value = strings.Trim(value, `"`)
+					switch {
+					case strings.EqualFold(key, "for"):
+						hop.addr = value
+					case strings.EqualFold(key, "proto"):
+						hop.proto = value
+					}
+				}
+				hops = append(hops, hop)
+			}
+		}
+		return hops
+	}
+
+	addrs, protos := headerList(h, "X-Forwarded-For"), headerList(h, "X-Forwarded-Proto")
+	n := max(len(addrs), len(protos))
+	hops = make([]forwardedHop, n)
+	for i, addr := range addrs {
+		hops[n-len(addrs)+i].addr = addr
+	}
+	for i, proto := range protos {
+		hops[n-len(protos)+i].proto = proto
+	}
+	return hops
+}
+
+// headerList returns the comma-separated elements of the header's values, without empty elements.
+func headerList(h stdhttp.Header, name string) []string {
+	var elements []string
+	for _, v := range h.Values(name) {
+		for _, element := range strings.Split(v, ",") {
+			if element = strings.TrimSpace(element); element != "" {
+				elements = append(elements, element)
+			}
+		}
+	}
+	return elements
+}
+
+// trusted reports whether the address belongs to a trusted proxy.
+func (c clientAddressSource) trusted(host string) bool {
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range c.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitHostPort splits an address into host and port, tolerating a missing port and bracketed IPv6
+// addresses without one (e.g. "[::1]").
+func splitHostPort(addr string) (host, port string) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]"), ""
+	}
+	return host, port
+}
@@ -0,0 +1,246 @@
+package http
+
+import (
+	"context"
+	"net"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+func TestClientAddress(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		opts       []ServerOption
+		remoteAddr string
+		forwarded  string
+		rfc7239    string
+		want       []attribute.KeyValue
+		wantAbsent []attribute.Key
+	}{
+		{
+			name:       "direct",
+			remoteAddr: "203.0.113.7:54321",
+			want: []attribute.KeyValue{
+				semconv.ClientAddress("203.0.113.7"),
+				semconv.NetworkPeerAddress("203.0.113.7"),
+				semconv.NetworkPeerPort(54321),
+			},
+			wantAbsent: []attribute.Key{semconv.ClientPortKey},
+		},
+		{
+			name:       "direct with port",
+			opts:       []ServerOption{WithClientPort()},
+			remoteAddr: "[2001:db8::1]:54321",
+			want:       []attribute.KeyValue{semconv.ClientAddress("2001:db8::1"), semconv.ClientPort(54321)},
+		},
+		{
+			name:       "forwarded header ignored without trusted proxy",
+			remoteAddr: "10.0.0.1:54321",
+			forwarded:  "198.51.100.1",
+			want:       []attribute.KeyValue{semconv.ClientAddress("10.0.0.1")},
+		},
+		{
+			name:       "forwarded through trusted proxies",
+			opts:       []ServerOption{WithTrustedProxies("10.0.0.0/8"), WithClientPort()},
+			remoteAddr: "10.0.0.1:54321",
+			forwarded:  "192.0.2.99, 198.51.100.1, 10.0.0.2",
+			want: []attribute.KeyValue{
+				semconv.ClientAddress("198.51.100.1"),
+				semconv.NetworkPeerAddress("10.0.0.1"),
+				semconv.NetworkPeerPort(54321),
+			},
+			wantAbsent: []attribute.Key{semconv.ClientPortKey},
+		},
+		{
+			name:       "RFC 7239 forwarded through trusted proxies",
+			opts:       []ServerOption{WithTrustedProxies("10.0.0.0/8")},
+			remoteAddr: "10.0.0.1:54321",
+			rfc7239:    `for=192.0.2.99;proto=https, for="[2001:db8:cafe::17]:4711", for=10.0.0.2;by=10.0.0.1`,
+			want: []attribute.KeyValue{
+				semconv.ClientAddress("2001:db8:cafe::17"),
+				semconv.NetworkPeerAddress("10.0.0.1"),
+			},
+		},
+		{
+			name:       "RFC 7239 preferred to X-Forwarded-For",
+			opts:       []ServerOption{WithTrustedProxies("10.0.0.0/8")},
+			remoteAddr: "10.0.0.1:54321",
+			forwarded:  "198.51.100.1",
+			rfc7239:    "for=192.0.2.60",
+			want:       []attribute.KeyValue{semconv.ClientAddress("192.0.2.60")},
+		},
+		{
+			name:       "RFC 7239 ignored without trusted proxy",
+			remoteAddr: "10.0.0.1:54321",
+			rfc7239:    "for=192.0.2.60",
+			want:       []attribute.KeyValue{semconv.ClientAddress("10.0.0.1")},
+		},
+		{
+			name:       "omitted",
+			opts:       []ServerOption{WithoutClientAddress()},
+			remoteAddr: "203.0.113.7:54321",
+			wantAbsent: []attribute.Key{semconv.ClientAddressKey, semconv.NetworkPeerAddressKey},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+			s, err := NewServer(":0", stdhttp.NotFoundHandler(), append(tc.opts, WithServerTracerProvider(tp))...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tc.remoteAddr
+			if tc.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tc.forwarded)
+			}
+			if tc.rfc7239 != "" {
+				req.Header.Set("Forwarded", tc.rfc7239)
+			}
+			s.server.Handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			attrs := exporter.GetSpans()[0].Attributes
+			for _, want := range tc.want {
+				if !hasAttr(attrs, want) {
+					t.Errorf("expected %s=%s, got %v", want.Key, want.Value.Emit(), attrs)
+				}
+			}
+			for _, key := range tc.wantAbsent {
+				for _, a := range attrs {
+					if a.Key == key {
+						t.Errorf("expected no %s, got %s", key, a.Value.Emit())
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestServerAddress(t *testing.T) {
+	for _, tc := range []struct {
+		host       string
+		localAddr  net.Addr
+		want       []attribute.KeyValue
+		wantAbsent []attribute.Key
+	}{
+		{
+			host: "example.com:8443",
+			want: []attribute.KeyValue{semconv.ServerAddress("example.com"), semconv.ServerPort(8443)},
+		},
+		{
+			host:       "example.com",
+			want:       []attribute.KeyValue{semconv.ServerAddress("example.com")},
+			wantAbsent: []attribute.Key{semconv.ServerPortKey},
+		},
+		{
+			// The port is taken from the address the request was received on, if the Host has none.
+			host:      "example.org",
+			localAddr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8080},
+			want:      []attribute.KeyValue{semconv.ServerAddress("example.org"), semconv.ServerPort(8080)},
+		},
+		{
+			host: "[2001:db8::1]:8080",
+			want: []attribute.KeyValue{semconv.ServerAddress("2001:db8::1"), semconv.ServerPort(8080)},
+		},
+	} {
+		t.Run(tc.host, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+			s, err := NewServer(":0", stdhttp.NotFoundHandler(), WithServerTracerProvider(tp))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Host = tc.host
+			if tc.localAddr != nil {
+				req = req.WithContext(context.WithValue(req.Context(), stdhttp.LocalAddrContextKey, tc.localAddr))
+			}
+			s.server.Handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			attrs := exporter.GetSpans()[0].Attributes
+			for _, want := range tc.want {
+				if !hasAttr(attrs, want) {
+					t.Errorf("expected %s=%s, got %v", want.Key, want.Value.Emit(), attrs)
+				}
+			}
+			for _, key := range tc.wantAbsent {
+				for _, a := range attrs {
+					if a.Key == key {
+						t.Errorf("expected no %s, got %s", key, a.Value.Emit())
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestServerScheme(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		target  string
+		trusted bool
+		header  stdhttp.Header
+		want    string
+	}{
+		{name: "plain", target: "/", want: "http"},
+		{name: "tls", target: "https://example.com/", want: "https"},
+		{name: "forwarded proto", target: "/", trusted: true,
+			header: stdhttp.Header{"X-Forwarded-Proto": {"HTTPS"}}, want: "https"},
+		{name: "forwarded through trusted proxies", target: "/", trusted: true,
+			header: stdhttp.Header{"X-Forwarded-For": {"198.51.100.1, 10.0.0.2"}, "X-Forwarded-Proto": {"https, http"}},
+			want:   "https"},
+		{name: "spoofed leftmost proto", target: "/", trusted: true,
+			header: stdhttp.Header{"X-Forwarded-For": {"192.0.2.99, 198.51.100.1"}, "X-Forwarded-Proto": {"https, http"}},
+			want:   "http"},
+		{name: "spoofed proto without an address", target: "/", trusted: true,
+			header: stdhttp.Header{"X-Forwarded-Proto": {"https, http"}}, want: "http"},
+		{name: "rfc 7239", target: "/", trusted: true,
+			header: stdhttp.Header{"Forwarded": {"for=198.51.100.1;proto=https"}}, want: "https"},
+		{name: "rfc 7239 spoofed leftmost proto", target: "/", trusted: true,
+			header: stdhttp.Header{"Forwarded": {"for=192.0.2.99;proto=https, for=198.51.100.1;proto=http"}},
+			want:   "http"},
+		{name: "untrusted", target: "/", header: stdhttp.Header{"X-Forwarded-Proto": {"https"}}, want: "http"},
+		{name: "unknown scheme", target: "/", trusted: true,
+			header: stdhttp.Header{"X-Forwarded-Proto": {"gopher"}}, want: "http"},
+		{name: "tls not downgraded", target: "https://example.com/", trusted: true,
+			header: stdhttp.Header{"X-Forwarded-Proto": {"http"}}, want: "https"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+			opts := []ServerOption{WithServerTracerProvider(tp)}
+			if tc.trusted {
+				opts = append(opts, WithTrustedProxies("10.0.0.0/8"))
+			}
+			s, err := NewServer(":0", stdhttp.NotFoundHandler(), opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			req := httptest.NewRequest("GET", tc.target, nil)
+			req.RemoteAddr = "10.0.0.1:54321"
+			for name, values := range tc.header {
+				req.Header[name] = values
+			}
+			s.server.Handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if attrs := exporter.GetSpans()[0].Attributes; !hasAttr(attrs, semconv.URLScheme(tc.want)) {
+				t.Errorf("expected url.scheme=%s, got %v", tc.want, attrs)
+			}
+		})
+	}
+}
+
+func TestWithTrustedProxies_Invalid(t *testing.T) {
+	if _, err := NewServer(":0", nil, WithTrustedProxies("not-a-cidr")); err == nil {
+		t.Error("expected error for invalid trusted proxy")
+	}
+}
@@ -0,0 +1,38 @@
+package http
+
+import (
+	stdhttp "net/http"
+)
+
+// WithServerH2C accepts HTTP/2 over cleartext (h2c) connections from clients with prior knowledge of HTTP/2
+// support, alongside HTTP/1.1. Connections over TLS negotiate HTTP/2 as usual, so this only matters for
+// servers without TLS, such as those behind a proxy that terminates it.
+//
+// Upgrading an HTTP/1.1 connection to h2c with the Upgrade header is not supported.
+func WithServerH2C() ServerOption {
+	return func(s *Server) error {
+		var p stdhttp.Protocols
+		p.SetHTTP1(true)
+		p.SetHTTP2(true)
+		p.SetUnencryptedHTTP2(true)
+		s.server.Protocols = &p
+		return nil
+	}
+}
+
+// WithClientH2C sends requests to http:// URLs over HTTP/2 over cleartext (h2c), with prior knowledge that
+// the server supports it, such as a server configured with WithServerH2C. The client no longer falls back to
+// HTTP/1.1, so servers that don't support HTTP/2 can't be reached with it.
+func WithClientH2C() ClientOption {
+	return func(c *stdhttp.Client) error {
+		t, err := getTransport(c)
+		if err != nil {
+			return err
+		}
+		var p stdhttp.Protocols
+		p.SetHTTP2(true)
+		p.SetUnencryptedHTTP2(true)
+		t.Protocols = &p
+		return nil
+	}
+}
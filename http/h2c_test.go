@@ -0,0 +1,68 @@
+package http
+
+import (
+	"context"
+	"io"
+	stdhttp "net/http"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestH2C(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+
+	s, err := NewServer("127.0.0.1:0", stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		_, _ = io.WriteString(w, r.Proto)
+	}), WithServerH2C(), WithServerTracerProvider(tp))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- s.RunContext(ctx) }()
+	url := "http://" + listeningAddr(t, s) + "/"
+
+	c, err := NewClient(WithClientH2C())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp, err := c.Get(url)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.ProtoMajor != 2 || string(body) != "HTTP/2.0" {
+		t.Errorf("expected the request to be made over HTTP/2, got %s (server saw %q)", resp.Proto, body)
+	}
+
+	// HTTP/1.1 clients are still served.
+	resp, err = stdhttp.Get(url)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if string(body) != "HTTP/1.1" {
+		t.Errorf("expected an HTTP/1.1 request to be served, got %q", body)
+	}
+
+	if spans := exporter.GetSpans(); len(spans) != 2 {
+		t.Errorf("expected a server span for each request, got %d", len(spans))
+	}
+
+	cancel()
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the server to shut down gracefully")
+	}
+}
@@ -0,0 +1,84 @@
+package http
+
+import (
+	"errors"
+	stdhttp "net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// HandlerOption configures a handler instrumented with NewHandler.
+type HandlerOption func(*instrumentedHandler) error
+
+// WithHandlerTracerProvider configures the handler with a specific tracer provider.
+func WithHandlerTracerProvider(tp trace.TracerProvider) HandlerOption {
+	return func(h *instrumentedHandler) error {
+		if tp == nil {
+			return errors.New("tracer provider must not be nil")
+		}
+		h.tracer = tp.Tracer(instrumentationName)
+		return nil
+	}
+}
+
+// WithHandlerMeterProvider configures the handler with a specific meter provider.
+func WithHandlerMeterProvider(mp metric.MeterProvider) HandlerOption {
+	return func(h *instrumentedHandler) error {
+		if mp == nil {
+			return errors.New("meter provider must not be nil")
+		}
+		h.meter = mp.Meter(instrumentationName)
+		return nil
+	}
+}
+
+// WithHandlerPropagator extracts trace context from requests with p, in place of the global propagator.
+func WithHandlerPropagator(p propagation.TextMapPropagator) HandlerOption {
+	return func(h *instrumentedHandler) error {
+		if p == nil {
+			return errors.New("propagator must not be nil")
+		}
+		h.propagator = p
+		return nil
+	}
+}
+
+// NewHandler wraps h in the same instrumentation as a server from NewServer, for handlers served some other
+// way, such as mounted in a larger mux or framework. Each request gets a server span and is recorded in
+// http.server.request.duration, http.server.active_requests and http.server.panics, and panics are recovered
+// with a 500. If h is nil, http.DefaultServeMux is used, as it is by NewServer.
+//
+// Only the request-level telemetry is recorded: the connection metrics and features such as draining and rate
+// limiting belong to the Server.
+func NewHandler(h stdhttp.Handler, opts ...HandlerOption) (stdhttp.Handler, error) {
+	if h == nil {
+		h = stdhttp.DefaultServeMux
+	}
+	ih := &instrumentedHandler{base: h}
+	for _, opt := range opts {
+		if err := opt(ih); err != nil {
+			return nil, err
+		}
+	}
+	if ih.tracer == nil {
+		ih.tracer = otel.GetTracerProvider().Tracer(instrumentationName)
+	}
+	if ih.meter == nil {
+		ih.meter = otel.GetMeterProvider().Meter(instrumentationName)
+	}
+
+	var err error
+	if ih.mActiveRequests, err = ih.meter.Int64UpDownCounter("http.server.active_requests"); err != nil {
+		return nil, err
+	}
+	if ih.mDuration, err = ih.meter.Float64Histogram("http.server.request.duration", metric.WithUnit("s")); err != nil {
+		return nil, err
+	}
+	if ih.mPanics, err = ih.meter.Int64Counter("http.server.panics"); err != nil {
+		return nil, err
+	}
+	return ih, nil
+}
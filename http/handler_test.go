@@ -0,0 +1,139 @@
+package http
+
+import (
+	"context"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestNewHandler(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	// The handler is mounted in a mux of the caller's, rather than served by a Server.
+	mux := stdhttp.NewServeMux()
+	h, err := NewHandler(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, _ *stdhttp.Request) {
+		w.WriteHeader(stdhttp.StatusCreated)
+	}), WithHandlerTracerProvider(tp), WithHandlerMeterProvider(mp))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mux.Handle("/bar", h)
+
+	req := httptest.NewRequest(stdhttp.MethodPost, "/bar", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Code != stdhttp.StatusCreated {
+		t.Errorf("expected 201, got %d", w.Code)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	s := spans[0]
+	// The route is taken from the pattern the mux matched.
+	if s.Name != "HTTP POST /bar" {
+		t.Errorf("expected span name HTTP POST /bar, got %s", s.Name)
+	}
+	if s.SpanKind != oteltrace.SpanKindServer {
+		t.Errorf("expected a server span, got %v", s.SpanKind)
+	}
+	if !hasAttr(s.Attributes, semconv.HTTPRequestMethodKey.String("POST")) {
+		t.Error("missing http.request.method=POST")
+	}
+	if !hasAttr(s.Attributes, semconv.URLPathKey.String("/bar")) {
+		t.Error("missing url.path=/bar")
+	}
+	if !hasAttr(s.Attributes, semconv.HTTPResponseStatusCodeKey.Int(201)) {
+		t.Error("missing http.response.status_code=201")
+	}
+
+	if got := histogramCountWithAttr(t, reader, "http.server.request.duration",
+		semconv.HTTPResponseStatusCodeKey.Int(201)); got != 1 {
+		t.Errorf("expected 1 request duration recorded, got %d", got)
+	}
+}
+
+func TestNewHandler_Propagation(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+
+	h, err := NewHandler(stdhttp.NotFoundHandler(),
+		WithHandlerTracerProvider(tp),
+		WithHandlerPropagator(propagation.TraceContext{}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	parent.End()
+	req := httptest.NewRequest(stdhttp.MethodGet, "/", nil)
+	propagation.TraceContext{}.Inject(ctx, propagation.HeaderCarrier(req.Header))
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	for _, s := range exporter.GetSpans() {
+		if s.SpanKind != oteltrace.SpanKindServer {
+			continue
+		}
+		if s.Parent.SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("expected the server span to continue the incoming trace, got parent %s", s.Parent.SpanID())
+		}
+		return
+	}
+	t.Fatal("expected a server span")
+}
+
+func TestNewHandler_Panic(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	h, err := NewHandler(stdhttp.HandlerFunc(func(stdhttp.ResponseWriter, *stdhttp.Request) {
+		panic("boom")
+	}), WithHandlerMeterProvider(mp))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(stdhttp.MethodGet, "/", nil))
+
+	if w.Code != stdhttp.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", w.Code)
+	}
+	if got := sumCounter(t, reader, "http.server.panics"); got != 1 {
+		t.Errorf("expected 1 panic counted, got %d", got)
+	}
+}
+
+func TestNewHandler_Options(t *testing.T) {
+	for _, opt := range []HandlerOption{
+		WithHandlerTracerProvider(nil), WithHandlerMeterProvider(nil), WithHandlerPropagator(nil),
+	} {
+		if _, err := NewHandler(stdhttp.NotFoundHandler(), opt); err == nil {
+			t.Error("expected an error for a nil option value")
+		}
+	}
+
+	// A nil handler serves http.DefaultServeMux, as NewServer does.
+	h, err := NewHandler(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(stdhttp.MethodGet, "/not-registered", nil))
+	if w.Code != stdhttp.StatusNotFound {
+		t.Errorf("expected 404 from http.DefaultServeMux, got %d", w.Code)
+	}
+}
@@ -0,0 +1,121 @@
+package http
+
+import (
+	"errors"
+	stdhttp "net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// sensitiveHeaders are the headers whose values are always redacted when captured, as they carry credentials.
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+	"X-Api-Key":           true,
+}
+
+// headerCapture is the set of headers recorded as span attributes, in canonical form.
+type headerCapture []string
+
+// newHeaderCapture returns a capture of the given headers, which may be named in any case.
+func newHeaderCapture(keys []string) (headerCapture, error) {
+	hc := make(headerCapture, 0, len(keys))
+	for _, key := range keys {
+		if key == "" {
+			return nil, errors.New("captured header names must not be empty")
+		}
+		hc = append(hc, stdhttp.CanonicalHeaderKey(key))
+	}
+	return hc, nil
+}
+
+// attrs returns the captured headers present in h as attributes named prefix followed by the lower-cased
+// header name, e.g. http.request.header.x-request-id. Multiple values are joined with commas, as they would be
+// on the wire, and the values of sensitive headers are redacted.
+func (hc headerCapture) attrs(prefix string, h stdhttp.Header) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	for _, key := range hc {
+		values := h.Values(key)
+		if len(values) == 0 {
+			continue
+		}
+		value := strings.Join(values, ",")
+		if sensitiveHeaders[key] {
+			value = redacted
+		}
+		attrs = append(attrs, attribute.String(prefix+strings.ToLower(key), value))
+	}
+	return attrs
+}
+
+// The prefixes of the attributes recording captured headers.
+const (
+	requestHeaderPrefix  = "http.request.header."
+	responseHeaderPrefix = "http.response.header."
+)
+
+// WithClientCaptureRequestHeaders records the given request headers on the client span as
+// http.request.header.<key> attributes, joining multiple values with commas. The values of headers that carry
+// credentials (Authorization, Proxy-Authorization, Cookie, Set-Cookie and X-Api-Key) are recorded as
+// "[REDACTED]". Only the given headers are recorded, in place of every header that is otherwise recorded on the
+// client span.
+func WithClientCaptureRequestHeaders(keys ...string) ClientOption {
+	return func(c *stdhttp.Client) error {
+		it, ok := c.Transport.(*InstrumentedTransport)
+		if !ok {
+			return errors.New("client transport must be *InstrumentedTransport")
+		}
+		hc, err := newHeaderCapture(keys)
+		if err != nil {
+			return err
+		}
+		it.requestHeaders = hc
+		return nil
+	}
+}
+
+// WithClientCaptureResponseHeaders records the given response headers on the client span as
+// http.response.header.<key> attributes, redacting credentials as WithClientCaptureRequestHeaders does.
+func WithClientCaptureResponseHeaders(keys ...string) ClientOption {
+	return func(c *stdhttp.Client) error {
+		it, ok := c.Transport.(*InstrumentedTransport)
+		if !ok {
+			return errors.New("client transport must be *InstrumentedTransport")
+		}
+		hc, err := newHeaderCapture(keys)
+		if err != nil {
+			return err
+		}
+		it.responseHeaders = hc
+		return nil
+	}
+}
+
+// WithServerCaptureRequestHeaders records the given request headers on the server span as
+// http.request.header.<key> attributes, redacting credentials as WithClientCaptureRequestHeaders does.
+func WithServerCaptureRequestHeaders(keys ...string) ServerOption {
+	return func(s *Server) error {
+		hc, err := newHeaderCapture(keys)
+		if err != nil {
+			return err
+		}
+		s.requestHeaders = hc
+		return nil
+	}
+}
+
+// WithServerCaptureResponseHeaders records the given response headers on the server span as
+// http.response.header.<key> attributes, redacting credentials as WithClientCaptureRequestHeaders does.
+func WithServerCaptureResponseHeaders(keys ...string) ServerOption {
+	return func(s *Server) error {
+		hc, err := newHeaderCapture(keys)
+		if err != nil {
+			return err
+		}
+		s.responseHeaders = hc
+		return nil
+	}
+}
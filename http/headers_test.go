@@ -0,0 +1,89 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestCaptureHeaders(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("X-Cache", "hit")
+		w.Header().Add("X-Cache", "miss")
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.WriteHeader(http.StatusOK)
+	})
+	srv, err := NewServer(":0", handler,
+		WithServerTracerProvider(tp),
+		WithServerCaptureRequestHeaders("x-request-id", "Authorization", "X-Absent"),
+		WithServerCaptureResponseHeaders("X-Cache", "Set-Cookie"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv.server.Handler)
+	defer ts.Close()
+
+	client, err := NewClient(
+		WithClientTracerProvider(tp),
+		WithClientCaptureRequestHeaders("X-Request-Id", "authorization"),
+		WithClientCaptureResponseHeaders("x-cache", "set-cookie"),
+	)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "parent-span")
+	req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL, nil)
+	req.Header.Set("X-Request-Id", "abc123")
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do failed: %v", err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	span.End()
+
+	want := []attribute.KeyValue{
+		attribute.String("http.request.header.x-request-id", "abc123"),
+		attribute.String("http.request.header.authorization", "[REDACTED]"),
+		attribute.String("http.response.header.x-cache", "hit,miss"),
+		attribute.String("http.response.header.set-cookie", "[REDACTED]"),
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+	for _, s := range spans {
+		for _, kv := range want {
+			if !hasAttr(s.Attributes, kv) {
+				t.Errorf("%s: missing %s=%s", s.Name, kv.Key, kv.Value.Emit())
+			}
+		}
+		for _, kv := range s.Attributes {
+			// Headers that are absent, or weren't selected, aren't recorded.
+			if kv.Key == "http.request.header.x-absent" || kv.Key == "http.request.header.user-agent" {
+				t.Errorf("%s: unexpected %s", s.Name, kv.Key)
+			}
+		}
+	}
+}
+
+func TestCaptureHeaders_EmptyName(t *testing.T) {
+	if _, err := NewClient(WithClientCaptureRequestHeaders("")); err == nil {
+		t.Error("Expected an error for an empty client header name")
+	}
+	if _, err := NewServer(":0", http.NotFoundHandler(), WithServerCaptureResponseHeaders("")); err == nil {
+		t.Error("Expected an error for an empty server header name")
+	}
+}
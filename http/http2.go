@@ -0,0 +1,91 @@
+package http
+
+import (
+	"crypto/tls"
+	"errors"
+	stdhttp "net/http"
+	"time"
+)
+
+// HTTP2Option configures the client's HTTP/2 settings, set with WithHTTP2.
+type HTTP2Option func(*stdhttp.HTTP2Config) error
+
+// WithHTTP2 configures how the client uses HTTP/2, which it negotiates with servers over TLS by default.
+// Settings that aren't configured keep the net/http defaults. The number of concurrent streams on a
+// connection is limited by the server, rather than configured here.
+//
+// Connections behind a load balancer can be silently dropped, leaving requests on them to hang until they
+// time out. WithHTTP2ReadIdleTimeout detects such connections with a ping health check, so that they are
+// closed and later requests use a new one.
+func WithHTTP2(opts ...HTTP2Option) ClientOption {
+	return func(c *stdhttp.Client) error {
+		t, err := getTransport(c)
+		if err != nil {
+			return err
+		}
+		cfg := stdhttp.HTTP2Config{}
+		if t.HTTP2 != nil {
+			cfg = *t.HTTP2
+		}
+		for _, opt := range opts {
+			if err := opt(&cfg); err != nil {
+				return err
+			}
+		}
+		t.HTTP2 = &cfg
+		t.ForceAttemptHTTP2 = true
+		return nil
+	}
+}
+
+// WithHTTP2ReadIdleTimeout sends a ping to check the health of a connection once nothing has been received
+// on it for d. By default, no health checks are made.
+func WithHTTP2ReadIdleTimeout(d time.Duration) HTTP2Option {
+	return func(cfg *stdhttp.HTTP2Config) error {
+		if d <= 0 {
+			return errors.New("HTTP/2 read idle timeout must be positive")
+		}
+		cfg.SendPingTimeout = d
+		return nil
+	}
+}
+
+// WithHTTP2PingTimeout closes a connection if a health check ping is not answered within d (by default 15s).
+// See WithHTTP2ReadIdleTimeout.
+func WithHTTP2PingTimeout(d time.Duration) HTTP2Option {
+	return func(cfg *stdhttp.HTTP2Config) error {
+		if d <= 0 {
+			return errors.New("HTTP/2 ping timeout must be positive")
+		}
+		cfg.PingTimeout = d
+		return nil
+	}
+}
+
+// WithHTTP2WriteByteTimeout closes a connection if no data can be written to it for d, while there is data
+// to write. By default, there is no timeout.
+func WithHTTP2WriteByteTimeout(d time.Duration) HTTP2Option {
+	return func(cfg *stdhttp.HTTP2Config) error {
+		if d <= 0 {
+			return errors.New("HTTP/2 write byte timeout must be positive")
+		}
+		cfg.WriteByteTimeout = d
+		return nil
+	}
+}
+
+// WithDisableHTTP2 makes the client use HTTP/1.1 only, even with servers that support HTTP/2. It overrides
+// WithHTTP2 and WithClientH2C.
+func WithDisableHTTP2() ClientOption {
+	return func(c *stdhttp.Client) error {
+		t, err := getTransport(c)
+		if err != nil {
+			return err
+		}
+		// A non-nil, empty TLSNextProto disables HTTP/2, unless ForceAttemptHTTP2 or Protocols enable it.
+		t.TLSNextProto = map[string]func(string, *tls.Conn) stdhttp.RoundTripper{}
+		t.ForceAttemptHTTP2 = false
+		t.Protocols = nil
+		return nil
+	}
+}
@@ -0,0 +1,61 @@
+package http
+
+import (
+	stdhttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithHTTP2(t *testing.T) {
+	ts := httptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	for _, tc := range []struct {
+		name      string
+		opt       ClientOption
+		wantMajor int
+	}{
+		{name: "default", opt: WithHTTP2(), wantMajor: 2},
+		{name: "tuned", opt: WithHTTP2(
+			WithHTTP2ReadIdleTimeout(10*time.Second),
+			WithHTTP2PingTimeout(time.Second),
+			WithHTTP2WriteByteTimeout(time.Second),
+		), wantMajor: 2},
+		{name: "disabled", opt: WithDisableHTTP2(), wantMajor: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClient(WithInsecureSkipVerify(true), tc.opt)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			resp, err := c.Get(ts.URL)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			_ = resp.Body.Close()
+			if resp.ProtoMajor != tc.wantMajor {
+				t.Errorf("expected HTTP/%d to be negotiated, got %s", tc.wantMajor, resp.Proto)
+			}
+		})
+	}
+
+	c, err := NewClient(WithHTTP2(WithHTTP2ReadIdleTimeout(10*time.Second), WithHTTP2PingTimeout(time.Second)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tr, _ := getTransport(c)
+	if tr.HTTP2 == nil || tr.HTTP2.SendPingTimeout != 10*time.Second || tr.HTTP2.PingTimeout != time.Second {
+		t.Errorf("expected the health check to be configured, got %+v", tr.HTTP2)
+	}
+
+	for _, opt := range []HTTP2Option{
+		WithHTTP2ReadIdleTimeout(0), WithHTTP2PingTimeout(0), WithHTTP2WriteByteTimeout(0),
+	} {
+		if _, err := NewClient(WithHTTP2(opt)); err == nil {
+			t.Error("expected an error for a zero timeout")
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Package httptest provides a server for testing handlers along with the telemetry this module's http
+// package records for them, as net/http/httptest does for net/http. It is kept apart from the http package
+// so that the in-memory exporters it uses aren't pulled into production builds.
+package httptest
+
+import (
+	"context"
+	"net"
+	stdhttp "net/http"
+	"sync"
+	"time"
+
+	"github.com/andrewhowdencom/stdlib/http"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// closeTimeout bounds how long Close waits for requests in flight to finish.
+const closeTimeout = 5 * time.Second
+
+// Server is an instrumented server listening on a random loopback port, whose spans and metrics are kept
+// in memory for tests to inspect.
+type Server struct {
+	// URL is the base URL of the server, such as http://127.0.0.1:51234.
+	URL string
+
+	// Spans holds the spans the server (and clients from NewClient) have ended.
+	Spans *tracetest.InMemoryExporter
+
+	// Metrics reads the metrics the server (and clients from NewClient) have recorded.
+	Metrics *sdkmetric.ManualReader
+
+	// TracerProvider and MeterProvider are the providers the telemetry is recorded with, for instrumenting
+	// other code under test alongside the server.
+	TracerProvider *sdktrace.TracerProvider
+	MeterProvider  *sdkmetric.MeterProvider
+
+	cancel    context.CancelFunc
+	done      chan error
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewServer starts a server for handler, created with http.NewServer and the given options, on a random
+// loopback port. Trace context is extracted with http.DefaultPropagator rather than the global propagator.
+// The options are applied after the server's providers and propagator are set, so they shouldn't replace
+// them. Callers should call Close when finished, to shut it down.
+func NewServer(handler stdhttp.Handler, opts ...http.ServerOption) (*Server, error) {
+	ts := &Server{
+		Spans:   tracetest.NewInMemoryExporter(),
+		Metrics: sdkmetric.NewManualReader(),
+		done:    make(chan error, 1),
+	}
+	ts.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSyncer(ts.Spans))
+	ts.MeterProvider = sdkmetric.NewMeterProvider(sdkmetric.WithReader(ts.Metrics))
+
+	srv, err := http.NewServer("127.0.0.1:0", handler, append([]http.ServerOption{
+		http.WithServerTracerProvider(ts.TracerProvider),
+		http.WithServerMeterProvider(ts.MeterProvider),
+		http.WithServerPropagator(http.DefaultPropagator()),
+		http.WithShutdownTimeout(closeTimeout),
+	}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	ts.URL = "http://" + ln.Addr().String()
+
+	var ctx context.Context
+	ctx, ts.cancel = context.WithCancel(context.Background())
+	go func() {
+		ts.done <- srv.ServeContext(ctx, ln)
+	}()
+	return ts, nil
+}
+
+// NewClient returns a client from http.NewClient that records its telemetry alongside the server's, so that
+// both sides of a request can be inspected. Like the server, it propagates trace context with
+// http.DefaultPropagator. The options are applied after the providers and propagator are set.
+func (ts *Server) NewClient(opts ...http.ClientOption) (*stdhttp.Client, error) {
+	return http.NewClient(append([]http.ClientOption{
+		http.WithClientTracerProvider(ts.TracerProvider),
+		http.WithClientMeterProvider(ts.MeterProvider),
+		http.WithClientPropagator(http.DefaultPropagator()),
+	}, opts...)...)
+}
+
+// Close shuts the server down, waiting for requests in flight to finish, and returns any error it stopped
+// with. It may be called more than once.
+func (ts *Server) Close() error {
+	ts.closeOnce.Do(func() {
+		ts.cancel()
+		ts.closeErr = <-ts.done
+	})
+	return ts.closeErr
+}
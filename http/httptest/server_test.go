@@ -0,0 +1,80 @@
+package httptest
+
+import (
+	"context"
+	stdhttp "net/http"
+	"testing"
+
+	"github.com/andrewhowdencom/stdlib/http"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNewServer(t *testing.T) {
+	ts, err := NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, _ *stdhttp.Request) {
+		w.WriteHeader(stdhttp.StatusCreated)
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	client, err := ts.NewClient()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, span := ts.TracerProvider.Tracer("test").Start(context.Background(), "parent")
+	req, _ := stdhttp.NewRequestWithContext(ctx, stdhttp.MethodPost, ts.URL+"/things", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+	span.End()
+	if resp.StatusCode != stdhttp.StatusCreated {
+		t.Errorf("expected 201, got %d", resp.StatusCode)
+	}
+
+	if err := ts.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+	if err := ts.Close(); err != nil {
+		t.Errorf("expected closing again to succeed, got %v", err)
+	}
+	if _, err := client.Get(ts.URL); err == nil {
+		t.Error("expected the server to be closed")
+	}
+
+	// Both sides of the request are recorded in the same trace.
+	var server bool
+	for _, s := range ts.Spans.GetSpans() {
+		if s.SpanKind == trace.SpanKindServer {
+			server = true
+			if s.Parent.TraceID() != span.SpanContext().TraceID() {
+				t.Errorf("expected the server span to continue the client's trace")
+			}
+		}
+	}
+	if !server {
+		t.Error("expected a server span")
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := ts.Metrics.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	want := map[string]bool{"http.server.request.duration": true, "http.client.request.duration": true}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			delete(want, m.Name)
+		}
+	}
+	if len(want) != 0 {
+		t.Errorf("expected metrics to be recorded, missing %v", want)
+	}
+}
+
+func TestNewServer_InvalidOption(t *testing.T) {
+	if _, err := NewServer(stdhttp.NotFoundHandler(), http.WithMaxHeaderBytes(-1)); err == nil {
+		t.Error("expected an error for an invalid option")
+	}
+}
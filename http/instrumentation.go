@@ -1,7 +1,22 @@
 package http
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net"
 	stdhttp "net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"net/http/httptrace"
@@ -9,6 +24,7 @@ import (
 	"go.opentelemetry.io/contrib/instrumentation/net/http/httptrace/otelhttptrace"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/propagation"
 	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
@@ -22,12 +38,160 @@ type InstrumentedTransport struct {
 	Meter           metric.Meter
 	mWaitTime       metric.Float64Histogram
 	mActiveRequests metric.Int64UpDownCounter
+	mOpenConns      metric.Int64UpDownCounter
+	mDuration       metric.Float64Histogram
+	mRedirects      metric.Int64Counter
+
+	// checkRedirect is the instrumented redirect policy installed on the client, kept so that instrumenting the
+	// same client again doesn't wrap it twice.
+	checkRedirect func(req *stdhttp.Request, via []*stdhttp.Request) error
+
+	// dialer is the dialer configured by WithConnectTimeout and WithDialKeepAlive.
+	dialer *net.Dialer
+
+	// contentTypeAttrs controls whether request and response Content-Type are recorded on the span.
+	contentTypeAttrs bool
+
+	// bodySizeAttrs controls whether request and response body sizes are recorded on the span.
+	bodySizeAttrs bool
+
+	// requestHeaders and responseHeaders are the headers recorded on the span.
+	requestHeaders, responseHeaders headerCapture
+
+	// slowWaitThreshold is the connection wait time above which a span event is recorded.
+	slowWaitThreshold time.Duration
+
+	// measureToBodyClose controls whether the request duration includes reading the response body.
+	measureToBodyClose bool
+
+	// bodySnippetSize is how much of a 5xx response body to record on the span, or 0 to record none.
+	bodySnippetSize int
+
+	// errorStatusThreshold is the lowest response status code that marks the span as an error. Zero means
+	// defaultErrorStatusThreshold.
+	errorStatusThreshold int
+
+	// propagator injects trace context into requests. Nil means the global propagator.
+	propagator propagation.TextMapPropagator
+
+	// requirePropagator controls whether construction fails when the propagator is a no-op.
+	requirePropagator bool
+
+	// skipPropagation holds the methods of requests that trace context is not injected into, set by
+	// WithoutPropagation. If nil, defaultSkipPropagation applies.
+	skipPropagation map[string]bool
+
+	// logicalSpan controls whether a span covering all attempts is started when resilience layers
+	// (e.g. retries) are in use.
+	logicalSpan bool
+
+	// spanName names the client spans the transport starts. Nil means the default, "HTTP {method}".
+	spanName func(*stdhttp.Request) string
+
+	// connectionSpans controls whether a span is started for the establishment of each new connection.
+	connectionSpans bool
+
+	// idlePuts tracks, per host:port, the most recent attempt to return a connection to the idle pool.
+	// It is used to explain why the pool had no connection to offer.
+	idlePuts sync.Map
+}
+
+// Connection pool miss reasons, recorded as http.connection.pool_miss_reason on the connection wait time
+// metric and slow wait span events when a request could not use an idle connection.
+const (
+	// poolMissNoIdle means no idle connection to the host was available; either none had been pooled
+	// yet, or all were in use.
+	poolMissNoIdle = "no_idle_connection"
+
+	// poolMissIdleTimeout means the most recently pooled connection to the host had been idle longer
+	// than IdleConnTimeout, so was reaped. Frequent occurrences suggest IdleConnTimeout is too low.
+	poolMissIdleTimeout = "idle_timeout"
+
+	// poolMissPoolFull means the most recently used connection to the host could not be returned to the
+	// pool (e.g. MaxIdleConnsPerHost was reached), so was closed.
+	poolMissPoolFull = "idle_pool_full"
+
+	// poolMissPerHostLimit means the request waited for a connection in use by another request to be
+	// handed over, typically because MaxConnsPerHost was reached.
+	poolMissPerHostLimit = "per_host_limit"
+)
+
+// defaultSlowWaitThreshold is the connection wait time above which a span event is recorded by default.
+const defaultSlowWaitThreshold = 100 * time.Millisecond
+
+// idlePut records the outcome of returning a connection to the idle pool.
+type idlePut struct {
+	at  time.Time
+	err error
+}
+
+// poolMissReason classifies why a request did not get an idle connection. It returns an empty string
+// when an idle connection was used.
+func (t *InstrumentedTransport) poolMissReason(hostPort string, info httptrace.GotConnInfo) string {
+	if info.Reused {
+		if info.WasIdle {
+			return ""
+		}
+		return poolMissPerHostLimit
+	}
+
+	v, ok := t.idlePuts.Load(hostPort)
+	if !ok {
+		return poolMissNoIdle
+	}
+	put := v.(idlePut)
+	if put.err != nil {
+		return poolMissPoolFull
+	}
+	if tr, err := baseTransport(t.Base); err == nil && tr.IdleConnTimeout > 0 &&
+		time.Since(put.at) >= tr.IdleConnTimeout {
+		return poolMissIdleTimeout
+	}
+	return poolMissNoIdle
+}
+
+// tracer returns the tracer for spans started by the transport itself.
+func (t *InstrumentedTransport) tracer() trace.Tracer {
+	if t.Tracer != nil {
+		return t.Tracer
+	}
+	return otel.GetTracerProvider().Tracer(instrumentationName)
+}
+
+// CloseIdleConnections closes the idle connections of the underlying http.Transport, so that
+// Client.CloseIdleConnections reaches it through the instrumentation and any wrapping transports.
+func (t *InstrumentedTransport) CloseIdleConnections() {
+	if tr, err := baseTransport(t.Base); err == nil {
+		tr.CloseIdleConnections()
+	}
+}
+
+// clientSpanName returns the name of a client span started for req.
+func clientSpanName(spanName func(*stdhttp.Request) string, req *stdhttp.Request) string {
+	if spanName != nil {
+		return spanName(req)
+	}
+	return "HTTP " + req.Method
 }
 
 // RoundTrip implements http.RoundTripper.
 func (t *InstrumentedTransport) RoundTrip(req *stdhttp.Request) (*stdhttp.Response, error) {
+	// 0. Start a span for the logical request, if there are resilience layers beneath us
+	var lr *logicalRequest
+	if t.logicalSpan && hasResilienceLayer(t.Base) {
+		propagator := t.propagator
+		if !t.propagates(req.Method) {
+			// Attempts inject their own spans, so they must skip the same methods.
+			propagator = propagation.NewCompositeTextMapPropagator()
+		}
+		req, lr = startLogicalRequest(t.tracer(), propagator, t.spanName, t.errorStatus(), req)
+		defer lr.end()
+	}
+
 	// 1. Inject propagation headers
-	otel.GetTextMapPropagator().Inject(req.Context(), propagation.HeaderCarrier(req.Header))
+	if t.propagates(req.Method) {
+		textMapPropagator(t.propagator).Inject(req.Context(), propagation.HeaderCarrier(req.Header))
+	}
 
 	// 2. Check for existing span
 	ctx := req.Context()
@@ -36,17 +200,28 @@ func (t *InstrumentedTransport) RoundTrip(req *stdhttp.Request) (*stdhttp.Respon
 	// 3. Enrich if recording
 	if span.IsRecording() {
 		span.SetAttributes(clientRequestAttrs(req)...)
+		span.SetAttributes(requestTimeoutAttrs(ctx)...)
+		span.SetAttributes(t.requestHeaders.attrs(requestHeaderPrefix, req.Header)...)
 	}
 
 	// 4. Trace Events & Wait Time
 	// Wrap the context with a ClientTrace that logs events to the span (via otelhttptrace)
-	// and measures connection wait time.
-	ct := otelhttptrace.NewClientTrace(ctx, otelhttptrace.WithoutSubSpans())
+	// and measures connection wait time. The ClientTrace records every request header it sees, unless only
+	// selected headers are captured.
+	traceOpts := []otelhttptrace.ClientTraceOption{otelhttptrace.WithoutSubSpans()}
+	if t.requestHeaders != nil {
+		traceOpts = append(traceOpts, otelhttptrace.WithoutHeaders())
+	}
+	ct := otelhttptrace.NewClientTrace(ctx, traceOpts...)
 
 	var getConnTime time.Time
+	var connHostPort string
+	var pooled *trackedConn
+	var pooledUse int64
 	originalGetConn := ct.GetConn
 	ct.GetConn = func(hostPort string) {
 		getConnTime = time.Now()
+		connHostPort = hostPort
 		if originalGetConn != nil {
 			originalGetConn(hostPort)
 		}
@@ -54,92 +229,801 @@ func (t *InstrumentedTransport) RoundTrip(req *stdhttp.Request) (*stdhttp.Respon
 
 	originalGotConn := ct.GotConn
 	ct.GotConn = func(info httptrace.GotConnInfo) {
-		if !getConnTime.IsZero() && t.mWaitTime != nil {
-			t.mWaitTime.Record(ctx, time.Since(getConnTime).Seconds())
+		if pooled = pooledConn(info.Conn); pooled != nil {
+			pooledUse = pooled.acquire()
+		}
+		if !getConnTime.IsZero() {
+			wait := time.Since(getConnTime)
+			attrs := []attribute.KeyValue{attribute.Bool("http.connection.reused", info.Reused)}
+			if reason := t.poolMissReason(connHostPort, info); reason != "" {
+				attrs = append(attrs, attribute.String("http.connection.pool_miss_reason", reason))
+			}
+
+			if t.mWaitTime != nil {
+				t.mWaitTime.Record(ctx, wait.Seconds(), metric.WithAttributes(append(hostAttrs(connHostPort), attrs...)...))
+			}
+
+			threshold := t.slowWaitThreshold
+			if threshold == 0 {
+				threshold = defaultSlowWaitThreshold
+			}
+			if wait >= threshold && span.IsRecording() {
+				span.AddEvent("http.connection.slow_wait", trace.WithAttributes(
+					append(attrs, attribute.Float64("http.connection.wait_time", wait.Seconds()))...,
+				))
+			}
 		}
 		if originalGotConn != nil {
 			originalGotConn(info)
 		}
 	}
+
+	originalPutIdleConn := ct.PutIdleConn
+	ct.PutIdleConn = func(err error) {
+		if connHostPort != "" {
+			t.idlePuts.Store(connHostPort, idlePut{at: time.Now(), err: err})
+		}
+		if err == nil && pooled != nil {
+			pooled.release(pooledUse)
+		}
+		if originalPutIdleConn != nil {
+			originalPutIdleConn(err)
+		}
+	}
+
+	var connSpan *connectionSpan
+	if t.connectionSpans {
+		connSpan = &connectionSpan{tracer: t.tracer(), ctx: ctx}
+		connSpan.hook(ct)
+	}
 	req = req.WithContext(httptrace.WithClientTrace(ctx, ct))
 
 	// 5. Active Requests
+	// Upgraded connections remain active until they are closed, rather than until RoundTrip returns.
+	var upgraded bool
 	if t.mActiveRequests != nil {
-		attrs := clientRequestAttrs(req)
-		t.mActiveRequests.Add(ctx, 1, metric.WithAttributes(attrs...))
-		defer t.mActiveRequests.Add(ctx, -1, metric.WithAttributes(attrs...))
+		attrs := metric.WithAttributes(clientRequestAttrs(req)...)
+		t.mActiveRequests.Add(ctx, 1, attrs)
+		defer func() {
+			if !upgraded {
+				t.mActiveRequests.Add(ctx, -1, attrs)
+			}
+		}()
 	}
 
-	// 6. Call Base
+	// 6. Call Base, timing the request
 	// Ensure Base is not nil
 	rt := t.Base
 	if rt == nil {
 		rt = stdhttp.DefaultTransport
 	}
-	resp, err := rt.RoundTrip(req)
+	start := time.Now()
+	var resp *stdhttp.Response
+	var err error
+	if lr != nil && !hasAttemptLayer(rt) {
+		// Without a layer making attempts (such as retries), the request is sent as a single attempt.
+		attemptReq, attemptSpan := lr.startAttempt(req)
+		resp, err = rt.RoundTrip(attemptReq)
+		lr.endAttempt(attemptSpan, resp, err)
+	} else {
+		resp, err = rt.RoundTrip(req)
+	}
+	if connSpan != nil {
+		// The connection span has normally ended once the connection was ready; it is still open if
+		// establishing the connection failed.
+		connSpan.end(err)
+	}
+	resp = t.recordDuration(ctx, req, resp, err, start)
+	if resp != nil && resp.StatusCode == stdhttp.StatusSwitchingProtocols {
+		if rwc, ok := resp.Body.(io.ReadWriteCloser); ok && t.mActiveRequests != nil {
+			upgraded = true
+			attrs := metric.WithAttributes(clientRequestAttrs(req)...)
+			resp.Body = &upgradedBody{ReadWriteCloser: rwc, onClose: func() {
+				t.mActiveRequests.Add(ctx, -1, attrs)
+			}}
+		}
+		if span.IsRecording() {
+			span.AddEvent("http.upgrade", trace.WithAttributes(
+				attribute.String("http.upgrade.protocol", resp.Header.Get("Upgrade")),
+			))
+		}
+	}
 
-	// 5. Enrich response
+	// 7. Enrich response
 	if span.IsRecording() {
 		if err != nil {
 			span.RecordError(err)
+			span.SetAttributes(semconv.ErrorTypeKey.String(errorType(err)))
 		}
 		if resp != nil {
 			span.SetAttributes(clientResponseAttrs(resp)...)
+			if resp.StatusCode >= t.errorStatus() {
+				span.SetStatus(codes.Error, "")
+			}
+			span.SetAttributes(tlsAttrs(resp.TLS)...)
+			span.SetAttributes(trailerAttrs(slices.Collect(maps.Keys(resp.Trailer)))...)
+			if t.contentTypeAttrs {
+				span.SetAttributes(responseContentTypeAttrs(resp.Header.Get("Content-Type"))...)
+			}
+			span.SetAttributes(t.responseHeaders.attrs(responseHeaderPrefix, resp.Header)...)
+			if t.bodySnippetSize > 0 && resp.StatusCode >= 500 && !isRawResponse(req) {
+				span.SetAttributes(attribute.String("http.response.body.snippet", bodySnippet(resp, t.bodySnippetSize)))
+			}
+		}
+		if t.bodySizeAttrs {
+			t.recordBodySizes(span, req, resp)
 		}
 	}
 
 	return resp, err
 }
 
+// recordBodySizes records the request and response body sizes on the span. A response body of unknown length
+// is counted as it is read, and recorded once it has been read to the end or closed.
+func (t *InstrumentedTransport) recordBodySizes(span trace.Span, req *stdhttp.Request, resp *stdhttp.Response) {
+	if req.ContentLength > 0 {
+		span.SetAttributes(semconv.HTTPRequestBodySizeKey.Int64(req.ContentLength))
+	}
+	if resp == nil {
+		return
+	}
+	if resp.ContentLength >= 0 {
+		span.SetAttributes(semconv.HTTPResponseBodySizeKey.Int64(resp.ContentLength))
+		return
+	}
+	// Upgraded connections keep a writable body that must not be hidden behind a wrapper, and raw responses
+	// are left untouched.
+	if resp.Body == nil || resp.StatusCode == stdhttp.StatusSwitchingProtocols || isRawResponse(req) {
+		return
+	}
+	resp.Body = &countingBody{ReadCloser: resp.Body, done: func(n int64) {
+		span.SetAttributes(semconv.HTTPResponseBodySizeKey.Int64(n))
+	}}
+}
+
+// countingBody counts the bytes read from a body, calling done once with the count when the body is read to
+// the end or closed, whichever happens first. It is not safe for concurrent use, as bodies are not.
+type countingBody struct {
+	io.ReadCloser
+	n    int64
+	done func(n int64)
+	once sync.Once
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	if err == io.EOF && b.done != nil {
+		b.once.Do(func() { b.done(b.n) })
+	}
+	return n, err
+}
+
+func (b *countingBody) Close() error {
+	if b.done != nil {
+		b.once.Do(func() { b.done(b.n) })
+	}
+	return b.ReadCloser.Close()
+}
+
 // instrumentedHandler wraps http.Handler to extract trace context and start spans.
 type instrumentedHandler struct {
 	base            stdhttp.Handler
 	tracer          trace.Tracer
 	meter           metric.Meter
 	mActiveRequests metric.Int64UpDownCounter
+
+	// ignore holds the functions that exclude requests from instrumentation.
+	ignore []func(*stdhttp.Request) bool
+
+	// activeRequests, when set, counts the requests being handled.
+	activeRequests *atomic.Int64
+
+	// shuttingDown, when set, is consulted to reject new requests once shutdown has begun.
+	shuttingDown *atomic.Bool
+
+	// background tracks tasks registered with WithBackgroundTask, which shutdown waits for.
+	background *backgroundTasks
+
+	// clientAddress determines how the client address is recorded on the span.
+	clientAddress clientAddressSource
+
+	// mQueueTime records how long requests waited before being handled, and is nil unless enabled.
+	mQueueTime metric.Float64Histogram
+
+	// logger, when set, is the base of the request-scoped logger placed in the request context.
+	logger *slog.Logger
+
+	// mDuration records how long each request took to handle.
+	mDuration metric.Float64Histogram
+
+	// mPanics counts handler panics, other than http.ErrAbortHandler.
+	mPanics metric.Int64Counter
+
+	// http2MaxStreams is the HTTP/2 concurrent stream limit that requests are compared against, or zero.
+	http2MaxStreams int
+	mStreamsAtLimit metric.Int64Counter
+
+	// rateLimit rejects requests over the limit of their client, if configured.
+	rateLimit    *keyedRateLimiter
+	mRateLimited metric.Int64Counter
+
+	// propagator extracts trace context from requests. Nil means the global propagator.
+	propagator propagation.TextMapPropagator
+
+	// requestContext derives the context the span is started from, and spanName names the span. Either may be nil.
+	requestContext func(ctx context.Context, r *stdhttp.Request) context.Context
+	spanName       func(r *stdhttp.Request) string
+
+	// contentTypeAttrs controls whether request and response Content-Type are recorded on the span.
+	contentTypeAttrs bool
+
+	// bodySizeAttrs controls whether request and response body sizes are recorded on the span.
+	bodySizeAttrs bool
+
+	// requestHeaders and responseHeaders are the headers recorded on the span.
+	requestHeaders, responseHeaders headerCapture
+
+	// baggageKeys are the baggage members copied onto the span.
+	baggageKeys []string
+
+	// requestIDHeader is the header request IDs are read from and echoed in, or "" if they are not enabled.
+	requestIDHeader string
+
+	// forceSampleHeader is the header that marks requests to be sampled, or "" if there is none.
+	forceSampleHeader string
+
+	// compression configures response compression, if enabled.
+	compression *compression
+
+	bodyReadTimeout   time.Duration
+	mBodyReadTimeouts metric.Int64Counter
+
+	// readTimeout is the server's ReadTimeout, which the body read deadline must not extend.
+	readTimeout time.Duration
+
+	// requestDeadline, if set, is the deadline placed on the handler's context.
+	requestDeadline time.Duration
+
+	maxRequestBodySize int64
+	mBodyTooLarge      metric.Int64Counter
+
+	// streamDeadline, if set, manages the write deadline of responses against writeTimeout.
+	streamDeadline *streamDeadline
+	writeTimeout   time.Duration
+
+	// Header size histograms, which are nil unless enabled.
+	mRequestHeaderSize  metric.Int64Histogram
+	mResponseHeaderSize metric.Int64Histogram
 }
 
+// recoverPanic recovers a handler panic, recording it on the span, and responds with a 500. If the handler had
+// already written the response headers, the response is aborted instead. It must be deferred directly. A panic
+// with http.ErrAbortHandler is the sanctioned way to abort a response, so it is recorded as an abort rather than
+// as an error, is not counted as a panic, and is passed on for net/http to abort the response.
+func (h *instrumentedHandler) recoverPanic(ctx context.Context, span trace.Span, method string, rr *responseRecorder) {
+	v := recover()
+	if v == nil {
+		return
+	}
+
+	if v == stdhttp.ErrAbortHandler { //nolint:errorlint // net/http compares the panic value directly.
+		span.SetAttributes(attribute.Bool("http.server.aborted", true))
+		panic(v)
+	}
+
+	span.RecordError(fmt.Errorf("panic: %v", v), trace.WithStackTrace(true))
+	span.SetStatus(codes.Error, "handler panicked")
+	if h.mPanics != nil {
+		h.mPanics.Add(ctx, 1, metric.WithAttributes(semconv.HTTPRequestMethodKey.String(method)))
+	}
+
+	// Once the headers are sent the status can't be changed, so the response is aborted instead, rather than
+	// letting a truncated response appear complete. A hijacked connection is the handler's to respond on.
+	if rr.wroteHeader || rr.hijacked {
+		span.SetAttributes(attribute.Bool("http.server.aborted", true))
+		panic(stdhttp.ErrAbortHandler)
+	}
+	stdhttp.Error(rr, stdhttp.StatusText(stdhttp.StatusInternalServerError), stdhttp.StatusInternalServerError)
+	span.SetAttributes(semconv.HTTPResponseStatusCodeKey.Int(rr.statusCode))
+}
+
+// ignored reports whether the request is excluded from instrumentation.
+func (h *instrumentedHandler) ignored(r *stdhttp.Request) bool {
+	for _, fn := range h.ignore {
+		if fn(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// shutdownRetryAfter is the value of the Retry-After header sent when rejecting requests during shutdown.
+const shutdownRetryAfter = "1"
+
 // ServeHTTP implements http.Handler.
 func (h *instrumentedHandler) ServeHTTP(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	// 0. Record that the connection's request reached a handler
+	ci := connInfoFromContext(r.Context())
+	if ci != nil {
+		ci.awaitingHandler.Store(false)
+	}
+
+	// 0. Serve ignored requests without instrumentation, though they still count towards draining
+	if h.ignored(r) {
+		if h.activeRequests != nil {
+			h.activeRequests.Add(1)
+			defer h.activeRequests.Add(-1)
+		}
+		h.base.ServeHTTP(w, r)
+		return
+	}
+
+	// 0. Record how long the request waited to be handled
+	if h.mQueueTime != nil && ci != nil {
+		if start := ci.queueStart(); !start.IsZero() {
+			h.mQueueTime.Record(r.Context(), time.Since(start).Seconds(),
+				metric.WithAttributes(semconv.HTTPRequestMethodKey.String(r.Method)))
+		}
+	}
+
+	// 0. Track how close the HTTP/2 connection is to its stream limit
+	if h.http2MaxStreams > 0 && r.ProtoMajor == 2 && ci != nil {
+		if ci.startStream() >= int64(h.http2MaxStreams) && h.mStreamsAtLimit != nil {
+			h.mStreamsAtLimit.Add(r.Context(), 1)
+		}
+		defer ci.endStream()
+	}
+
 	// 1. Extract propagation headers
-	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx := textMapPropagator(h.propagator).Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx = h.extractBaggage(ctx, r)
+	if h.requestIDHeader != "" {
+		id := requestID(r, h.requestIDHeader)
+		ctx = WithRequestID(ctx, id)
+		w.Header().Set(h.requestIDHeader, id)
+	}
 
-	// 2. Start Span (Server Kind)
+	// 2. Derive the context the span is started from, so request-derived values can influence it
+	if h.requestContext != nil {
+		ctx = h.requestContext(ctx, r)
+	}
+	forced := forceSampled(r, h.forceSampleHeader)
+	if forced {
+		ctx = withForceSample(ctx)
+	}
+
+	// The route may already be known (set with WithRoute); otherwise a router reports it as it is matched.
+	routes := &routeHolder{}
+	if route := RouteFromContext(ctx); route != "" {
+		routes.set(route)
+	}
+
+	// 3. Start Span (Server Kind)
 	// NOTE: The handler can overwrite the span name later in the request.
-	spanName := "HTTP " + r.Method
-	ctx, span := h.tracer.Start(ctx, spanName, trace.WithSpanKind(trace.SpanKindServer))
+	methodSpanName := "HTTP " + r.Method
+	spanName := methodSpanName
+	if h.spanName != nil {
+		spanName = h.spanName(r.WithContext(ctx))
+	} else if route := routes.get(); route != "" {
+		spanName = methodSpanName + " " + route
+	}
+	start := time.Now()
+	ctx, span := h.tracer.Start(ctx, spanName, trace.WithSpanKind(trace.SpanKindServer), trace.WithTimestamp(start))
 	defer span.End()
 
-	// 3. Add Request Attributes
-	span.SetAttributes(serverRequestAttrs(r)...)
+	// 4. Add Request Attributes
+	scheme := h.clientAddress.scheme(r)
+	span.SetAttributes(serverRequestAttrs(r, scheme)...)
+	span.SetAttributes(serverAddressAttrs(r)...)
+	span.SetAttributes(tlsAttrs(r.TLS)...)
+	span.SetAttributes(h.clientAddress.attrs(r)...)
+	if h.contentTypeAttrs {
+		span.SetAttributes(requestContentTypeAttrs(r.Header.Get("Content-Type"))...)
+	}
+	span.SetAttributes(h.requestHeaders.attrs(requestHeaderPrefix, r.Header)...)
+	span.SetAttributes(baggageAttrs(ctx, h.baggageKeys)...)
+	span.SetAttributes(requestIDAttrs(ctx)...)
+	if forced {
+		span.SetAttributes(attribute.Bool("http.server.force_sampled", true))
+	}
 
-	// 4. Active Requests
+	// 5. Active Requests
+	if h.activeRequests != nil {
+		h.activeRequests.Add(1)
+		defer h.activeRequests.Add(-1)
+	}
 	if h.mActiveRequests != nil {
-		attrs := serverRequestAttrs(r)
+		attrs := serverActiveRequestAttrs(r, scheme, routes.get())
 		h.mActiveRequests.Add(ctx, 1, metric.WithAttributes(attrs...))
 		defer h.mActiveRequests.Add(ctx, -1, metric.WithAttributes(attrs...))
 	}
 
-	// 5. Wrap ResponseWriter to capture status code
+	// 6. Wrap ResponseWriter to capture status code
 	rr := &responseRecorder{ResponseWriter: w, statusCode: stdhttp.StatusOK}
+	// Deferred first, so that it runs after a panic has been recovered and sees the resulting status.
+	if h.mDuration != nil {
+		defer func() {
+			h.mDuration.Record(ctx, time.Since(start).Seconds(),
+				metric.WithAttributes(serverMetricAttrs(r, rr.status(), routes.get())...))
+		}()
+	}
+	defer h.recoverPanic(ctx, span, r.Method, rr)
 
-	// 6. Serve
-	h.base.ServeHTTP(rr, r.WithContext(ctx))
+	// 7. Serve (or reject, if the server is draining, the client is over its rate limit, or the declared body is
+	// too large)
+	var requestBody *countingBody
+	if h.shuttingDown != nil && h.shuttingDown.Load() {
+		rr.Header().Set("Connection", "close")
+		rr.Header().Set("Retry-After", shutdownRetryAfter)
+		stdhttp.Error(rr, stdhttp.StatusText(stdhttp.StatusServiceUnavailable), stdhttp.StatusServiceUnavailable)
+	} else if wait, limited := h.rateLimit.reject(r); limited {
+		span.SetAttributes(attribute.Bool("http.server.rate_limited", true))
+		h.mRateLimited.Add(ctx, 1, metric.WithAttributes(semconv.HTTPRequestMethodKey.String(r.Method)))
+		writeRateLimited(rr, wait)
+	} else if h.maxRequestBodySize > 0 && r.ContentLength > h.maxRequestBodySize {
+		recordBodyTooLarge(ctx, h.mBodyTooLarge, h.maxRequestBodySize)
+		writeBodyTooLarge(rr, h.maxRequestBodySize)
+	} else {
+		reqCtx := context.WithValue(ctx, routeHolderKey{}, routes)
+		if h.requestDeadline > 0 {
+			var cancel context.CancelFunc
+			reqCtx, cancel = context.WithTimeout(reqCtx, h.requestDeadline)
+			defer cancel()
+		}
+		if h.background != nil {
+			reqCtx = context.WithValue(reqCtx, backgroundTasksKey{}, h.background)
+		}
+		if h.logger != nil {
+			rl := &requestLogger{
+				base: h.logger, span: span, method: r.Method, routes: routes, requestID: RequestIDFromContext(ctx),
+			}
+			reqCtx = context.WithValue(reqCtx, loggerKey{}, rl)
+		}
+		req := r.WithContext(reqCtx)
+		var rw stdhttp.ResponseWriter = rr
+		var limited *bodyLimitWriter
+		if h.maxRequestBodySize > 0 && req.Body != nil && req.Body != stdhttp.NoBody {
+			// The original ResponseWriter lets net/http close the connection once the limit is hit.
+			body := &limitedBody{
+				ReadCloser: stdhttp.MaxBytesReader(w, req.Body, h.maxRequestBodySize),
+				ctx:        ctx,
+				limit:      h.maxRequestBodySize,
+				counter:    h.mBodyTooLarge,
+			}
+			req.Body = body
+			limited = &bodyLimitWriter{ResponseWriter: rr, body: body}
+			rw = limited
+		}
+		finishCompression := func() {}
+		if h.compression != nil {
+			rw, finishCompression = h.compression.wrap(rw, req)
+		}
+		if h.streamDeadline != nil && h.writeTimeout > 0 {
+			rw = newDeadlineWriter(rw, span, h.streamDeadline, h.writeTimeout)
+		}
+		if h.bodySizeAttrs && req.Body != nil && req.Body != stdhttp.NoBody {
+			requestBody = &countingBody{ReadCloser: req.Body}
+			req.Body = requestBody
+		}
+		if h.bodyReadTimeout > 0 && req.Body != nil && req.Body != stdhttp.NoBody {
+			rc := stdhttp.NewResponseController(rr)
+			// net/http resets the read deadline when it reads the next request on the connection, so the
+			// deadline is deliberately left in place after the handler returns; it also bounds the
+			// discard of any unread body.
+			// The deadline replaces the one net/http set from ReadTimeout when it started reading the request,
+			// approximated here by when the handler started, so the earlier of the two is kept.
+			deadline := time.Now().Add(h.bodyReadTimeout)
+			if h.readTimeout > 0 && start.Add(h.readTimeout).Before(deadline) {
+				deadline = start.Add(h.readTimeout)
+			}
+			if err := rc.SetReadDeadline(deadline); err == nil {
+				req.Body = &timeoutBody{ReadCloser: req.Body, ctx: ctx, counter: h.mBodyReadTimeouts}
+			}
+		}
+		h.base.ServeHTTP(exposeOptional(rw, w), req)
+		if h.requestDeadline > 0 && errors.Is(reqCtx.Err(), context.DeadlineExceeded) {
+			span.SetAttributes(attribute.Bool("http.server.deadline_exceeded", true))
+		}
+		finishCompression()
+		if limited != nil {
+			limited.finish()
+		}
 
-	// 7. Add Response Attributes
-	span.SetAttributes(semconv.HTTPResponseStatusCodeKey.Int(rr.statusCode))
+		// Fall back to the pattern recorded by a plain http.ServeMux, if it was handed the request directly.
+		if routes.get() == "" && req.Pattern != "" {
+			routes.set(routeFromPattern(req.Pattern))
+		}
+	}
+
+	// 8. Name the span after the route, if one was matched (and neither a formatter nor the handler named it)
+	if route := routes.get(); route != "" {
+		if named, ok := span.(interface{ Name() string }); h.spanName == nil && (!ok || named.Name() == spanName) {
+			span.SetName(methodSpanName + " " + route)
+		}
+		span.SetAttributes(semconv.HTTPRouteKey.String(route))
+	}
+
+	// 9. Add Response Attributes. A hijacked connection has no response from net/http; the handler wrote its own,
+	// if any, so there is no status code to record.
+	if rr.hijacked {
+		span.SetAttributes(attribute.Bool("http.connection.hijacked", true))
+	} else {
+		span.SetAttributes(semconv.HTTPResponseStatusCodeKey.Int(rr.statusCode))
+		// Only server errors are the server's fault; 4xx responses are the client's, per the HTTP semantic
+		// conventions.
+		if rr.statusCode >= stdhttp.StatusInternalServerError {
+			span.SetStatus(codes.Error, "")
+		}
+	}
+	trailers := rr.trailers
+	if !rr.wroteHeader {
+		trailers = declaredTrailers(rr.Header())
+	}
+	span.SetAttributes(trailerAttrs(append(trailers, prefixedTrailers(rr.Header())...))...)
+	if h.contentTypeAttrs {
+		span.SetAttributes(responseContentTypeAttrs(rr.contentType)...)
+	}
+	span.SetAttributes(h.responseHeaders.attrs(responseHeaderPrefix, rr.Header())...)
+	if h.bodySizeAttrs {
+		if requestBody != nil {
+			span.SetAttributes(semconv.HTTPRequestBodySizeKey.Int64(requestBody.n))
+		}
+		if !rr.hijacked {
+			span.SetAttributes(semconv.HTTPResponseBodySizeKey.Int64(rr.bodySize))
+		}
+	}
+
+	// 10. Header sizes
+	if h.mRequestHeaderSize != nil {
+		attrs := serverMetricAttrs(r, rr.status(), routes.get())
+		h.mRequestHeaderSize.Record(ctx, headerSize(r.Header), metric.WithAttributes(attrs...))
+		if rr.hijacked {
+			return
+		}
+		respSize := rr.headerSize
+		if !rr.wroteHeader {
+			// The handler never wrote, so net/http sends the headers as they are now.
+			respSize = headerSize(rr.Header())
+		}
+		h.mResponseHeaderSize.Record(ctx, respSize, metric.WithAttributes(attrs...))
+	}
 }
 
+// responseRecorder wraps http.ResponseWriter to capture what was committed to the client.
 type responseRecorder struct {
 	stdhttp.ResponseWriter
-	statusCode int
+	statusCode  int
+	wroteHeader bool
+
+	// contentType is the Content-Type of the response at the time the headers were committed.
+	contentType string
+
+	// headerSize is the serialized size of the response headers at the time they were committed.
+	headerSize int64
+
+	// trailers are the trailer keys declared in the Trailer header at the time the headers were committed.
+	trailers []string
+
+	// bodySize is the number of response body bytes written.
+	bodySize int64
+
+	// hijacked is set once the handler has taken over the connection.
+	hijacked bool
+}
+
+// status returns the status code of the response, or 0 if the connection was hijacked.
+func (r *responseRecorder) status() int {
+	if r.hijacked {
+		return 0
+	}
+	return r.statusCode
 }
 
 func (r *responseRecorder) WriteHeader(statusCode int) {
-	r.statusCode = statusCode
+	// Informational (1xx) responses may be sent several times before the final header.
+	if !r.wroteHeader && (statusCode >= 200 || statusCode == stdhttp.StatusSwitchingProtocols) {
+		r.statusCode = statusCode
+		r.commit(nil)
+	}
 	r.ResponseWriter.WriteHeader(statusCode)
 }
 
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if !r.wroteHeader {
+		r.commit(b)
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bodySize += int64(n)
+	return n, err
+}
+
+// Unwrap returns the underlying ResponseWriter, allowing http.ResponseController to reach it.
+func (r *responseRecorder) Unwrap() stdhttp.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// commit snapshots the response headers as they are sent to the client. body is the first chunk of the
+// response body (if any), used to mirror net/http content sniffing when no Content-Type was set.
+func (r *responseRecorder) commit(body []byte) {
+	r.wroteHeader = true
+	h := r.Header()
+	r.headerSize = headerSize(h)
+	r.trailers = declaredTrailers(h)
+	r.contentType = h.Get("Content-Type")
+	if _, ok := h["Content-Type"]; !ok && len(body) > 0 {
+		r.contentType = stdhttp.DetectContentType(body)
+	}
+}
+
+// timeoutBody wraps a request body to count reads that fail because the body read deadline passed.
+type timeoutBody struct {
+	io.ReadCloser
+	ctx     context.Context
+	counter metric.Int64Counter
+	counted bool
+}
+
+func (b *timeoutBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var ne net.Error
+	if err != nil && !b.counted && errors.As(err, &ne) && ne.Timeout() {
+		b.counted = true
+		if b.counter != nil {
+			b.counter.Add(b.ctx, 1)
+		}
+	}
+	return n, err
+}
+
+// upgradedBody is the body of a 101 Switching Protocols response, which is the upgraded connection. It stays
+// writable, and calls onClose once when closed.
+type upgradedBody struct {
+	io.ReadWriteCloser
+	onClose func()
+	once    sync.Once
+}
+
+func (b *upgradedBody) Close() error {
+	err := b.ReadWriteCloser.Close()
+	b.once.Do(b.onClose)
+	return err
+}
+
+// Values of the http.client.request.duration.mode attribute.
+const (
+	durationModeHeaders   = "headers"
+	durationModeBodyClose = "body_close"
+)
+
+// recordDuration records the request duration, either now or, if configured, once the response body has
+// been consumed. It returns the response, with its body wrapped if the recording was deferred. Failed requests
+// are recorded with error.type.
+func (t *InstrumentedTransport) recordDuration(
+	ctx context.Context, req *stdhttp.Request, resp *stdhttp.Response, err error, start time.Time,
+) *stdhttp.Response {
+	if t.mDuration == nil {
+		return resp
+	}
+	attrs := append(clientRequestAttrs(req), requestTimeoutAttrs(ctx)...)
+	if resp != nil {
+		attrs = append(attrs, clientResponseAttrs(resp)...)
+	}
+	if err != nil {
+		attrs = append(attrs, semconv.ErrorTypeKey.String(errorType(err)))
+	}
+
+	// Upgraded connections keep a writable body that must not be hidden behind a wrapper, and raw responses
+	// are left untouched.
+	if !t.measureToBodyClose || resp == nil || resp.Body == nil ||
+		resp.StatusCode == stdhttp.StatusSwitchingProtocols || isRawResponse(req) {
+		attrs = append(attrs, attribute.String("http.client.request.duration.mode", durationModeHeaders))
+		t.mDuration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attrs...))
+		return resp
+	}
+
+	attrs = append(attrs, attribute.String("http.client.request.duration.mode", durationModeBodyClose))
+	resp.Body = &measuredBody{ReadCloser: resp.Body, record: func(err error) {
+		if err != nil {
+			attrs = append(attrs, semconv.ErrorTypeKey.String(errorType(err)))
+		}
+		t.mDuration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attrs...))
+	}}
+	return resp
+}
+
+// measuredBody calls record once, when the body is read to the end, fails to be read, or is closed,
+// whichever happens first. A read failure (such as the connection being reset mid-body) is passed to record.
+type measuredBody struct {
+	io.ReadCloser
+	record func(err error)
+	once   sync.Once
+}
+
+func (b *measuredBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil {
+		b.once.Do(func() {
+			if err == io.EOF {
+				b.record(nil)
+				return
+			}
+			b.record(err)
+		})
+	}
+	return n, err
+}
+
+func (b *measuredBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.record(nil) })
+	return err
+}
+
+// defaultSkipPropagation holds the methods of requests that trace context is not injected into by default.
+// CONNECT requests are addressed to a proxy, which trace headers would leak context to.
+var defaultSkipPropagation = map[string]bool{stdhttp.MethodConnect: true}
+
+// propagates reports whether trace context is injected into requests with the given method.
+func (t *InstrumentedTransport) propagates(method string) bool {
+	if t.skipPropagation == nil {
+		return !defaultSkipPropagation[method]
+	}
+	return !t.skipPropagation[method]
+}
+
+// defaultErrorStatusThreshold is the lowest response status code that marks a client span as an error, unless
+// configured otherwise with WithErrorStatusThreshold.
+const defaultErrorStatusThreshold = stdhttp.StatusBadRequest
+
+// errorStatus returns the lowest response status code that marks the span as an error.
+func (t *InstrumentedTransport) errorStatus() int {
+	if t.errorStatusThreshold == 0 {
+		return defaultErrorStatusThreshold
+	}
+	return t.errorStatusThreshold
+}
+
+// maxBodySnippetSize caps how much of a response body may be recorded on a span.
+const maxBodySnippetSize = 1024
+
+// redacted replaces values recorded in telemetry that contain, or look like they contain, credentials.
+const redacted = "[REDACTED]"
+
+// secretPattern matches text that suggests a body contains credentials.
+var secretPattern = regexp.MustCompile(
+	`(?i)(password|passwd|secret|token|api[_-]?key|authorization|bearer\s|private[_-]?key|-----BEGIN)`,
+)
+
+// bodySnippet reads up to n bytes from the start of the response body and puts them back, so that the caller
+// still receives the full body. Snippets that look like they contain credentials are redacted.
+func bodySnippet(resp *stdhttp.Response, n int) string {
+	if resp.Body == nil || resp.Body == stdhttp.NoBody {
+		return ""
+	}
+	snippet, err := io.ReadAll(io.LimitReader(resp.Body, int64(n)))
+	resp.Body = &struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(snippet), errReader{err}, resp.Body), resp.Body}
+
+	if secretPattern.Match(snippet) {
+		return redacted
+	}
+	return strings.ToValidUTF8(string(snippet), "")
+}
+
+// errReader returns err once, if it is set, so that a read error hit while taking a snippet is still seen by
+// the caller.
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	return 0, io.EOF
+}
+
 // Helpers for extracting attributes
 
 func clientRequestAttrs(req *stdhttp.Request) []attribute.KeyValue {
@@ -150,21 +1034,186 @@ func clientRequestAttrs(req *stdhttp.Request) []attribute.KeyValue {
 		attrs = append(attrs, semconv.URLPathKey.String(req.URL.Path))
 		attrs = append(attrs, semconv.URLSchemeKey.String(req.URL.Scheme))
 		attrs = append(attrs, semconv.ServerAddressKey.String(req.URL.Hostname()))
+		if port := urlPort(req.URL.Port(), req.URL.Scheme); port != 0 {
+			attrs = append(attrs, semconv.ServerPort(port))
+		}
 	}
 	return attrs
 }
 
+// urlPort returns the port a URL refers to: its explicit port, or the default for its scheme, or 0 if neither
+// is known.
+func urlPort(port, scheme string) int {
+	if p, err := strconv.Atoi(port); err == nil {
+		return p
+	}
+	switch scheme {
+	case "http":
+		return 80
+	case "https":
+		return 443
+	}
+	return 0
+}
+
+// clientResponseAttrs returns the attributes describing the response, including the protocol version it was
+// received with, as the request's own version is not the one negotiated.
 func clientResponseAttrs(resp *stdhttp.Response) []attribute.KeyValue {
 	return []attribute.KeyValue{
 		semconv.HTTPResponseStatusCodeKey.Int(resp.StatusCode),
+		semconv.NetworkProtocolVersion(protocolVersion(resp.ProtoMajor, resp.ProtoMinor)),
+	}
+}
+
+// protocolVersion formats an HTTP version as network.protocol.version expects: "1.1" for HTTP/1.1, but "2"
+// rather than "2.0" for HTTP/2 (and likewise HTTP/3).
+func protocolVersion(major, minor int) string {
+	if major >= 2 && minor == 0 {
+		return strconv.Itoa(major)
+	}
+	return strconv.Itoa(major) + "." + strconv.Itoa(minor)
+}
+
+// tlsAttrs describes the negotiated TLS version and application protocol (ALPN), for confirming that protocol
+// negotiation behaves as expected. They are recorded on spans only, to keep metric cardinality down. It
+// returns nil for plaintext connections.
+func tlsAttrs(cs *tls.ConnectionState) []attribute.KeyValue {
+	if cs == nil {
+		return nil
+	}
+	name, version := "tls", strings.TrimPrefix(tls.VersionName(cs.Version), "TLS ")
+	if cs.Version == tls.VersionSSL30 { //nolint:staticcheck // Old servers may still negotiate SSLv3.
+		name, version = "ssl", "3"
+	}
+	attrs := []attribute.KeyValue{
+		semconv.TLSProtocolNameKey.String(name),
+		semconv.TLSProtocolVersion(version),
+	}
+	if cs.NegotiatedProtocol != "" {
+		attrs = append(attrs, semconv.TLSNextProtocol(cs.NegotiatedProtocol))
 	}
+	return attrs
+}
+
+// declaredTrailers returns the trailer keys announced in the Trailer header.
+func declaredTrailers(h stdhttp.Header) []string {
+	var keys []string
+	for _, v := range h.Values("Trailer") {
+		for _, k := range strings.Split(v, ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				keys = append(keys, stdhttp.CanonicalHeaderKey(k))
+			}
+		}
+	}
+	return keys
+}
+
+// prefixedTrailers returns the trailer keys set by a handler with http.TrailerPrefix, which need not be
+// declared in advance.
+func prefixedTrailers(h stdhttp.Header) []string {
+	var keys []string
+	for k := range h {
+		if name, ok := strings.CutPrefix(k, stdhttp.TrailerPrefix); ok {
+			keys = append(keys, stdhttp.CanonicalHeaderKey(name))
+		}
+	}
+	return keys
+}
+
+// trailerAttrs records the trailer keys of a response (not their values, which may be sensitive) so that
+// protocols that rely on trailers, such as gRPC-Web, can be debugged. It returns nil if there are none.
+func trailerAttrs(keys []string) []attribute.KeyValue {
+	if len(keys) == 0 {
+		return nil
+	}
+	slices.Sort(keys)
+	return []attribute.KeyValue{attribute.StringSlice("http.response.trailers", slices.Compact(keys))}
+}
+
+func requestContentTypeAttrs(contentType string) []attribute.KeyValue {
+	if contentType == "" {
+		return nil
+	}
+	return []attribute.KeyValue{semconv.HTTPRequestHeader("content-type", contentType)}
+}
+
+func responseContentTypeAttrs(contentType string) []attribute.KeyValue {
+	if contentType == "" {
+		return nil
+	}
+	return []attribute.KeyValue{semconv.HTTPResponseHeader("content-type", contentType)}
+}
+
+// headerSize returns the size of the headers as serialized in HTTP/1.1 ("Key: Value\r\n" per value).
+func headerSize(h stdhttp.Header) int64 {
+	var n int
+	for k, vs := range h {
+		for _, v := range vs {
+			n += len(k) + len(": ") + len(v) + len("\r\n")
+		}
+	}
+	return int64(n)
+}
+
+// serverMetricAttrs returns a bounded set of attributes suitable for server metrics, including the status code
+// unless it is 0 (as for a hijacked connection), and the route if one was matched.
+func serverMetricAttrs(req *stdhttp.Request, statusCode int, route string) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		semconv.HTTPRequestMethodKey.String(req.Method),
+		semconv.NetworkProtocolVersion(protocolVersion(req.ProtoMajor, req.ProtoMinor)),
+	}
+	if statusCode != 0 {
+		attrs = append(attrs, semconv.HTTPResponseStatusCodeKey.Int(statusCode))
+	}
+	if route != "" {
+		attrs = append(attrs, semconv.HTTPRouteKey.String(route))
+	}
+	return attrs
+}
+
+// serverActiveRequestAttrs returns the attributes of the active requests metric. The route replaces the raw
+// path when it is known, to keep the metric's cardinality down.
+func serverActiveRequestAttrs(req *stdhttp.Request, scheme, route string) []attribute.KeyValue {
+	attrs := serverRequestAttrs(req, scheme)
+	if route == "" {
+		return attrs
+	}
+	for i, attr := range attrs {
+		if attr.Key == semconv.URLPathKey {
+			attrs[i] = semconv.HTTPRouteKey.String(route)
+		}
+	}
+	return attrs
+}
+
+// serverAddressAttrs returns the server.address and server.port attributes from the request's Host, taking the
+// port from the address the request was received on if the Host has none. They are only recorded on the span,
+// as the Host is chosen by the client, so would let it inflate metric cardinality.
+func serverAddressAttrs(req *stdhttp.Request) []attribute.KeyValue {
+	host, port := splitHostPort(req.Host)
+	if host == "" {
+		return nil
+	}
+	if port == "" {
+		if addr, ok := req.Context().Value(stdhttp.LocalAddrContextKey).(net.Addr); ok {
+			_, port = splitHostPort(addr.String())
+		}
+	}
+	attrs := []attribute.KeyValue{semconv.ServerAddress(host)}
+	if p, err := strconv.Atoi(port); err == nil {
+		attrs = append(attrs, semconv.ServerPort(p))
+	}
+	return attrs
 }
 
-func serverRequestAttrs(req *stdhttp.Request) []attribute.KeyValue {
+// serverRequestAttrs returns the attributes describing the request. The scheme is passed in, as the request's URL
+// rarely has one on the server side; see clientAddressSource.scheme.
+func serverRequestAttrs(req *stdhttp.Request, scheme string) []attribute.KeyValue {
 	attrs := []attribute.KeyValue{
 		semconv.HTTPRequestMethodKey.String(req.Method),
 		semconv.URLPathKey.String(req.URL.Path),
-		semconv.URLSchemeKey.String(req.URL.Scheme),
+		semconv.URLSchemeKey.String(scheme),
+		semconv.NetworkProtocolVersion(protocolVersion(req.ProtoMajor, req.ProtoMinor)),
 		semconv.UserAgentOriginalKey.String(req.UserAgent()),
 	}
 	return attrs
@@ -1,17 +1,28 @@
 package http
 
 import (
+	"bytes"
 	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/exemplar"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
 	"go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
 )
 
 func TestClientInstrumentation(t *testing.T) {
@@ -126,6 +137,914 @@ func TestServerInstrumentation(t *testing.T) {
 	}
 }
 
+func TestClientInstrumentation_Redirects(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusFound)
+		case "/b":
+			http.Redirect(w, r, "/c", http.StatusMovedPermanently)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(WithClientTracerProvider(tp), WithClientMeterProvider(mp))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "parent-span")
+	req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL+"/a", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	span.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	var hops []int64
+	for _, e := range spans[0].Events {
+		if e.Name != "http.redirect" {
+			continue
+		}
+		for _, a := range e.Attributes {
+			if a.Key == "http.redirect.count" {
+				hops = append(hops, a.Value.AsInt64())
+			}
+		}
+	}
+	if len(hops) != 2 || hops[0] != 1 || hops[1] != 2 {
+		t.Errorf("Expected redirect events with hop counts [1 2], got %v", hops)
+	}
+
+	if got := sumCounter(t, reader, "http.client.redirects"); got != 2 {
+		t.Errorf("Expected 2 redirects recorded, got %d", got)
+	}
+}
+
+func TestContentTypeAttributes(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	})
+
+	srv, err := NewServer(":0", handler, WithServerTracerProvider(tp), WithServerContentTypeAttributes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv.server.Handler)
+	defer ts.Close()
+
+	client, err := NewClient(WithClientTracerProvider(tp), WithClientContentTypeAttributes())
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "parent-span")
+	req, _ := http.NewRequestWithContext(ctx, "POST", ts.URL, strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	span.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+	for _, s := range spans {
+		if !hasAttrValue(s.Attributes, "http.request.header.content-type", "application/json") {
+			t.Errorf("%s: missing http.request.header.content-type", s.Name)
+		}
+		if !hasAttr(s.Attributes, semconv.HTTPResponseHeader("content-type", "application/json")) {
+			t.Errorf("%s: missing http.response.header.content-type", s.Name)
+		}
+	}
+}
+
+func TestBodySizeAttributes(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		_, _ = w.Write([]byte(`{"ok":true}`))
+		if r.URL.Path == "/chunked" {
+			// Flushing commits the response before its length is known.
+			_ = http.NewResponseController(w).Flush()
+			_, _ = w.Write([]byte(`{}`))
+		}
+	})
+	srv, err := NewServer(":0", handler, WithServerTracerProvider(tp), WithServerBodySizeAttributes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv.server.Handler)
+	defer ts.Close()
+
+	client, err := NewClient(WithClientTracerProvider(tp), WithClientBodySizeAttributes())
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	for path, want := range map[string]int64{"/known": 11, "/chunked": 13} {
+		t.Run(path, func(t *testing.T) {
+			exporter.Reset()
+			ctx, span := tp.Tracer("test").Start(context.Background(), "parent-span")
+			req, _ := http.NewRequestWithContext(ctx, "POST", ts.URL+path, strings.NewReader(`{}`))
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Do failed: %v", err)
+			}
+			if path == "/chunked" && resp.ContentLength != -1 {
+				t.Fatalf("Expected an unknown content length, got %d", resp.ContentLength)
+			}
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+			span.End()
+
+			spans := exporter.GetSpans()
+			if len(spans) != 2 {
+				t.Fatalf("Expected 2 spans, got %d", len(spans))
+			}
+			for _, s := range spans {
+				if !hasAttr(s.Attributes, semconv.HTTPRequestBodySize(2)) {
+					t.Errorf("%s: missing http.request.body.size=2", s.Name)
+				}
+				if !hasAttr(s.Attributes, semconv.HTTPResponseBodySize(int(want))) {
+					t.Errorf("%s: missing http.response.body.size=%d", s.Name, want)
+				}
+			}
+		})
+	}
+}
+
+func TestClientInstrumentation_PoolMissReason(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(WithClientMeterProvider(mp), WithIdleConnTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	get := func() {
+		resp, err := client.Get(ts.URL)
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}
+
+	get()                              // New connection: nothing pooled yet.
+	get()                              // Reuses the idle connection.
+	time.Sleep(100 * time.Millisecond) // Outlive IdleConnTimeout.
+	get()                              // New connection: the idle one was reaped.
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	counts := map[string]uint64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != "http.client.connection.wait_time" {
+				continue
+			}
+			for _, dp := range m.Data.(metricdata.Histogram[float64]).DataPoints {
+				reason, _ := dp.Attributes.Value("http.connection.pool_miss_reason")
+				counts[reason.AsString()] += dp.Count
+			}
+		}
+	}
+
+	want := map[string]uint64{"": 1, poolMissNoIdle: 1, poolMissIdleTimeout: 1}
+	for reason, n := range want {
+		if counts[reason] != n {
+			t.Errorf("expected %d wait(s) with reason %q, got %d (all: %v)", n, reason, counts[reason], counts)
+		}
+	}
+}
+
+func TestClientInstrumentation_GlobalMeterProvider(t *testing.T) {
+	// Clients without WithClientMeterProvider record every instrument with the global meter provider.
+	reader := sdkmetric.NewManualReader()
+	prev := otel.GetMeterProvider()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	t.Cleanup(func() { otel.SetMeterProvider(prev) })
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	client, err := NewClient()
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	resp, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	method := semconv.HTTPRequestMethodKey.String(http.MethodGet)
+	newConn := attribute.Bool("http.connection.reused", false)
+	if got := histogramCountWithAttr(t, reader, "http.client.connection.wait_time", newConn); got != 1 {
+		t.Errorf("expected 1 connection wait recorded, got %d", got)
+	}
+	if got := histogramCountWithAttr(t, reader, "http.client.request.duration", method); got != 1 {
+		t.Errorf("expected 1 request duration recorded, got %d", got)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	var activeRequests bool
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			activeRequests = activeRequests || m.Name == "http.client.active_requests"
+		}
+	}
+	if !activeRequests {
+		t.Error("expected active requests to be recorded")
+	}
+}
+
+func TestServerInstrumentation_HeaderSizes(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Out", "12345")
+		w.WriteHeader(http.StatusOK)
+		// Headers set after they have been committed are not sent, so must not be counted.
+		w.Header().Set("X-Late", "ignored")
+	})
+
+	srv, err := NewServer(":0", handler, WithServerMeterProvider(mp), WithHeaderSizeMetrics())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Test", "abc")
+	srv.server.Handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	// "X-Test: abc\r\n" and "X-Out: 12345\r\n"
+	if got := histogramSum(t, reader, "http.server.request.header.size"); got != 13 {
+		t.Errorf("Expected request header size 13, got %v", got)
+	}
+	if got := histogramSum(t, reader, "http.server.response.header.size"); got != 14 {
+		t.Errorf("Expected response header size 14, got %v", got)
+	}
+}
+
+func TestClientInstrumentation_RequestDuration(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer ts.Close()
+
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	client, err := NewClient(WithClientMeterProvider(mp))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	resp, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	// A request that fails outright is recorded with error.type, in place of a status code.
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	if _, err := client.Get(closed.URL); err == nil {
+		t.Fatal("Expected a request to a closed server to fail")
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	var points []metricdata.HistogramDataPoint[float64]
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if h, ok := m.Data.(metricdata.Histogram[float64]); ok && m.Name == "http.client.request.duration" {
+				points = append(points, h.DataPoints...)
+			}
+		}
+	}
+	if len(points) != 2 {
+		t.Fatalf("Expected 2 duration data points, got %d", len(points))
+	}
+	var sawStatus, sawError bool
+	for _, p := range points {
+		attrs := p.Attributes.ToSlice()
+		if !hasAttr(attrs, semconv.HTTPRequestMethodGet) || !hasAttr(attrs, semconv.ServerAddress("127.0.0.1")) {
+			t.Errorf("Expected method and server.address, got %v", attrs)
+		}
+		if hasAttr(attrs, semconv.HTTPResponseStatusCode(http.StatusTeapot)) {
+			sawStatus = true
+		}
+		if v, ok := p.Attributes.Value(semconv.ErrorTypeKey); ok && v.AsString() != "" {
+			sawError = true
+		}
+	}
+	if !sawStatus || !sawError {
+		t.Errorf("Expected a data point with the status code and one with error.type, got %+v", points)
+	}
+}
+
+func TestClientInstrumentation_MeasureToBodyClose(t *testing.T) {
+	const transferDelay = 100 * time.Millisecond
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		time.Sleep(transferDelay)
+		_, _ = w.Write([]byte("body"))
+	}))
+	defer ts.Close()
+
+	for _, tc := range []struct {
+		name     string
+		opts     []ClientOption
+		wantMode string
+		wantSlow bool
+	}{
+		{name: "headers", wantMode: durationModeHeaders, wantSlow: false},
+		{name: "body close", opts: []ClientOption{WithMeasureToBodyClose()}, wantMode: durationModeBodyClose, wantSlow: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			reader := sdkmetric.NewManualReader()
+			mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+			client, err := NewClient(append([]ClientOption{WithClientMeterProvider(mp)}, tc.opts...)...)
+			if err != nil {
+				t.Fatalf("NewClient failed: %v", err)
+			}
+
+			resp, err := client.Get(ts.URL)
+			if err != nil {
+				t.Fatalf("Get failed: %v", err)
+			}
+			// Reading to EOF and then closing must only record once.
+			_, _ = io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+
+			var rm metricdata.ResourceMetrics
+			if err := reader.Collect(context.Background(), &rm); err != nil {
+				t.Fatalf("Collect failed: %v", err)
+			}
+			var points []metricdata.HistogramDataPoint[float64]
+			for _, sm := range rm.ScopeMetrics {
+				for _, m := range sm.Metrics {
+					if h, ok := m.Data.(metricdata.Histogram[float64]); ok && m.Name == "http.client.request.duration" {
+						points = append(points, h.DataPoints...)
+					}
+				}
+			}
+			if len(points) != 1 || points[0].Count != 1 {
+				t.Fatalf("Expected a single duration recording, got %+v", points)
+			}
+			mode, _ := points[0].Attributes.Value("http.client.request.duration.mode")
+			if mode.AsString() != tc.wantMode {
+				t.Errorf("Expected mode %q, got %q", tc.wantMode, mode.AsString())
+			}
+			if slow := points[0].Sum >= transferDelay.Seconds(); slow != tc.wantSlow {
+				t.Errorf("Expected duration to include body transfer: %v, got %vs", tc.wantSlow, points[0].Sum)
+			}
+		})
+	}
+}
+
+func TestInstrumentation_TLSAttributes(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+
+	srv, err := NewServer(":0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		WithServerTracerProvider(tp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewUnstartedServer(srv.server.Handler)
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	client, err := NewClient(WithClientTracerProvider(tp))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	transport, err := getTransport(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport.TLSClientConfig = ts.Client().Transport.(*http.Transport).TLSClientConfig
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "parent-span")
+	req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	span.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+	for _, s := range spans {
+		if !hasAttr(s.Attributes, semconv.TLSProtocolNameKey.String("tls")) {
+			t.Errorf("%s: missing tls.protocol.name=tls", s.Name)
+		}
+		if !hasAttr(s.Attributes, semconv.TLSProtocolVersion("1.3")) {
+			t.Errorf("%s: missing tls.protocol.version=1.3", s.Name)
+		}
+		if !hasAttr(s.Attributes, semconv.TLSNextProtocol("h2")) {
+			t.Errorf("%s: missing tls.next_protocol=h2", s.Name)
+		}
+	}
+
+	// Plaintext requests carry no TLS attributes.
+	exporter.Reset()
+	w := httptest.NewRecorder()
+	srv.server.Handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	for _, a := range exporter.GetSpans()[0].Attributes {
+		if strings.HasPrefix(string(a.Key), "tls.") {
+			t.Errorf("Unexpected TLS attribute on plaintext request: %s", a.Key)
+		}
+	}
+}
+
+func TestInstrumentation_ProtocolVersion(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	srv, err := NewServer(":0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		WithServerTracerProvider(tp), WithServerMeterProvider(mp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h2 := httptest.NewUnstartedServer(srv.server.Handler)
+	h2.EnableHTTP2 = true
+	h2.StartTLS()
+	defer h2.Close()
+	h1 := httptest.NewServer(srv.server.Handler)
+	defer h1.Close()
+
+	client, err := NewClient(WithClientTracerProvider(tp))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	transport, err := getTransport(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport.TLSClientConfig = h2.Client().Transport.(*http.Transport).TLSClientConfig
+
+	for _, tc := range []struct {
+		url     string
+		version string
+	}{
+		{url: h2.URL, version: "2"},
+		{url: h1.URL, version: "1.1"},
+	} {
+		exporter.Reset()
+		ctx, span := tp.Tracer("test").Start(context.Background(), "parent-span")
+		req, _ := http.NewRequestWithContext(ctx, "GET", tc.url, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Do failed: %v", err)
+		}
+		_ = resp.Body.Close()
+		span.End()
+
+		p, _ := strconv.Atoi(req.URL.Port())
+		port := semconv.ServerPort(p)
+		for _, s := range exporter.GetSpans() {
+			if !hasAttr(s.Attributes, semconv.NetworkProtocolVersion(tc.version)) {
+				t.Errorf("%s: missing network.protocol.version=%s", s.Name, tc.version)
+			}
+			if !hasAttr(s.Attributes, port) {
+				t.Errorf("%s: missing server.port=%s", s.Name, req.URL.Port())
+			}
+		}
+		if got := histogramCountWithAttr(t, reader, "http.server.request.duration",
+			semconv.NetworkProtocolVersion(tc.version)); got != 1 {
+			t.Errorf("expected 1 request duration recorded for version %s, got %d", tc.version, got)
+		}
+	}
+}
+
+func TestClientInstrumentation_ErrorBodySnippet(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		status      int
+		body        string
+		wantSnippet string
+	}{
+		{name: "server error", status: 500, body: "database unavailable: connection refused", wantSnippet: "database unav"},
+		{name: "redacted", status: 502, body: `{"token": "abc123"}`, wantSnippet: redacted},
+		{name: "success", status: 200, body: "all good"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer ts.Close()
+
+			exporter := tracetest.NewInMemoryExporter()
+			tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+			client, err := NewClient(WithClientTracerProvider(tp), WithErrorBodySnippet(13))
+			if err != nil {
+				t.Fatalf("NewClient failed: %v", err)
+			}
+
+			ctx, span := tp.Tracer("test").Start(context.Background(), "parent-span")
+			req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL, nil)
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Do failed: %v", err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			span.End()
+
+			if string(body) != tc.body {
+				t.Errorf("Expected caller to receive the full body %q, got %q", tc.body, body)
+			}
+
+			var snippet string
+			for _, a := range exporter.GetSpans()[0].Attributes {
+				if a.Key == "http.response.body.snippet" {
+					snippet = a.Value.AsString()
+				}
+			}
+			if snippet != tc.wantSnippet {
+				t.Errorf("Expected snippet %q, got %q", tc.wantSnippet, snippet)
+			}
+		})
+	}
+
+	if _, err := NewClient(WithErrorBodySnippet(maxBodySnippetSize + 1)); err == nil {
+		t.Error("Expected error for snippet size above the cap")
+	}
+}
+
+func TestInstrumentation_SpanStatus(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+
+	srv, err := NewServer(":0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status, _ := strconv.Atoi(r.URL.Query().Get("status"))
+		w.WriteHeader(status)
+	}), WithServerTracerProvider(tp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv.server.Handler)
+	defer ts.Close()
+
+	for _, tc := range []struct {
+		name       string
+		opts       []ClientOption
+		status     int
+		wantClient codes.Code
+		wantServer codes.Code
+	}{
+		{name: "ok", status: 200, wantClient: codes.Unset, wantServer: codes.Unset},
+		{name: "not found", status: 404, wantClient: codes.Error, wantServer: codes.Unset},
+		{name: "server error", status: 500, wantClient: codes.Error, wantServer: codes.Error},
+		{name: "not found above the threshold", opts: []ClientOption{WithErrorStatusThreshold(500)}, status: 404,
+			wantClient: codes.Unset, wantServer: codes.Unset},
+		{name: "server error at the threshold", opts: []ClientOption{WithErrorStatusThreshold(500)}, status: 500,
+			wantClient: codes.Error, wantServer: codes.Error},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			exporter.Reset()
+			client, err := NewClient(append([]ClientOption{WithClientTracerProvider(tp)}, tc.opts...)...)
+			if err != nil {
+				t.Fatalf("NewClient failed: %v", err)
+			}
+
+			ctx, span := tp.Tracer("test").Start(context.Background(), "parent-span")
+			req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL+"?status="+strconv.Itoa(tc.status), nil)
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Do failed: %v", err)
+			}
+			_ = resp.Body.Close()
+			span.End()
+
+			spans := exporter.GetSpans()
+			if len(spans) != 2 {
+				t.Fatalf("Expected 2 spans, got %d", len(spans))
+			}
+			for _, s := range spans {
+				want := tc.wantServer
+				if s.SpanKind != oteltrace.SpanKindServer {
+					want = tc.wantClient
+				}
+				if s.Status.Code != want {
+					t.Errorf("%s: expected status %s, got %s", s.Name, want, s.Status.Code)
+				}
+			}
+		})
+	}
+
+	for _, code := range []int{399, 600} {
+		if _, err := NewClient(WithErrorStatusThreshold(code)); err == nil {
+			t.Errorf("Expected error for threshold %d", code)
+		}
+	}
+}
+
+func TestInstrumentation_Trailers(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+
+	srv, err := NewServer(":0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "Grpc-Status")
+		_, _ = w.Write([]byte("message"))
+		w.Header().Set("Grpc-Status", "0")
+		w.Header().Set(http.TrailerPrefix+"Grpc-Message", "ok")
+	}), WithServerTracerProvider(tp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv.server.Handler)
+	defer ts.Close()
+
+	client, err := NewClient(WithClientTracerProvider(tp), WithMeasureToBodyClose())
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "parent-span")
+	req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do failed: %v", err)
+	}
+	_, _ = io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	span.End()
+
+	if got := resp.Trailer.Get("Grpc-Status"); got != "0" {
+		t.Errorf("Expected declared trailer Grpc-Status=0 after reading the body, got %q", got)
+	}
+	if got := resp.Trailer.Get("Grpc-Message"); got != "ok" {
+		t.Errorf("Expected prefixed trailer Grpc-Message=ok after reading the body, got %q", got)
+	}
+
+	for _, s := range exporter.GetSpans() {
+		var keys []string
+		for _, a := range s.Attributes {
+			if a.Key == "http.response.trailers" {
+				keys = a.Value.AsStringSlice()
+			}
+		}
+		switch s.SpanKind {
+		case oteltrace.SpanKindServer:
+			if len(keys) != 2 || keys[0] != "Grpc-Message" || keys[1] != "Grpc-Status" {
+				t.Errorf("Expected server span to record both trailer keys, got %v", keys)
+			}
+		default:
+			// Only declared trailers are known to the client when the headers arrive.
+			if len(keys) != 1 || keys[0] != "Grpc-Status" {
+				t.Errorf("Expected client span to record the declared trailer key, got %v", keys)
+			}
+		}
+	}
+}
+
+func TestClientInstrumentation_WithoutPropagation(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	for _, tc := range []struct {
+		name    string
+		opts    []ClientOption
+		skipped []string
+	}{
+		// CONNECT requests go to a proxy, and don't carry trace context without any configuration.
+		{name: "default", skipped: []string{http.MethodConnect}},
+		{name: "default with retries", opts: []ClientOption{WithRetry(2), WithLogicalRequestSpan()},
+			skipped: []string{http.MethodConnect}},
+		{name: "replaced", opts: []ClientOption{WithoutPropagation(http.MethodConnect, http.MethodOptions)},
+			skipped: []string{http.MethodConnect, http.MethodOptions}},
+		{name: "none", opts: []ClientOption{WithoutPropagation()}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+
+			injected := map[string]bool{}
+			client, err := NewClient(append([]ClientOption{
+				func(c *http.Client) error {
+					c.Transport = &InstrumentedTransport{Base: &mockRoundTripper{
+						roundTrip: func(req *http.Request) (*http.Response, error) {
+							injected[req.Method] = req.Header.Get("traceparent") != ""
+							return &http.Response{StatusCode: 200, Body: http.NoBody, Request: req}, nil
+						},
+					}}
+					return nil
+				},
+				WithClientTracerProvider(tp),
+			}, tc.opts...)...)
+			if err != nil {
+				t.Fatalf("NewClient failed: %v", err)
+			}
+
+			methods := []string{http.MethodConnect, http.MethodOptions, http.MethodGet}
+			for _, method := range methods {
+				ctx, span := tp.Tracer("test").Start(context.Background(), "parent-span")
+				req, _ := http.NewRequestWithContext(ctx, method, "http://example.com", nil)
+				resp, err := client.Do(req)
+				if err != nil {
+					t.Fatalf("Do failed: %v", err)
+				}
+				_ = resp.Body.Close()
+				span.End()
+			}
+
+			for _, method := range methods {
+				if want := !slices.Contains(tc.skipped, method); injected[method] != want {
+					t.Errorf("Expected traceparent on %s to be %t, got %t", method, want, injected[method])
+				}
+			}
+			// Requests without propagation are still traced.
+			if !hasAttr(exporter.GetSpans()[0].Attributes, semconv.HTTPRequestMethodKey.String(http.MethodConnect)) {
+				t.Error("Expected the CONNECT request to still be recorded on the span")
+			}
+		})
+	}
+}
+
+func histogramSum(t *testing.T, reader sdkmetric.Reader, name string) int64 {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	var total int64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+			if h, ok := m.Data.(metricdata.Histogram[int64]); ok {
+				for _, dp := range h.DataPoints {
+					total += dp.Sum
+				}
+			}
+		}
+	}
+	return total
+}
+
+func TestInstrumentation_DurationExemplars(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader), sdkmetric.WithExemplarFilter(exemplar.TraceBasedFilter))
+
+	s, err := NewServer(":0", http.NotFoundHandler(), WithServerTracerProvider(tp), WithServerMeterProvider(mp))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ts := httptest.NewServer(s.server.Handler)
+	defer ts.Close()
+	c, err := NewClient(WithClientTracerProvider(tp), WithClientMeterProvider(mp))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL, nil)
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+	parent.End()
+
+	var serverSpan trace.ReadOnlySpan
+	for _, span := range exporter.GetSpans().Snapshots() {
+		if span.SpanKind() == oteltrace.SpanKindServer {
+			serverSpan = span
+		}
+	}
+	if serverSpan == nil {
+		t.Fatal("expected a server span")
+	}
+
+	// Each duration points to the span it was recorded in: the server span, and the caller's span for the
+	// client, as the client enriches it rather than starting its own.
+	want := map[string]oteltrace.SpanContext{
+		"http.server.request.duration": serverSpan.SpanContext(),
+		"http.client.request.duration": parent.SpanContext(),
+	}
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			sc, ok := want[m.Name]
+			if !ok {
+				continue
+			}
+			delete(want, m.Name)
+			h := m.Data.(metricdata.Histogram[float64])
+			if len(h.DataPoints) != 1 || len(h.DataPoints[0].Exemplars) != 1 {
+				t.Fatalf("expected an exemplar for %s, got %+v", m.Name, h.DataPoints)
+			}
+			e := h.DataPoints[0].Exemplars[0]
+			traceID, spanID := sc.TraceID(), sc.SpanID()
+			if !bytes.Equal(e.TraceID, traceID[:]) || !bytes.Equal(e.SpanID, spanID[:]) {
+				t.Errorf("expected the %s exemplar to point to span %s, got %x/%x", m.Name, spanID, e.TraceID, e.SpanID)
+			}
+		}
+	}
+	if len(want) != 0 {
+		t.Errorf("expected durations to be recorded, missing %v", want)
+	}
+}
+
+// histogramCountWithAttr returns the number of measurements recorded by the named float64 histogram with attr.
+func histogramCountWithAttr(t *testing.T, reader sdkmetric.Reader, name string, attr attribute.KeyValue) uint64 {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	var count uint64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if h, ok := m.Data.(metricdata.Histogram[float64]); ok && m.Name == name {
+				for _, dp := range h.DataPoints {
+					if hasAttr(dp.Attributes.ToSlice(), attr) {
+						count += dp.Count
+					}
+				}
+			}
+		}
+	}
+	return count
+}
+
+func sumCounter(t *testing.T, reader sdkmetric.Reader, name string) int64 {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	var total int64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+			if sum, ok := m.Data.(metricdata.Sum[int64]); ok {
+				for _, dp := range sum.DataPoints {
+					total += dp.Value
+				}
+			}
+		}
+	}
+	return total
+}
+
 func hasAttr(attrs []attribute.KeyValue, want attribute.KeyValue) bool {
 	for _, a := range attrs {
 		if a.Key == want.Key && a.Value.Emit() == want.Value.Emit() {
@@ -135,6 +1054,17 @@ func hasAttr(attrs []attribute.KeyValue, want attribute.KeyValue) bool {
 	return false
 }
 
+// hasAttrValue reports whether the key is present with a value that contains want, regardless of whether
+// the value is a string or a string slice.
+func hasAttrValue(attrs []attribute.KeyValue, key attribute.Key, want string) bool {
+	for _, a := range attrs {
+		if a.Key == key && strings.Contains(a.Value.Emit(), want) {
+			return true
+		}
+	}
+	return false
+}
+
 type mockRoundTripper struct {
 	roundTrip func(*http.Request) (*http.Response, error)
 }
@@ -0,0 +1,61 @@
+package http
+
+import (
+	"context"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// loggerKey is the context key for the request-scoped logger.
+type loggerKey struct{}
+
+// discardLogger is returned by LoggerFromContext when no logger has been set.
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// WithRequestLogger puts a logger derived from base into each request's context, retrievable with
+// LoggerFromContext. It is pre-populated with the trace_id, span_id, method and route of the request, so that
+// handlers' logs are correlated with their traces. The route is the one matched by this package's ServeMux (or
+// reported with WithRoute), and is omitted until a route has been matched.
+func WithRequestLogger(base *slog.Logger) ServerOption {
+	return func(s *Server) error {
+		s.logger = base
+		return nil
+	}
+}
+
+// LoggerFromContext returns the request-scoped logger set up by WithRequestLogger. If there is none, it
+// returns a logger that discards everything, so it is always safe to use.
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	if rl, ok := ctx.Value(loggerKey{}).(*requestLogger); ok {
+		return rl.logger()
+	}
+	return discardLogger
+}
+
+// requestLogger derives the logger for a request. The route is only known once a router has matched the
+// request, and handlers (and the loggers they derive) format attributes eagerly, so the logger is derived
+// when it is asked for rather than when the request starts.
+type requestLogger struct {
+	base      *slog.Logger
+	span      trace.Span
+	method    string
+	routes    *routeHolder
+	requestID string
+}
+
+func (rl *requestLogger) logger() *slog.Logger {
+	sc := rl.span.SpanContext()
+	attrs := []any{
+		slog.String("trace_id", sc.TraceID().String()),
+		slog.String("span_id", sc.SpanID().String()),
+		slog.String("method", rl.method),
+	}
+	if route := rl.routes.get(); route != "" {
+		attrs = append(attrs, slog.String("route", route))
+	}
+	if rl.requestID != "" {
+		attrs = append(attrs, slog.String("request_id", rl.requestID))
+	}
+	return rl.base.With(attrs...)
+}
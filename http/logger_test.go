@@ -0,0 +1,85 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestWithRequestLogger(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+
+	var buf bytes.Buffer
+	mux := NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		LoggerFromContext(r.Context()).Info("fetching user")
+	})
+
+	s, err := NewServer(":0", mux,
+		WithServerTracerProvider(tp),
+		WithRequestLogger(slog.New(slog.NewJSONHandler(&buf, nil))),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.server.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/42", nil))
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("expected a JSON log record, got %q: %v", buf.String(), err)
+	}
+	sc := exporter.GetSpans()[0].SpanContext
+	want := map[string]string{
+		"trace_id": sc.TraceID().String(),
+		"span_id":  sc.SpanID().String(),
+		"method":   "GET",
+		"route":    "/users/{id}",
+	}
+	for k, v := range want {
+		if record[k] != v {
+			t.Errorf("expected %s=%q, got %v", k, v, record[k])
+		}
+	}
+}
+
+func TestWithRequestLogger_NoRoute(t *testing.T) {
+	var buf bytes.Buffer
+	handler := stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		LoggerFromContext(r.Context()).Info("no route")
+	})
+
+	s, err := NewServer(":0", handler, WithRequestLogger(slog.New(slog.NewJSONHandler(&buf, nil))))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.server.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/", nil))
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("expected a JSON log record, got %q: %v", buf.String(), err)
+	}
+	if record["method"] != "POST" {
+		t.Errorf("expected method=POST, got %v", record["method"])
+	}
+	if route, ok := record["route"]; ok {
+		t.Errorf("expected no route without a router, got %v", route)
+	}
+}
+
+func TestLoggerFromContext_Default(t *testing.T) {
+	l := LoggerFromContext(context.Background())
+	if l == nil {
+		t.Fatal("expected a logger")
+	}
+	if l.Enabled(context.Background(), slog.LevelError) {
+		t.Error("expected the default logger to discard records")
+	}
+}
@@ -0,0 +1,139 @@
+package http
+
+import (
+	"context"
+	stdhttp "net/http"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// resilienceTransport is implemented by wrapping transports that may make several attempts for, or
+// otherwise intervene in, a single logical request (e.g. retries).
+type resilienceTransport interface {
+	wrappingTransport
+	resilience()
+}
+
+// attemptTransport is implemented by resilience layers that make the attempts for a logical request
+// themselves, starting a span for each (see logicalRequest.startAttempt).
+type attemptTransport interface {
+	resilienceTransport
+	makesAttempts()
+}
+
+// hasResilienceLayer reports whether any of the transports beneath rt is a resilience layer.
+func hasResilienceLayer(rt stdhttp.RoundTripper) bool {
+	return hasLayer(rt, func(w wrappingTransport) bool {
+		_, ok := w.(resilienceTransport)
+		return ok
+	})
+}
+
+// hasAttemptLayer reports whether any of the transports beneath rt makes its own attempts.
+func hasAttemptLayer(rt stdhttp.RoundTripper) bool {
+	return hasLayer(rt, func(w wrappingTransport) bool {
+		_, ok := w.(attemptTransport)
+		return ok
+	})
+}
+
+// hasLayer reports whether any of the transports beneath rt satisfies match.
+func hasLayer(rt stdhttp.RoundTripper, match func(wrappingTransport) bool) bool {
+	for {
+		w, ok := rt.(wrappingTransport)
+		if !ok {
+			return false
+		}
+		if match(w) {
+			return true
+		}
+		rt = w.unwrap()
+	}
+}
+
+type logicalRequestKey struct{}
+
+// logicalRequest tracks a request across all of the attempts the resilience layers make for it. It owns
+// the span covering the whole operation, and the layers report what they did into it.
+type logicalRequest struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	spanName   func(*stdhttp.Request) string
+	span       trace.Span
+	attempts   atomic.Int64
+
+	// errorStatus is the lowest response status code that marks an attempt span as an error, as it does the
+	// client span (see WithErrorStatusThreshold).
+	errorStatus int
+
+	// shortCircuited is set when a circuit breaker rejected an attempt, and throttleWait is the total time
+	// attempts waited for client-side limits, in nanoseconds.
+	shortCircuited atomic.Bool
+	throttleWait   atomic.Int64
+}
+
+// startLogicalRequest starts the span for a logical request, returning a request carrying it.
+func startLogicalRequest(
+	tracer trace.Tracer, propagator propagation.TextMapPropagator, spanName func(*stdhttp.Request) string,
+	errorStatus int, req *stdhttp.Request,
+) (*stdhttp.Request, *logicalRequest) {
+	ctx, span := tracer.Start(req.Context(), clientSpanName(spanName, req), trace.WithSpanKind(trace.SpanKindInternal))
+	lr := &logicalRequest{
+		tracer: tracer, propagator: propagator, spanName: spanName, span: span, errorStatus: errorStatus,
+	}
+	return req.WithContext(context.WithValue(ctx, logicalRequestKey{}, lr)), lr
+}
+
+func logicalRequestFromContext(ctx context.Context) *logicalRequest {
+	lr, _ := ctx.Value(logicalRequestKey{}).(*logicalRequest)
+	return lr
+}
+
+// end records the outcome of the logical request and ends its span.
+func (lr *logicalRequest) end() {
+	lr.span.SetAttributes(
+		attribute.Int64("http.request.attempts", lr.attempts.Load()),
+		attribute.Bool("http.client.circuit.short_circuited", lr.shortCircuited.Load()),
+		attribute.Float64("http.client.throttle.wait_time", time.Duration(lr.throttleWait.Load()).Seconds()),
+	)
+	lr.span.End()
+}
+
+// startAttempt starts a client span for a single attempt as a child of the logical request span, and
+// returns a copy of the request that carries it (and propagates it downstream).
+func (lr *logicalRequest) startAttempt(req *stdhttp.Request) (*stdhttp.Request, trace.Span) {
+	n := lr.attempts.Add(1)
+	ctx, span := lr.tracer.Start(req.Context(), clientSpanName(lr.spanName, req),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(clientRequestAttrs(req)...),
+	)
+	if n > 1 {
+		span.SetAttributes(semconv.HTTPRequestResendCountKey.Int64(n - 1))
+	}
+
+	attempt := req.Clone(ctx)
+	textMapPropagator(lr.propagator).Inject(ctx, propagation.HeaderCarrier(attempt.Header))
+	return attempt, span
+}
+
+// endAttempt records the outcome of an attempt on its span, and ends it. Failed responses, such as a 503
+// that is then retried, mark the span as an error as they would the client span.
+func (lr *logicalRequest) endAttempt(span trace.Span, resp *stdhttp.Response, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	if resp != nil {
+		span.SetAttributes(clientResponseAttrs(resp)...)
+		if resp.StatusCode >= lr.errorStatus {
+			span.SetStatus(codes.Error, "")
+		}
+	}
+	span.End()
+}
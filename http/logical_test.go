@@ -0,0 +1,273 @@
+package http
+
+import (
+	"context"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestWithLogicalRequestSpan(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+
+	var mu sync.Mutex
+	var traceparents []string
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+		if len(traceparents) < 3 {
+			w.WriteHeader(stdhttp.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	c, err := NewClient(WithClientTracerProvider(tp), WithLogicalRequestSpan(), WithRetry(3))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent-span")
+	req, _ := stdhttp.NewRequestWithContext(ctx, "GET", ts.URL, nil)
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+	parent.End()
+
+	var logical tracetest.SpanStub
+	var attempts []tracetest.SpanStub
+	for _, s := range exporter.GetSpans() {
+		switch {
+		case s.Name == "parent-span":
+		case s.SpanKind == oteltrace.SpanKindInternal:
+			logical = s
+		case s.SpanKind == oteltrace.SpanKindClient:
+			attempts = append(attempts, s)
+		}
+	}
+
+	if logical.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Error("Expected the logical span to be a child of the caller's span")
+	}
+	if !hasAttr(logical.Attributes, attribute.Int64("http.request.attempts", 3)) {
+		t.Error("Missing http.request.attempts=3 on the logical span")
+	}
+	if len(attempts) != 3 {
+		t.Fatalf("Expected 3 attempt spans, got %d", len(attempts))
+	}
+	for i, a := range attempts {
+		if a.Parent.SpanID() != logical.SpanContext.SpanID() {
+			t.Errorf("Expected attempt %d to be a child of the logical span", i)
+		}
+		if !strings.Contains(traceparents[i], a.SpanContext.SpanID().String()) {
+			t.Errorf("Expected attempt %d to propagate its own span, got %s", i, traceparents[i])
+		}
+		// The attempts that got a 503 failed, as a client span for them would have.
+		want := codes.Unset
+		if i < 2 {
+			want = codes.Error
+		}
+		if a.Status.Code != want {
+			t.Errorf("Expected attempt %d to have status %v, got %v", i, want, a.Status.Code)
+		}
+	}
+}
+
+func TestWithLogicalRequestSpan_WithoutResilience(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {}))
+	defer ts.Close()
+
+	c, err := NewClient(WithClientTracerProvider(tp), WithLogicalRequestSpan())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resp, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if n := len(exporter.GetSpans()); n != 0 {
+		t.Errorf("Expected no spans without resilience features, got %d", n)
+	}
+}
+
+// logicalSpans sends a request to url with c for each of the given number of requests, in parallel, and returns
+// the logical spans they recorded.
+func logicalSpans(t *testing.T, c *stdhttp.Client, exporter *tracetest.InMemoryExporter, url string,
+	requests int,
+) []tracetest.SpanStub {
+	t.Helper()
+	var wg sync.WaitGroup
+	for range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := c.Get(url)
+			if err == nil {
+				_ = resp.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+
+	var logical []tracetest.SpanStub
+	for _, s := range exporter.GetSpans() {
+		if s.SpanKind == oteltrace.SpanKindInternal {
+			logical = append(logical, s)
+		}
+	}
+	if len(logical) != requests {
+		t.Fatalf("Expected %d logical spans, got %d", requests, len(logical))
+	}
+	return logical
+}
+
+// attrValue returns the value of the attribute key in attrs.
+func attrValue(attrs []attribute.KeyValue, key attribute.Key) attribute.Value {
+	for _, a := range attrs {
+		if a.Key == key {
+			return a.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestWithLogicalRequestSpan_CircuitBreaker(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		w.WriteHeader(stdhttp.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	c, err := NewClient(WithClientTracerProvider(tp), WithLogicalRequestSpan(),
+		WithCircuitBreaker(WithBreakerWindow(1), WithBreakerCooldown(time.Hour)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The first request fails and opens the breaker, which rejects the second.
+	for _, want := range []bool{false, true} {
+		exporter.Reset()
+		logical := logicalSpans(t, c, exporter, ts.URL, 1)[0]
+		if !hasAttr(logical.Attributes, attribute.Bool("http.client.circuit.short_circuited", want)) {
+			t.Errorf("Expected http.client.circuit.short_circuited=%t, got %v", want, logical.Attributes)
+		}
+		if !hasAttr(logical.Attributes, attribute.Int64("http.request.attempts", 1)) {
+			t.Errorf("Expected http.request.attempts=1, got %v", logical.Attributes)
+		}
+	}
+}
+
+func TestWithLogicalRequestSpan_RateLimit(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {}))
+	defer ts.Close()
+
+	// The second request waits 50ms for a token.
+	c, err := NewClient(WithClientTracerProvider(tp), WithLogicalRequestSpan(), WithClientRateLimit(20, 1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var waited int
+	for _, s := range logicalSpans(t, c, exporter, ts.URL, 2) {
+		if attrValue(s.Attributes, "http.client.throttle.wait_time").AsFloat64() > 0 {
+			waited++
+		}
+		if !hasAttr(s.Attributes, attribute.Bool("http.client.circuit.short_circuited", false)) {
+			t.Errorf("Expected http.client.circuit.short_circuited=false, got %v", s.Attributes)
+		}
+	}
+	if waited != 1 {
+		t.Errorf("Expected 1 request to record a throttle wait, got %d", waited)
+	}
+}
+
+func TestWithLogicalRequestSpan_ConcurrencyLimit(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer ts.Close()
+
+	// Only one request is in flight at a time, so the other waits for it.
+	c, err := NewClient(WithClientTracerProvider(tp), WithLogicalRequestSpan(), WithMaxConcurrentRequests(1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var waited int
+	for _, s := range logicalSpans(t, c, exporter, ts.URL, 2) {
+		if attrValue(s.Attributes, "http.client.throttle.wait_time").AsFloat64() > 0 {
+			waited++
+		}
+	}
+	if waited != 1 {
+		t.Errorf("Expected 1 request to record a throttle wait, got %d", waited)
+	}
+}
+
+func TestWithClientSpanNameFormatter(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {}))
+	defer ts.Close()
+
+	c, err := NewClient(
+		WithClientTracerProvider(tp),
+		WithLogicalRequestSpan(),
+		WithRetry(2),
+		WithClientSpanNameFormatter(func(r *stdhttp.Request) string { return r.Method + " " + r.URL.Host }),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	want := "GET " + strings.TrimPrefix(ts.URL, "http://")
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("Expected a logical and an attempt span, got %d", len(spans))
+	}
+	for _, s := range spans {
+		if s.Name != want {
+			t.Errorf("Expected span name %q, got %q", want, s.Name)
+		}
+	}
+
+	if _, err := NewClient(WithClientSpanNameFormatter(nil)); err == nil {
+		t.Error("expected an error for a nil formatter")
+	}
+}
@@ -0,0 +1,101 @@
+package http
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// Reasons a request is counted as malformed, recorded as error.type.
+const (
+	malformedUnhandled    = "unhandled_request"
+	malformedTLSHandshake = "tls_handshake"
+)
+
+// otherValue is the semantic conventions' placeholder for attribute values beyond a cardinality limit.
+const otherValue = "_OTHER"
+
+// maxMalformedClients bounds the distinct client addresses recorded on http.server.malformed_requests.
+// Further addresses are recorded as _OTHER.
+const maxMalformedClients = 100
+
+// tlsHandshakeErrorPrefix starts the message net/http logs when a TLS handshake fails.
+const tlsHandshakeErrorPrefix = "http: TLS handshake error from "
+
+// WithMalformedRequestMetrics counts requests that net/http rejects before they reach a handler in
+// http.server.malformed_requests, as a signal of request smuggling attempts and other malicious or broken
+// clients. net/http answers these itself (usually with a 400), so they are otherwise invisible. The counter
+// records the client.address, bounded to the first 100 distinct addresses, and the error.type:
+//   - unhandled_request: a connection started reading a request that never reached a handler. This covers
+//     requests net/http rejects at the protocol level, such as conflicting or invalid Content-Length and
+//     Transfer-Encoding headers, invalid methods or request lines, a missing Host header, oversized
+//     headers and unsupported HTTP versions. net/http does not report why, and it also covers clients that
+//     disconnect or time out part way through sending a request.
+//   - tls_handshake: a TLS handshake failed, as reported to the server's ErrorLog (which is still written to).
+//
+// Malformed HTTP/2 frames are handled within the HTTP/2 connection and are not detected.
+func WithMalformedRequestMetrics() ServerOption {
+	return func(s *Server) error {
+		s.malformedRequestMetrics = true
+		return nil
+	}
+}
+
+// recordMalformedRequest counts a malformed request from the client at addr.
+func (s *Server) recordMalformedRequest(reason, addr string) {
+	host, _ := splitHostPort(addr)
+	s.mMalformedRequests.Add(context.Background(), 1, metric.WithAttributes(
+		semconv.ErrorTypeKey.String(reason),
+		semconv.ClientAddressKey.String(s.malformedClients.bound(host)),
+	))
+}
+
+// errorLogWriter receives the server's ErrorLog output, counting the malformed requests it reports before
+// passing it on to next (or the standard logger, if next is nil).
+type errorLogWriter struct {
+	s    *Server
+	next *log.Logger
+}
+
+func (w *errorLogWriter) Write(p []byte) (int, error) {
+	msg := string(p)
+	if rest, ok := strings.CutPrefix(msg, tlsHandshakeErrorPrefix); ok {
+		addr, _, _ := strings.Cut(rest, ": ")
+		w.s.recordMalformedRequest(malformedTLSHandshake, addr)
+	}
+
+	if w.next != nil {
+		return len(p), w.next.Output(2, msg)
+	}
+	return len(p), log.Output(2, msg)
+}
+
+// boundedValues bounds the cardinality of an attribute, passing through up to limit distinct values and
+// replacing any others with _OTHER.
+type boundedValues struct {
+	mu     sync.Mutex
+	limit  int
+	values map[string]struct{}
+}
+
+func newBoundedValues(limit int) *boundedValues {
+	return &boundedValues{limit: limit, values: make(map[string]struct{})}
+}
+
+// bound returns v if it is one of the first limit distinct values seen, or _OTHER.
+func (b *boundedValues) bound(v string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.values[v]; ok {
+		return v
+	}
+	if len(b.values) >= b.limit {
+		return otherValue
+	}
+	b.values[v] = struct{}{}
+	return v
+}
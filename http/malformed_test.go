@@ -0,0 +1,98 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net"
+	stdhttp "net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+func TestServer_MalformedRequestMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	var logged bytes.Buffer
+	s, err := NewServer(":0", stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {}),
+		WithServerMeterProvider(mp),
+		WithMalformedRequestMetrics(),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.server.ErrorLog.SetOutput(&errorLogWriter{s: s, next: log.New(&logged, "", 0)})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	go func() { _ = s.server.Serve(l) }()
+	defer func() { _ = s.shutdown(context.Background()) }()
+
+	send := func(raw string) string {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer func() { _ = conn.Close() }()
+		if _, err := io.WriteString(conn, raw); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		status, _ := bufio.NewReader(conn).ReadString('\n')
+		return status
+	}
+
+	// A well-formed request is not counted.
+	wellFormed := "GET / HTTP/1.1\r\nHost: example.com\r\nConnection: close\r\n\r\n"
+	if status := send(wellFormed); !strings.Contains(status, "200") {
+		t.Fatalf("expected a 200, got %q", status)
+	}
+
+	// Conflicting Content-Lengths, a classic smuggling vector, are rejected by net/http.
+	smuggled := "POST / HTTP/1.1\r\nHost: example.com\r\nContent-Length: 1\r\nContent-Length: 2\r\n\r\nab"
+	if status := send(smuggled); !strings.Contains(status, "400") {
+		t.Fatalf("expected a 400, got %q", status)
+	}
+
+	unhandled := attribute.String("error.type", "unhandled_request")
+	deadline := time.Now().Add(2 * time.Second)
+	for sumCounterWithAttr(t, reader, "http.server.malformed_requests", unhandled) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := sumCounterWithAttr(t, reader, "http.server.malformed_requests", unhandled); got != 1 {
+		t.Errorf("expected 1 malformed request, got %d", got)
+	}
+	if got := sumCounterWithAttr(t, reader, "http.server.malformed_requests",
+		attribute.String("client.address", "127.0.0.1")); got != 1 {
+		t.Errorf("expected the client address to be recorded, got %d", got)
+	}
+
+	// TLS handshake failures are picked up from the ErrorLog, which is still written to.
+	s.server.ErrorLog.Printf("http: TLS handshake error from 192.0.2.1:1234: EOF")
+	if got := sumCounterWithAttr(t, reader, "http.server.malformed_requests",
+		attribute.String("error.type", "tls_handshake")); got != 1 {
+		t.Errorf("expected 1 TLS handshake failure, got %d", got)
+	}
+	if !strings.Contains(logged.String(), "TLS handshake error") {
+		t.Errorf("expected the error to be passed on, got %q", logged.String())
+	}
+}
+
+func TestBoundedValues(t *testing.T) {
+	b := newBoundedValues(2)
+	for _, tc := range []struct{ in, want string }{
+		{"a", "a"}, {"b", "b"}, {"c", "_OTHER"}, {"a", "a"},
+	} {
+		if got := b.bound(tc.in); got != tc.want {
+			t.Errorf("bound(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
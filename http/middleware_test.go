@@ -0,0 +1,97 @@
+package http
+
+import (
+	"context"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestWithMiddleware_Order(t *testing.T) {
+	var order []string
+	record := func(name string) func(stdhttp.Handler) stdhttp.Handler {
+		return func(next stdhttp.Handler) stdhttp.Handler {
+			return stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	handler := stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		order = append(order, "handler")
+	})
+
+	s, err := NewServer(":0", handler,
+		WithMiddleware("first", record("first")),
+		WithMiddleware("second", record("second")),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.server.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if len(order) != 3 || order[0] != "first" || order[1] != "second" || order[2] != "handler" {
+		t.Errorf("expected [first second handler], got %v", order)
+	}
+
+	if _, err := NewServer(":0", handler, WithMiddleware("", record("x"))); err == nil {
+		t.Error("expected error for unnamed middleware")
+	}
+}
+
+func TestWithMiddlewareTiming(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	slowAuth := func(next stdhttp.Handler) stdhttp.Handler {
+		return stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+			time.Sleep(50 * time.Millisecond)
+			next.ServeHTTP(w, r)
+		})
+	}
+	passthrough := func(next stdhttp.Handler) stdhttp.Handler { return next }
+	handler := stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		time.Sleep(50 * time.Millisecond)
+	})
+
+	s, err := NewServer(":0", handler,
+		WithServerMeterProvider(mp),
+		WithMiddleware("auth", slowAuth),
+		WithMiddleware("passthrough", passthrough),
+		WithMiddlewareTiming(),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.server.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	durations := map[string]float64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			h, ok := m.Data.(metricdata.Histogram[float64])
+			if !ok || m.Name != "http.server.middleware.duration" {
+				continue
+			}
+			for _, dp := range h.DataPoints {
+				name, _ := dp.Attributes.Value("http.server.middleware.name")
+				durations[name.AsString()] += dp.Sum
+			}
+		}
+	}
+
+	// Each layer's own time excludes the layers and handler it wraps.
+	if d := durations["auth"]; d < 0.05 || d >= 0.1 {
+		t.Errorf("expected auth to account for its own 50ms only, got %vs", d)
+	}
+	if d, ok := durations["passthrough"]; !ok || d >= 0.05 {
+		t.Errorf("expected passthrough to be recorded without the handler's time, got %vs", d)
+	}
+}
@@ -0,0 +1,100 @@
+package http
+
+import (
+	"context"
+	stdhttp "net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// ServeMux wraps http.ServeMux, recording the pattern a request matched so that the server instrumentation
+// can name spans and label telemetry by route (e.g. "/users/{id}") rather than by raw path.
+type ServeMux struct {
+	mux *stdhttp.ServeMux
+}
+
+// NewServeMux returns a new, empty ServeMux.
+func NewServeMux() *ServeMux {
+	return &ServeMux{mux: stdhttp.NewServeMux()}
+}
+
+// Handle registers the handler for the given pattern, using the http.ServeMux pattern syntax.
+func (m *ServeMux) Handle(pattern string, handler stdhttp.Handler) {
+	m.mux.Handle(pattern, &routeHandler{route: routeFromPattern(pattern), base: handler})
+}
+
+// HandleFunc registers the handler function for the given pattern, using the http.ServeMux pattern syntax.
+func (m *ServeMux) HandleFunc(pattern string, handler func(stdhttp.ResponseWriter, *stdhttp.Request)) {
+	m.Handle(pattern, stdhttp.HandlerFunc(handler))
+}
+
+// ServeHTTP implements http.Handler.
+func (m *ServeMux) ServeHTTP(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	m.mux.ServeHTTP(w, r)
+}
+
+// routeHandler reports its route to the instrumentation before calling the registered handler.
+type routeHandler struct {
+	route string
+	base  stdhttp.Handler
+}
+
+func (h *routeHandler) ServeHTTP(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	h.base.ServeHTTP(w, r.WithContext(WithRoute(r.Context(), h.route)))
+}
+
+// routeFromPattern strips the optional method and host from a ServeMux pattern, leaving the path template.
+func routeFromPattern(pattern string) string {
+	if i := strings.IndexAny(pattern, " \t"); i >= 0 {
+		pattern = strings.TrimLeft(pattern[i:], " \t")
+	}
+	if i := strings.IndexByte(pattern, '/'); i > 0 {
+		pattern = pattern[i:]
+	}
+	return pattern
+}
+
+type routeKey struct{}
+
+// WithRoute returns a copy of ctx carrying the route template (e.g. "/users/{id}") that the request matched.
+// Routers other than ServeMux call it once they have matched a request, so that the server instrumentation
+// names spans and labels metrics by route rather than by raw path:
+//
+//	r = r.WithContext(http.WithRoute(r.Context(), "/users/{id}"))
+//
+// It may also be called before the instrumentation runs, such as from WithRequestContextFunc, in which case
+// the route is used from the start of the request.
+func WithRoute(ctx context.Context, route string) context.Context {
+	if rh := routeHolderFromContext(ctx); rh != nil {
+		rh.set(route)
+	}
+	return context.WithValue(ctx, routeKey{}, route)
+}
+
+// RouteFromContext returns the route set with WithRoute, or "" if there is none.
+func RouteFromContext(ctx context.Context) string {
+	route, _ := ctx.Value(routeKey{}).(string)
+	return route
+}
+
+type routeHolderKey struct{}
+
+// routeHolder is placed in the request context by the instrumented handler so that handlers further down
+// the chain, which run with a derived context, can report the matched route back up to it.
+type routeHolder struct {
+	route atomic.Value
+}
+
+func (rh *routeHolder) set(route string) {
+	rh.route.Store(route)
+}
+
+func (rh *routeHolder) get() string {
+	route, _ := rh.route.Load().(string)
+	return route
+}
+
+func routeHolderFromContext(ctx context.Context) *routeHolder {
+	rh, _ := ctx.Value(routeHolderKey{}).(*routeHolder)
+	return rh
+}
@@ -0,0 +1,181 @@
+package http
+
+import (
+	"context"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestServeMux_Route(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+
+	mux := NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		w.WriteHeader(stdhttp.StatusOK)
+	})
+	mux.HandleFunc("/named", func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		oteltrace.SpanFromContext(r.Context()).SetName("fetch-named")
+	})
+
+	srv, err := NewServer(":0", mux, WithServerTracerProvider(tp))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv.server.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/42", nil))
+	srv.server.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/named", nil))
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+
+	if spans[0].Name != "HTTP GET /users/{id}" {
+		t.Errorf("Expected span name HTTP GET /users/{id}, got %s", spans[0].Name)
+	}
+	if !hasAttr(spans[0].Attributes, semconv.HTTPRouteKey.String("/users/{id}")) {
+		t.Error("Missing http.route=/users/{id}")
+	}
+
+	if spans[1].Name != "fetch-named" {
+		t.Errorf("Expected the handler's span name to be kept, got %s", spans[1].Name)
+	}
+	if !hasAttr(spans[1].Attributes, semconv.HTTPRouteKey.String("/named")) {
+		t.Error("Missing http.route=/named")
+	}
+}
+
+func TestServer_StandardServeMuxRoute(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+
+	mux := stdhttp.NewServeMux()
+	mux.HandleFunc("POST example.com/items/{id}", func(w stdhttp.ResponseWriter, r *stdhttp.Request) {})
+
+	srv, err := NewServer(":0", mux, WithServerTracerProvider(tp))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv.server.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "http://example.com/items/1", nil))
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	if !hasAttr(spans[0].Attributes, semconv.HTTPRouteKey.String("/items/{id}")) {
+		t.Error("Missing http.route=/items/{id}")
+	}
+}
+
+func TestRouteFromPattern(t *testing.T) {
+	for pattern, want := range map[string]string{
+		"/users/{id}":                   "/users/{id}",
+		"GET /users/{id}":               "/users/{id}",
+		"example.com/":                  "/",
+		"DELETE  example.com/items/{$}": "/items/{$}",
+	} {
+		if got := routeFromPattern(pattern); got != want {
+			t.Errorf("routeFromPattern(%q) = %q, want %q", pattern, got, want)
+		}
+	}
+}
+
+func TestWithRoute(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	// A third-party router reports the route it matched.
+	router := stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		r = r.WithContext(WithRoute(r.Context(), "/users/{id}"))
+		if got := RouteFromContext(r.Context()); got != "/users/{id}" {
+			t.Errorf("expected the route to be in the context, got %q", got)
+		}
+	})
+	srv, err := NewServer(":0", router, WithServerTracerProvider(tp), WithServerMeterProvider(mp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.server.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/42", nil))
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	if spans[0].Name != "HTTP GET /users/{id}" {
+		t.Errorf("Expected span name HTTP GET /users/{id}, got %s", spans[0].Name)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if h, ok := m.Data.(metricdata.Histogram[float64]); ok && m.Name == "http.server.request.duration" {
+				attrs := h.DataPoints[0].Attributes.ToSlice()
+				if !hasAttr(attrs, semconv.HTTPRouteKey.String("/users/{id}")) {
+					t.Errorf("expected the duration to carry http.route, got %v", attrs)
+				}
+			}
+		}
+	}
+}
+
+func TestWithRoute_BeforeInstrumentation(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	srv, err := NewServer(":0", stdhttp.NotFoundHandler(),
+		WithServerTracerProvider(tp),
+		WithServerMeterProvider(mp),
+		WithRequestContextFunc(func(ctx context.Context, r *stdhttp.Request) context.Context {
+			return WithRoute(ctx, "/users/{id}")
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.server.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/42", nil))
+
+	if spans := exporter.GetSpans(); len(spans) != 1 || spans[0].Name != "HTTP GET /users/{id}" {
+		t.Errorf("Expected a single span named HTTP GET /users/{id}, got %v", spans)
+	}
+
+	// Active requests are labelled by the route, rather than by the raw path.
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	var found bool
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if sum, ok := m.Data.(metricdata.Sum[int64]); ok && m.Name == "http.server.active_requests" {
+				found = true
+				attrs := sum.DataPoints[0].Attributes.ToSlice()
+				if !hasAttr(attrs, semconv.HTTPRouteKey.String("/users/{id}")) {
+					t.Errorf("expected http.route, got %v", attrs)
+				}
+				if _, ok := sum.DataPoints[0].Attributes.Value(semconv.URLPathKey); ok {
+					t.Errorf("expected no url.path, got %v", attrs)
+				}
+			}
+		}
+	}
+	if !found {
+		t.Error("expected active requests to be recorded")
+	}
+}
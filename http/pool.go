@@ -0,0 +1,106 @@
+package http
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// trackedConn is a connection dialed by an instrumented transport. It counts itself in
+// http.client.open_connections, by host and by whether it is in use (active) or in the idle pool (idle), for
+// as long as it is open.
+//
+// The transport's pool isn't observable directly, so the state is inferred from the ClientTrace hooks of the
+// requests that use the connection: it is active from when it is dialed or handed to a request (GotConn), and
+// idle from when that request returns it to the pool (PutIdleConn). HTTP/2 connections are shared by many
+// requests and never returned to the pool, so they are always counted as active. Connections to a proxy are
+// counted against the proxy.
+type trackedConn struct {
+	net.Conn
+	counter metric.Int64UpDownCounter
+	ctx     context.Context
+	host    []attribute.KeyValue
+
+	mu     sync.Mutex
+	idle   bool
+	closed bool
+
+	// uses counts the requests the connection has been handed to, so that a request returning it to the pool
+	// after another has already been handed it doesn't mark it idle.
+	uses int64
+}
+
+// newTrackedConn tracks conn, dialed to addr, as an active connection.
+func newTrackedConn(
+	ctx context.Context, conn net.Conn, addr string, counter metric.Int64UpDownCounter,
+) *trackedConn {
+	c := &trackedConn{Conn: conn, counter: counter, ctx: ctx, host: hostAttrs(addr)}
+	c.add(1, false)
+	return c
+}
+
+// pooledConn returns the trackedConn beneath conn (e.g. beneath a TLS connection), or nil if it isn't one.
+func pooledConn(conn net.Conn) *trackedConn {
+	for {
+		switch c := conn.(type) {
+		case *trackedConn:
+			return c
+		case interface{ NetConn() net.Conn }:
+			conn = c.NetConn()
+		default:
+			return nil
+		}
+	}
+}
+
+// add adds n to the count of open connections in the given state.
+func (c *trackedConn) add(n int64, idle bool) {
+	state := semconv.HTTPConnectionStateActive
+	if idle {
+		state = semconv.HTTPConnectionStateIdle
+	}
+	attrs := append([]attribute.KeyValue{state}, c.host...)
+	c.counter.Add(c.ctx, n, metric.WithAttributes(attrs...))
+}
+
+// setIdle moves the connection between the active and idle counts. It must be called with mu held.
+func (c *trackedConn) setIdle(idle bool) {
+	if c.closed || c.idle == idle {
+		return
+	}
+	c.add(-1, c.idle)
+	c.add(1, idle)
+	c.idle = idle
+}
+
+// acquire records the connection being handed to a request, returning the use to pass to release.
+func (c *trackedConn) acquire() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.uses++
+	c.setIdle(false)
+	return c.uses
+}
+
+// release records the request that acquired the connection as use returning it to the idle pool.
+func (c *trackedConn) release(use int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.uses == use {
+		c.setIdle(true)
+	}
+}
+
+func (c *trackedConn) Close() error {
+	c.mu.Lock()
+	if !c.closed {
+		c.closed = true
+		c.add(-1, c.idle)
+	}
+	c.mu.Unlock()
+	return c.Conn.Close()
+}
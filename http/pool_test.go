@@ -0,0 +1,80 @@
+package http
+
+import (
+	"io"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+func TestClient_ConnectionPoolMetrics(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		if r.URL.Path == "/block" {
+			<-release
+		}
+	}))
+	defer ts.Close()
+
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	client, err := NewClient(WithClientMeterProvider(mp))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	get := func(path string) {
+		resp, err := client.Get(ts.URL + path)
+		if err != nil {
+			t.Errorf("Get failed: %v", err)
+			return
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}
+	// Connections are returned to the pool asynchronously, once the response body has been read.
+	waitFor := func(active, idle int64) {
+		t.Helper()
+		var gotActive, gotIdle int64
+		for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); {
+			gotActive = sumCounterWithAttr(t, reader, "http.client.open_connections", semconv.HTTPConnectionStateActive)
+			gotIdle = sumCounterWithAttr(t, reader, "http.client.open_connections", semconv.HTTPConnectionStateIdle)
+			if gotActive == active && gotIdle == idle {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("Expected %d active and %d idle connections, got %d and %d", active, idle, gotActive, gotIdle)
+	}
+
+	// A newly dialed connection is returned to the pool.
+	get("/")
+	waitFor(0, 1)
+
+	// The pooled connection is reused, and a concurrent request dials another.
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			get("/block")
+		}()
+	}
+	waitFor(2, 0)
+	close(release)
+	wg.Wait()
+	waitFor(0, 2)
+
+	// Closing idle connections removes them from the count.
+	client.CloseIdleConnections()
+	waitFor(0, 0)
+
+	if got := sumCounterWithAttr(t, reader, "http.client.open_connections", semconv.ServerAddress("127.0.0.1")); got != 0 {
+		t.Errorf("Expected open connections to be counted by server.address, got %d", got)
+	}
+}
@@ -0,0 +1,103 @@
+package http
+
+import (
+	"crypto/subtle"
+	"errors"
+	stdhttp "net/http"
+	"net/http/pprof"
+	"net/netip"
+)
+
+// PprofOption configures the access control of the handler returned by NewPprofHandler.
+type PprofOption func(*pprofAccess) error
+
+// pprofAccess holds the ways a request may be authorized to reach the profiling endpoints. A request is
+// allowed if it satisfies any of them.
+type pprofAccess struct {
+	loopback     bool
+	secretHeader string
+	secret       []byte
+	clientCert   bool
+}
+
+// WithPprofLoopback allows requests from loopback addresses. This is the default when no other access is
+// configured. It checks the address of the connection, so it must not be used behind a proxy running on the
+// same host.
+func WithPprofLoopback() PprofOption {
+	return func(a *pprofAccess) error {
+		a.loopback = true
+		return nil
+	}
+}
+
+// WithPprofSharedSecret allows requests that carry the secret in the named header.
+func WithPprofSharedSecret(header, secret string) PprofOption {
+	return func(a *pprofAccess) error {
+		if header == "" || secret == "" {
+			return errors.New("pprof shared secret header and secret must not be empty")
+		}
+		a.secretHeader = header
+		a.secret = []byte(secret)
+		return nil
+	}
+}
+
+// WithPprofClientCertificate allows requests made over TLS with a client certificate that the server
+// verified (mTLS). The server's tls.Config must request and verify client certificates.
+func WithPprofClientCertificate() PprofOption {
+	return func(a *pprofAccess) error {
+		a.clientCert = true
+		return nil
+	}
+}
+
+// NewPprofHandler returns a handler serving the net/http/pprof profiling endpoints under /debug/pprof/,
+// guarded by access control. Profiles expose memory contents and allow expensive work to be triggered, so
+// exposing them publicly is a security incident; by default only loopback requests are allowed.
+//
+// Unauthorized requests receive a 403 for any path, so that they cannot discover which endpoints exist.
+func NewPprofHandler(opts ...PprofOption) (stdhttp.Handler, error) {
+	a := &pprofAccess{}
+	for _, opt := range opts {
+		if err := opt(a); err != nil {
+			return nil, err
+		}
+	}
+	if a.secret == nil && !a.clientCert {
+		a.loopback = true
+	}
+
+	mux := stdhttp.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	return stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		if !a.allowed(r) {
+			stdhttp.Error(w, stdhttp.StatusText(stdhttp.StatusForbidden), stdhttp.StatusForbidden)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}), nil
+}
+
+// allowed reports whether the request satisfies any of the configured access methods.
+func (a *pprofAccess) allowed(r *stdhttp.Request) bool {
+	if a.loopback {
+		host, _ := splitHostPort(r.RemoteAddr)
+		if addr, err := netip.ParseAddr(host); err == nil && addr.Unmap().IsLoopback() {
+			return true
+		}
+	}
+	if a.secret != nil {
+		if got := r.Header.Get(a.secretHeader); got != "" && subtle.ConstantTimeCompare([]byte(got), a.secret) == 1 {
+			return true
+		}
+	}
+	if a.clientCert && r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		return true
+	}
+	return false
+}
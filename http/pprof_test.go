@@ -0,0 +1,89 @@
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewPprofHandler(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		opts       []PprofOption
+		remoteAddr string
+		header     string
+		tls        *tls.ConnectionState
+		path       string
+		want       int
+	}{
+		{name: "loopback by default", remoteAddr: "127.0.0.1:1234", path: "/debug/pprof/", want: stdhttp.StatusOK},
+		{name: "ipv6 loopback", remoteAddr: "[::1]:1234", path: "/debug/pprof/cmdline", want: stdhttp.StatusOK},
+		{name: "remote denied", remoteAddr: "203.0.113.7:1234", path: "/debug/pprof/", want: stdhttp.StatusForbidden},
+		{name: "unknown path denied", remoteAddr: "203.0.113.7:1234", path: "/nope", want: stdhttp.StatusForbidden},
+		{
+			name:       "shared secret",
+			opts:       []PprofOption{WithPprofSharedSecret("X-Pprof-Token", "s3cret")},
+			remoteAddr: "203.0.113.7:1234",
+			header:     "s3cret",
+			path:       "/debug/pprof/",
+			want:       stdhttp.StatusOK,
+		},
+		{
+			name:       "wrong shared secret",
+			opts:       []PprofOption{WithPprofSharedSecret("X-Pprof-Token", "s3cret")},
+			remoteAddr: "203.0.113.7:1234",
+			header:     "guess",
+			path:       "/debug/pprof/",
+			want:       stdhttp.StatusForbidden,
+		},
+		{
+			name:       "shared secret replaces loopback default",
+			opts:       []PprofOption{WithPprofSharedSecret("X-Pprof-Token", "s3cret")},
+			remoteAddr: "127.0.0.1:1234",
+			path:       "/debug/pprof/",
+			want:       stdhttp.StatusForbidden,
+		},
+		{
+			name:       "verified client certificate",
+			opts:       []PprofOption{WithPprofClientCertificate()},
+			remoteAddr: "203.0.113.7:1234",
+			tls:        &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}},
+			path:       "/debug/pprof/",
+			want:       stdhttp.StatusOK,
+		},
+		{
+			name:       "tls without client certificate",
+			opts:       []PprofOption{WithPprofClientCertificate()},
+			remoteAddr: "203.0.113.7:1234",
+			tls:        &tls.ConnectionState{},
+			path:       "/debug/pprof/",
+			want:       stdhttp.StatusForbidden,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := NewPprofHandler(tc.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			req := httptest.NewRequest("GET", tc.path, nil)
+			req.RemoteAddr = tc.remoteAddr
+			req.TLS = tc.tls
+			if tc.header != "" {
+				req.Header.Set("X-Pprof-Token", tc.header)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			if w.Code != tc.want {
+				t.Errorf("expected %d, got %d", tc.want, w.Code)
+			}
+		})
+	}
+
+	if _, err := NewPprofHandler(WithPprofSharedSecret("X-Pprof-Token", "")); err == nil {
+		t.Error("expected error for empty secret")
+	}
+}
@@ -0,0 +1,118 @@
+package http
+
+import (
+	"context"
+	"errors"
+	stdhttp "net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// ErrNoopPropagator is returned when a propagator is required but the effective propagator does nothing,
+// which means trace context is silently not propagated between services.
+var ErrNoopPropagator = errors.New("text map propagator is a no-op; configure one with otel.SetTextMapPropagator")
+
+// checkPropagator returns ErrNoopPropagator if the effective propagator would not propagate any fields.
+func checkPropagator(p propagation.TextMapPropagator) error {
+	if len(textMapPropagator(p).Fields()) == 0 {
+		return ErrNoopPropagator
+	}
+	return nil
+}
+
+// DefaultPropagator returns a propagator for W3C trace context (including tracestate) and W3C baggage, a
+// sensible choice for otel.SetTextMapPropagator, WithClientPropagator or WithServerPropagator.
+func DefaultPropagator() propagation.TextMapPropagator {
+	return propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+}
+
+// textMapPropagator returns p, or the global propagator if p is nil. The global propagator is looked up each
+// time, so that it may be set after clients and servers are constructed.
+func textMapPropagator(p propagation.TextMapPropagator) propagation.TextMapPropagator {
+	if p != nil {
+		return p
+	}
+	return otel.GetTextMapPropagator()
+}
+
+// WithClientPropagator makes the client inject trace context with p, rather than with the global propagator.
+func WithClientPropagator(p propagation.TextMapPropagator) ClientOption {
+	return func(c *stdhttp.Client) error {
+		it, ok := c.Transport.(*InstrumentedTransport)
+		if !ok {
+			return errors.New("client transport must be *InstrumentedTransport")
+		}
+		if p == nil {
+			return errors.New("propagator must not be nil")
+		}
+		it.propagator = p
+		return nil
+	}
+}
+
+// WithServerPropagator makes the server extract trace context with p, rather than with the global propagator.
+func WithServerPropagator(p propagation.TextMapPropagator) ServerOption {
+	return func(s *Server) error {
+		if p == nil {
+			return errors.New("propagator must not be nil")
+		}
+		s.propagator = p
+		return nil
+	}
+}
+
+// WithBaggageMember returns a copy of ctx whose W3C baggage includes the member key=value, replacing any
+// existing member with that key. Clients send the baggage of the request context to the server, provided their
+// propagator includes propagation.Baggage, as DefaultPropagator does.
+func WithBaggageMember(ctx context.Context, key, value string) (context.Context, error) {
+	member, err := baggage.NewMemberRaw(key, value)
+	if err != nil {
+		return nil, err
+	}
+	b, err := baggage.FromContext(ctx).SetMember(member)
+	if err != nil {
+		return nil, err
+	}
+	return baggage.ContextWithBaggage(ctx, b), nil
+}
+
+// WithBaggageAttributes copies the W3C baggage members with the given keys, such as a tenant ID, onto the
+// server span as attributes of the same name. Baggage is read from the request headers whether or not the
+// server's propagator includes propagation.Baggage, and is available to handlers through the request context.
+func WithBaggageAttributes(keys ...string) ServerOption {
+	return func(s *Server) error {
+		for _, key := range keys {
+			if key == "" {
+				return errors.New("baggage keys must not be empty")
+			}
+		}
+		s.baggageKeys = keys
+		return nil
+	}
+}
+
+// extractBaggage adds the baggage in the request headers to ctx, if baggage is copied onto the span.
+func (h *instrumentedHandler) extractBaggage(ctx context.Context, r *stdhttp.Request) context.Context {
+	if h.baggageKeys == nil {
+		return ctx
+	}
+	return propagation.Baggage{}.Extract(ctx, propagation.HeaderCarrier(r.Header))
+}
+
+// baggageAttrs returns the members of the baggage in ctx with the given keys as attributes.
+func baggageAttrs(ctx context.Context, keys []string) []attribute.KeyValue {
+	if keys == nil {
+		return nil
+	}
+	b := baggage.FromContext(ctx)
+	var attrs []attribute.KeyValue
+	for _, key := range keys {
+		if member := b.Member(key); member.Key() != "" {
+			attrs = append(attrs, attribute.String(key, member.Value()))
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,176 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestRequirePropagator(t *testing.T) {
+	original := otel.GetTextMapPropagator()
+	defer otel.SetTextMapPropagator(original)
+
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+
+	if _, err := NewClient(WithClientRequirePropagator()); !errors.Is(err, ErrNoopPropagator) {
+		t.Errorf("expected ErrNoopPropagator from NewClient, got %v", err)
+	}
+	if _, err := NewServer(":0", nil, WithServerRequirePropagator()); !errors.Is(err, ErrNoopPropagator) {
+		t.Errorf("expected ErrNoopPropagator from NewServer, got %v", err)
+	}
+
+	// The check is opt-in.
+	if _, err := NewClient(); err != nil {
+		t.Errorf("expected no error without the option, got %v", err)
+	}
+
+	// An injected propagator is checked in place of the global one.
+	if _, err := NewClient(WithClientRequirePropagator(), WithClientPropagator(DefaultPropagator())); err != nil {
+		t.Errorf("expected no error with an injected propagator, got %v", err)
+	}
+	_, err := NewServer(":0", nil, WithServerRequirePropagator(), WithServerPropagator(DefaultPropagator()))
+	if err != nil {
+		t.Errorf("expected no error with an injected propagator, got %v", err)
+	}
+
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	if _, err := NewClient(WithClientRequirePropagator()); err != nil {
+		t.Errorf("expected no error with a propagator configured, got %v", err)
+	}
+	if _, err := NewServer(":0", nil, WithServerRequirePropagator()); err != nil {
+		t.Errorf("expected no error with a propagator configured, got %v", err)
+	}
+}
+
+func TestBaggageAttributes(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+
+	var handlerTenant string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerTenant = baggage.FromContext(r.Context()).Member("tenant.id").Value()
+	})
+	srv, err := NewServer(":0", handler, WithServerTracerProvider(tp), WithBaggageAttributes("tenant.id", "absent"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv.server.Handler)
+	defer ts.Close()
+
+	client, err := NewClient(WithClientPropagator(DefaultPropagator()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	ctx, err := WithBaggageMember(context.Background(), "tenant.id", "acme")
+	if err != nil {
+		t.Fatalf("WithBaggageMember failed: %v", err)
+	}
+	ctx, err = WithBaggageMember(ctx, "region", "eu")
+	if err != nil {
+		t.Fatalf("WithBaggageMember failed: %v", err)
+	}
+	req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do failed: %v", err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	if handlerTenant != "acme" {
+		t.Errorf("Expected the handler to see tenant.id=acme in its baggage, got %q", handlerTenant)
+	}
+	var server *tracetest.SpanStub
+	for _, s := range exporter.GetSpans() {
+		if s.SpanKind == oteltrace.SpanKindServer {
+			server = &s
+		}
+	}
+	if server == nil {
+		t.Fatal("Expected a server span")
+	}
+	if !hasAttr(server.Attributes, attribute.String("tenant.id", "acme")) {
+		t.Errorf("Expected tenant.id=acme on the server span, got %v", server.Attributes)
+	}
+	for _, kv := range server.Attributes {
+		if kv.Key == "region" || kv.Key == "absent" {
+			t.Errorf("Unexpected attribute %s", kv.Key)
+		}
+	}
+}
+
+func TestWithBaggageMember_Invalid(t *testing.T) {
+	if _, err := WithBaggageMember(context.Background(), "", "value"); err == nil {
+		t.Error("Expected an error for an empty key")
+	}
+	if _, err := NewServer(":0", nil, WithBaggageAttributes("")); err == nil {
+		t.Error("Expected an error for an empty baggage key")
+	}
+}
+
+func TestWithPropagator(t *testing.T) {
+	original := otel.GetTextMapPropagator()
+	defer otel.SetTextMapPropagator(original)
+	// The global propagator does nothing, so trace context only flows through the injected propagators.
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+
+	var traceparent string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("Traceparent")
+	})
+	srv, err := NewServer(":0", handler, WithServerTracerProvider(tp), WithServerPropagator(DefaultPropagator()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv.server.Handler)
+	defer ts.Close()
+
+	client, err := NewClient(WithClientPropagator(DefaultPropagator()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "parent-span")
+	req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	span.End()
+
+	if traceparent == "" {
+		t.Fatal("Expected the client to inject traceparent")
+	}
+	spans := exporter.GetSpans()
+	server := spans[0]
+	if server.SpanKind != oteltrace.SpanKindServer {
+		t.Fatalf("Expected the server span to end first, got %s", server.Name)
+	}
+	if server.Parent.SpanID() != span.SpanContext().SpanID() {
+		t.Error("Expected the server span to be a child of the client's span")
+	}
+
+	if _, err := NewClient(WithClientPropagator(nil)); err == nil {
+		t.Error("Expected an error for a nil client propagator")
+	}
+	if _, err := NewServer(":0", nil, WithServerPropagator(nil)); err == nil {
+		t.Error("Expected an error for a nil server propagator")
+	}
+}
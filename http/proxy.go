@@ -0,0 +1,76 @@
+package http
+
+import (
+	"errors"
+	stdhttp "net/http"
+	"net/http/httputil"
+	"net/url"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// defaultProxyClientOptions are the defaults for the upstream transport of NewReverseProxy. They keep the
+// client's connection timeouts, but don't bound how long the upstream takes to respond, which is left to the
+// inbound request: its context, and the server's timeouts.
+var defaultProxyClientOptions = []ClientOption{
+	WithConnectTimeout(500 * time.Millisecond),
+	WithTLSHandshakeTimeout(500 * time.Millisecond),
+	WithMaxIdleConns(100),
+	WithIdleConnTimeout(90 * time.Second),
+	WithExpectContinueTimeout(1 * time.Second),
+}
+
+// NewReverseProxy returns a reverse proxy to target whose upstream requests are made with the transport of
+// a client built with opts. Serve it with NewServer to instrument inbound requests: each proxied request then
+// has a server span and a child client span for the upstream request, and trace context is propagated
+// upstream.
+//
+// The client has defaults suited to a proxy, rather than NewClient's: connecting (WithConnectTimeout) and the
+// TLS handshake (WithTLSHandshakeTimeout) are limited to 500ms each, but the upstream's response is not, so
+// that slow upstreams aren't cut off with a 502. It is bounded instead by the server's timeouts (such as
+// WithWriteTimeout or WithServerHandlerTimeout), and can be limited with WithResponseHeaderTimeout.
+// Client.Timeout (WithTimeout) does not apply, as the response is streamed.
+//
+// Hop-by-hop headers are removed, and X-Forwarded-For, X-Forwarded-Host and X-Forwarded-Proto are set from
+// the inbound request, replacing any sent by the client.
+func NewReverseProxy(target *url.URL, opts ...ClientOption) (*httputil.ReverseProxy, error) {
+	if target == nil {
+		return nil, errors.New("reverse proxy target must not be nil")
+	}
+
+	c, err := NewClientWithDefaults(defaultProxyClientOptions, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	tracer := otel.GetTracerProvider().Tracer(instrumentationName)
+	var spanName func(*stdhttp.Request) string
+	if it, ok := c.Transport.(*InstrumentedTransport); ok {
+		tracer, spanName = it.tracer(), it.spanName
+	}
+
+	return &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			pr.SetXForwarded()
+		},
+		Transport: &proxyTransport{base: c.Transport, tracer: tracer, spanName: spanName},
+	}, nil
+}
+
+// proxyTransport starts a client span for each upstream request. The client transport enriches the span in
+// the request context rather than starting its own, which in a proxy would be the inbound server span.
+type proxyTransport struct {
+	base     stdhttp.RoundTripper
+	tracer   trace.Tracer
+	spanName func(*stdhttp.Request) string
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *proxyTransport) RoundTrip(req *stdhttp.Request) (*stdhttp.Response, error) {
+	ctx, span := t.tracer.Start(req.Context(), clientSpanName(t.spanName, req), trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+	return t.base.RoundTrip(req.WithContext(ctx))
+}
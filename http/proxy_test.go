@@ -0,0 +1,120 @@
+package http
+
+import (
+	"context"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestNewReverseProxy(t *testing.T) {
+	original := otel.GetTextMapPropagator()
+	defer otel.SetTextMapPropagator(original)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	upstream := make(chan *stdhttp.Request, 1)
+	backend := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		upstream <- r
+		w.WriteHeader(stdhttp.StatusTeapot)
+	}))
+	defer backend.Close()
+	target, _ := url.Parse(backend.URL)
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+
+	proxy, err := NewReverseProxy(target, WithClientTracerProvider(tp))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	srv, err := NewServer(":0", proxy, WithServerTracerProvider(tp))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	front := httptest.NewServer(srv.server.Handler)
+	defer front.Close()
+
+	req, _ := stdhttp.NewRequestWithContext(context.Background(), stdhttp.MethodGet, front.URL+"/path", nil)
+	req.Header.Set("Connection", "X-Hop")
+	req.Header.Set("X-Hop", "dropped")
+	req.Header.Set("X-Forwarded-For", "192.0.2.1")
+	resp, err := stdhttp.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != stdhttp.StatusTeapot {
+		t.Errorf("expected the upstream status, got %d", resp.StatusCode)
+	}
+
+	got := <-upstream
+	if got.URL.Path != "/path" {
+		t.Errorf("expected path /path upstream, got %s", got.URL.Path)
+	}
+	if got.Header.Get("X-Hop") != "" {
+		t.Error("expected hop-by-hop headers to be stripped")
+	}
+	if xff := got.Header.Get("X-Forwarded-For"); xff != "127.0.0.1" {
+		t.Errorf("expected X-Forwarded-For to be replaced with the inbound peer, got %q", xff)
+	}
+
+	spans := exporter.GetSpans()
+	var server, client *tracetest.SpanStub
+	for i := range spans {
+		switch spans[i].SpanKind {
+		case oteltrace.SpanKindServer:
+			server = &spans[i]
+		case oteltrace.SpanKindClient:
+			client = &spans[i]
+		}
+	}
+	if server == nil || client == nil {
+		t.Fatalf("expected a server and a client span, got %d spans", len(spans))
+	}
+	if client.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Error("expected the client span to be a child of the server span")
+	}
+
+	propagated := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(got.Header))
+	if oteltrace.SpanContextFromContext(propagated).SpanID() != client.SpanContext.SpanID() {
+		t.Error("expected the client span to be propagated upstream")
+	}
+}
+
+func TestNewReverseProxy_Defaults(t *testing.T) {
+	target, _ := url.Parse("http://upstream.example")
+	responseHeaderTimeout := func(p *httputil.ReverseProxy) time.Duration {
+		t.Helper()
+		tr, err := baseTransport(p.Transport.(*proxyTransport).base.(*InstrumentedTransport).Base)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return tr.ResponseHeaderTimeout
+	}
+
+	// Slow upstreams aren't cut off by NewClient's response header timeout.
+	proxy, err := NewReverseProxy(target)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := responseHeaderTimeout(proxy); got != 0 {
+		t.Errorf("expected no response header timeout, got %s", got)
+	}
+
+	proxy, err = NewReverseProxy(target, WithResponseHeaderTimeout(10*time.Second))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := responseHeaderTimeout(proxy); got != 10*time.Second {
+		t.Errorf("expected the configured response header timeout, got %s", got)
+	}
+}
@@ -0,0 +1,189 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"math"
+	stdhttp "net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/metric"
+	"golang.org/x/time/rate"
+)
+
+// WithClientRateLimit limits the client to r requests per second, with bursts of up to burst requests.
+// Requests over the limit wait for a token, for as long as their context allows, rather than failing. Every
+// attempt counts, including retries.
+//
+// The limiter's available tokens are reported by the http.client.rate_limit.tokens gauge, and requests
+// that had to wait are counted in http.client.rate_limit.throttled. The wait is recorded as an
+// http.client.throttled event on the request span.
+func WithClientRateLimit(r rate.Limit, burst int) ClientOption {
+	return func(c *stdhttp.Client) error {
+		if r <= 0 {
+			return errors.New("rate limit must be positive")
+		}
+		if burst < 1 {
+			return errors.New("rate limit burst must be at least 1")
+		}
+
+		rl := &rateLimitTransport{limiter: rate.NewLimiter(r, burst)}
+		wrapInnermost(c, func(base stdhttp.RoundTripper) stdhttp.RoundTripper {
+			rl.base = base
+			return rl
+		})
+		return nil
+	}
+}
+
+// rateLimitTransport is a RoundTripper that waits for a token before each request.
+type rateLimitTransport struct {
+	base    stdhttp.RoundTripper
+	limiter *rate.Limiter
+
+	mThrottled metric.Int64Counter
+
+	// registration is the gauge callback, replaced whenever the transport is instrumented again.
+	registration metric.Registration
+}
+
+func (t *rateLimitTransport) unwrap() stdhttp.RoundTripper {
+	return t.base
+}
+
+func (t *rateLimitTransport) setBase(base stdhttp.RoundTripper) {
+	t.base = base
+}
+
+func (t *rateLimitTransport) resilience() {}
+
+func (t *rateLimitTransport) instrument(meter metric.Meter) error {
+	var err error
+	t.mThrottled, err = meter.Int64Counter("http.client.rate_limit.throttled")
+	if err != nil {
+		return err
+	}
+
+	tokens, err := meter.Float64ObservableGauge("http.client.rate_limit.tokens")
+	if err != nil {
+		return err
+	}
+	if t.registration != nil {
+		if err := t.registration.Unregister(); err != nil {
+			return err
+		}
+	}
+	t.registration, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		o.ObserveFloat64(tokens, t.limiter.Tokens())
+		return nil
+	}, tokens)
+	return err
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *rateLimitTransport) RoundTrip(req *stdhttp.Request) (*stdhttp.Response, error) {
+	if !t.limiter.Allow() {
+		ctx := req.Context()
+		if t.mThrottled != nil {
+			t.mThrottled.Add(ctx, 1, metric.WithAttributes(clientRequestAttrs(req)...))
+		}
+		start := time.Now()
+		err := t.limiter.Wait(ctx)
+		recordThrottled(ctx, throttleRateLimit, time.Since(start))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return t.base.RoundTrip(req)
+}
+
+// WithServerRateLimit limits each client to r requests per second, with bursts of up to burst requests.
+// Requests over the limit are rejected with a 429 Too Many Requests response, with a Retry-After header
+// stating when a request will next be allowed. Health checks (see WithHealthEndpoints) are not limited.
+//
+// Clients are told apart by key, which returns the key a request is limited under. If key is nil, requests
+// are limited by client address, taken from X-Forwarded-For for requests from a trusted proxy (see
+// WithTrustedProxies). A client's limiter is discarded once it has been idle long enough to refill, so
+// memory is only held for recently active clients.
+//
+// Rejected requests are recorded with http.server.rate_limited=true on the span, and counted in
+// http.server.rate_limited.
+func WithServerRateLimit(r rate.Limit, burst int, key func(*stdhttp.Request) string) ServerOption {
+	return func(s *Server) error {
+		if r <= 0 {
+			return errors.New("rate limit must be positive")
+		}
+		if burst < 1 {
+			return errors.New("rate limit burst must be at least 1")
+		}
+		s.rateLimit = &keyedRateLimiter{
+			limit:    r,
+			burst:    burst,
+			key:      key,
+			now:      time.Now,
+			limiters: make(map[string]*rate.Limiter),
+		}
+		return nil
+	}
+}
+
+// keyedRateLimiter keeps a token bucket rate limiter for each key.
+type keyedRateLimiter struct {
+	limit rate.Limit
+	burst int
+	key   func(*stdhttp.Request) string
+
+	// now returns the current time, and is replaced in tests.
+	now func() time.Time
+
+	mu        sync.Mutex
+	limiters  map[string]*rate.Limiter
+	lastSweep time.Time
+}
+
+// reject reports whether the request is over its key's limit and, if it is, how long until the next
+// request will be allowed. A nil limiter rejects nothing.
+func (l *keyedRateLimiter) reject(r *stdhttp.Request) (time.Duration, bool) {
+	if l == nil {
+		return 0, false
+	}
+	key := l.key(r)
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+	limiter, ok := l.limiters[key]
+	if !ok {
+		limiter = rate.NewLimiter(l.limit, l.burst)
+		l.limiters[key] = limiter
+	}
+	if limiter.AllowN(now, 1) {
+		return 0, false
+	}
+	wait := time.Duration((1 - limiter.TokensAt(now)) / float64(l.limit) * float64(time.Second))
+	return wait, true
+}
+
+// sweep discards the limiters whose buckets have refilled, at most once per refill period. A full bucket
+// behaves exactly as a new one, so discarding it loses nothing.
+func (l *keyedRateLimiter) sweep(now time.Time) {
+	refill := time.Duration(float64(l.burst) / float64(l.limit) * float64(time.Second))
+	if now.Sub(l.lastSweep) < refill {
+		return
+	}
+	l.lastSweep = now
+	for key, limiter := range l.limiters {
+		if limiter.TokensAt(now) >= float64(l.burst) {
+			delete(l.limiters, key)
+		}
+	}
+}
+
+// writeRateLimited responds with a 429, with a Retry-After header of the wait rounded up to whole seconds.
+func writeRateLimited(w stdhttp.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.FormatInt(int64(math.Ceil(max(wait.Seconds(), 1))), 10))
+	stdhttp.Error(w, stdhttp.StatusText(stdhttp.StatusTooManyRequests), stdhttp.StatusTooManyRequests)
+}
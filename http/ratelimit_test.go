@@ -0,0 +1,239 @@
+package http
+
+import (
+	"context"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"golang.org/x/time/rate"
+)
+
+func TestWithClientRateLimit(t *testing.T) {
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {}))
+	defer ts.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	c, err := NewClient(WithClientTracerProvider(tp), WithClientMeterProvider(mp), WithClientRateLimit(20, 1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Waits are recorded on the request span, which is the caller's.
+	ctx, span := tp.Tracer("test").Start(context.Background(), "parent")
+	start := time.Now()
+	for range 2 {
+		req, _ := stdhttp.NewRequestWithContext(ctx, stdhttp.MethodGet, ts.URL, nil)
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		_ = resp.Body.Close()
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("expected the second request to wait for a token, took %v", elapsed)
+	}
+
+	if got := sumCounter(t, reader, "http.client.rate_limit.throttled"); got != 1 {
+		t.Errorf("expected 1 throttled request, got %d", got)
+	}
+	span.End()
+	if got := throttledEvents(exporter.GetSpans(), "rate_limit"); got != 1 {
+		t.Errorf("expected 1 throttled span event, got %d", got)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	var observed bool
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if g, ok := m.Data.(metricdata.Gauge[float64]); ok && m.Name == "http.client.rate_limit.tokens" {
+				observed = len(g.DataPoints) == 1 && g.DataPoints[0].Value <= 1
+			}
+		}
+	}
+	if !observed {
+		t.Error("expected the available tokens to be observed")
+	}
+}
+
+func TestWithClientRateLimit_ContextCancelled(t *testing.T) {
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {}))
+	defer ts.Close()
+
+	c, err := NewClient(WithClientRateLimit(0.1, 1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resp, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, _ := stdhttp.NewRequestWithContext(ctx, stdhttp.MethodGet, ts.URL, nil)
+	start := time.Now()
+	if _, err := c.Do(req); err == nil {
+		t.Fatal("expected the throttled request to fail with its context")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the wait to respect the context, took %v", elapsed)
+	}
+}
+
+func TestWithServerRateLimit(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	s, err := NewServer(":0", stdhttp.NotFoundHandler(),
+		WithServerTracerProvider(tp),
+		WithServerMeterProvider(mp),
+		WithServerRateLimit(1, 2, func(r *stdhttp.Request) string { return r.Header.Get("X-Tenant") }),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := time.Now()
+	s.rateLimit.now = func() time.Time { return now }
+
+	get := func(tenant string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(stdhttp.MethodGet, "/", nil)
+		req.Header.Set("X-Tenant", tenant)
+		rec := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// The burst is allowed, and the next request is rejected.
+	for range 2 {
+		if rec := get("a"); rec.Code != stdhttp.StatusNotFound {
+			t.Fatalf("expected the request to be served, got %d", rec.Code)
+		}
+	}
+	rec := get("a")
+	if rec.Code != stdhttp.StatusTooManyRequests {
+		t.Fatalf("expected a 429, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("expected Retry-After: 1, got %q", got)
+	}
+	spans := exporter.GetSpans()
+	if !hasAttr(spans[len(spans)-1].Attributes, attribute.Bool("http.server.rate_limited", true)) {
+		t.Error("expected the rejected request to be recorded on the span")
+	}
+	if got := sumCounter(t, reader, "http.server.rate_limited"); got != 1 {
+		t.Errorf("expected 1 rejected request counted, got %d", got)
+	}
+
+	// Other keys have limits of their own.
+	if rec := get("b"); rec.Code != stdhttp.StatusNotFound {
+		t.Errorf("expected another key to be served, got %d", rec.Code)
+	}
+
+	// Tokens are replenished over time.
+	now = now.Add(time.Second)
+	if rec := get("a"); rec.Code != stdhttp.StatusNotFound {
+		t.Errorf("expected the request to be served once a token was replenished, got %d", rec.Code)
+	}
+
+	for _, opt := range []ServerOption{
+		WithServerRateLimit(0, 1, nil), WithServerRateLimit(1, 0, nil),
+	} {
+		if _, err := NewServer(":0", nil, opt); err == nil {
+			t.Error("expected an error for an invalid rate limit")
+		}
+	}
+}
+
+func TestWithServerRateLimit_ClientAddress(t *testing.T) {
+	s, err := NewServer(":0", stdhttp.NotFoundHandler(),
+		WithServerRateLimit(1, 1, nil),
+		WithTrustedProxies("10.0.0.1"),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	get := func(remoteAddr, forwardedFor string) int {
+		req := httptest.NewRequest(stdhttp.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		rec := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Connections from the same address share a limit, whatever their port.
+	if code := get("192.0.2.1:1000", ""); code != stdhttp.StatusNotFound {
+		t.Fatalf("expected the request to be served, got %d", code)
+	}
+	if code := get("192.0.2.1:2000", ""); code != stdhttp.StatusTooManyRequests {
+		t.Errorf("expected the same address to be limited, got %d", code)
+	}
+
+	// Requests through a trusted proxy are limited by the forwarded address.
+	if code := get("10.0.0.1:1000", "192.0.2.2"); code != stdhttp.StatusNotFound {
+		t.Errorf("expected a forwarded client to be served, got %d", code)
+	}
+	if code := get("10.0.0.1:1000", "192.0.2.3"); code != stdhttp.StatusNotFound {
+		t.Errorf("expected another forwarded client to be served, got %d", code)
+	}
+}
+
+func TestKeyedRateLimiter_Eviction(t *testing.T) {
+	now := time.Now()
+	l := &keyedRateLimiter{
+		limit:    rate.Limit(10),
+		burst:    10,
+		key:      func(r *stdhttp.Request) string { return r.Header.Get("X-Tenant") },
+		now:      func() time.Time { return now },
+		limiters: make(map[string]*rate.Limiter),
+	}
+	reject := func(tenant string) {
+		req := httptest.NewRequest(stdhttp.MethodGet, "/", nil)
+		req.Header.Set("X-Tenant", tenant)
+		l.reject(req)
+	}
+
+	for _, tenant := range []string{"a", "b", "c"} {
+		reject(tenant)
+	}
+	if len(l.limiters) != 3 {
+		t.Fatalf("expected a limiter for each key, got %d", len(l.limiters))
+	}
+
+	// Limiters that have refilled are discarded, and those still refilling are kept.
+	now = now.Add(500 * time.Millisecond)
+	for range 10 {
+		reject("a")
+	}
+	now = now.Add(500 * time.Millisecond)
+	reject("b")
+	if len(l.limiters) != 2 {
+		t.Errorf("expected idle limiters to be discarded, got %d", len(l.limiters))
+	}
+	if _, ok := l.limiters["a"]; !ok {
+		t.Error("expected the limiter still refilling to be kept")
+	}
+	if _, ok := l.limiters["c"]; ok {
+		t.Error("expected the idle limiter to be discarded")
+	}
+}
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	stdhttp "net/http"
+)
+
+// WithCheckRedirect sets the client's redirect policy (Client.CheckRedirect), which is called before each
+// redirect is followed, as described by net/http. The redirects the policy allows are still recorded as
+// http.redirect span events and counted in http.client.redirects, and trace context is injected into each
+// redirected request, as it is into the first.
+func WithCheckRedirect(policy func(req *stdhttp.Request, via []*stdhttp.Request) error) ClientOption {
+	return func(c *stdhttp.Client) error {
+		if policy == nil {
+			return errors.New("redirect policy must not be nil")
+		}
+		c.CheckRedirect = policy
+		return nil
+	}
+}
+
+// WithMaxRedirects follows at most n redirects, in place of net/http's default of 10. Requests redirected
+// more often than that fail. Zero makes any redirect an error; to return redirect responses to the caller
+// instead, use WithNoRedirects.
+func WithMaxRedirects(n int) ClientOption {
+	return func(c *stdhttp.Client) error {
+		if n < 0 {
+			return errors.New("max redirects must not be negative")
+		}
+		c.CheckRedirect = func(_ *stdhttp.Request, via []*stdhttp.Request) error {
+			if len(via) > n {
+				return fmt.Errorf("stopped after %d redirects", n)
+			}
+			return nil
+		}
+		return nil
+	}
+}
+
+// WithNoRedirects doesn't follow redirects, returning the redirect response itself (with its body unread)
+// to the caller, as http.ErrUseLastResponse does.
+func WithNoRedirects() ClientOption {
+	return func(c *stdhttp.Client) error {
+		c.CheckRedirect = func(*stdhttp.Request, []*stdhttp.Request) error {
+			return stdhttp.ErrUseLastResponse
+		}
+		return nil
+	}
+}
@@ -0,0 +1,176 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+// newRedirectServer returns a server that redirects /n to /n-1 until /0, recording the traceparent header of
+// each request it receives.
+func newRedirectServer(t *testing.T) (*httptest.Server, func() []string) {
+	t.Helper()
+	var (
+		mu           sync.Mutex
+		traceparents []string
+	)
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		mu.Lock()
+		traceparents = append(traceparents, r.Header.Get("Traceparent"))
+		mu.Unlock()
+
+		n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		if n > 0 {
+			stdhttp.Redirect(w, r, fmt.Sprintf("/%d", n-1), stdhttp.StatusFound)
+			return
+		}
+		w.WriteHeader(stdhttp.StatusOK)
+	}))
+	t.Cleanup(ts.Close)
+	return ts, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return traceparents
+	}
+}
+
+func TestWithMaxRedirects(t *testing.T) {
+	tests := []struct {
+		name      string
+		max       int
+		redirects int
+		wantErr   bool
+	}{
+		{name: "under the limit", max: 3, redirects: 2},
+		{name: "at the limit", max: 3, redirects: 3},
+		{name: "over the limit", max: 3, redirects: 4, wantErr: true},
+		{name: "none allowed", max: 0, redirects: 1, wantErr: true},
+		{name: "none needed", max: 0, redirects: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, _ := newRedirectServer(t)
+			reader := sdkmetric.NewManualReader()
+			mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+			c, err := NewClient(WithMaxRedirects(tt.max), WithClientMeterProvider(mp))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			resp, err := c.Get(fmt.Sprintf("%s/%d", ts.URL, tt.redirects))
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), fmt.Sprintf("stopped after %d redirects", tt.max)) {
+					t.Fatalf("expected the redirect limit error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode != stdhttp.StatusOK {
+				t.Errorf("expected status 200, got %d", resp.StatusCode)
+			}
+			if got := sumCounter(t, reader, "http.client.redirects"); got != int64(tt.redirects) {
+				t.Errorf("expected %d redirects followed, got %d", tt.redirects, got)
+			}
+		})
+	}
+}
+
+func TestWithMaxRedirects_Negative(t *testing.T) {
+	if _, err := NewClient(WithMaxRedirects(-1)); err == nil {
+		t.Error("expected an error for negative max redirects")
+	}
+}
+
+func TestWithNoRedirects(t *testing.T) {
+	ts, received := newRedirectServer(t)
+	c, err := NewClient(WithNoRedirects())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resp, err := c.Get(ts.URL + "/2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != stdhttp.StatusFound || resp.Header.Get("Location") != "/1" {
+		t.Errorf("expected the redirect response, got %d to %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if got := len(received()); got != 1 {
+		t.Errorf("expected 1 request, got %d", got)
+	}
+}
+
+func TestWithCheckRedirect(t *testing.T) {
+	ts, _ := newRedirectServer(t)
+	errStop := errors.New("stop")
+	var calls int
+	c, err := NewClient(WithCheckRedirect(func(req *stdhttp.Request, _ []*stdhttp.Request) error {
+		calls++
+		if req.URL.Path == "/0" {
+			return errStop
+		}
+		return nil
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := c.Get(ts.URL + "/2"); !errors.Is(err, errStop) {
+		t.Errorf("expected the policy's error, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected the policy to be called for each redirect, got %d calls", calls)
+	}
+
+	if _, err := NewClient(WithCheckRedirect(nil)); err == nil {
+		t.Error("expected an error for a nil policy")
+	}
+}
+
+func TestRedirects_PropagateTraceContext(t *testing.T) {
+	ts, received := newRedirectServer(t)
+	tp := trace.NewTracerProvider()
+	c, err := NewClient(
+		WithClientTracerProvider(tp),
+		WithClientPropagator(propagation.TraceContext{}),
+		WithMaxRedirects(5),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "parent")
+	defer span.End()
+	req, _ := stdhttp.NewRequestWithContext(ctx, stdhttp.MethodGet, ts.URL+"/3", nil)
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	traceparents := received()
+	if len(traceparents) != 4 {
+		t.Fatalf("expected 4 requests, got %d", len(traceparents))
+	}
+	traceID := span.SpanContext().TraceID().String()
+	for i, header := range traceparents {
+		if !strings.Contains(header, traceID) {
+			t.Errorf("expected request %d to carry trace %s, got %q", i, traceID, header)
+		}
+	}
+}
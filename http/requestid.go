@@ -0,0 +1,123 @@
+package http
+
+import (
+	"context"
+	"errors"
+	stdhttp "net/http"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// maxRequestIDLength bounds the length of incoming request IDs that are accepted.
+const maxRequestIDLength = 128
+
+// requestIDAttr is the span attribute the request ID is recorded as.
+const requestIDAttr = "http.request.id"
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the request ID id. Clients configured with WithClientRequestID
+// send it with requests made with the context.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID set by WithServerRequestID or WithRequestID, or "" if there is
+// none.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// WithServerRequestID gives every request an ID, for correlating logs whether or not the request is traced.
+// The ID is taken from the header (such as X-Request-Id) if the request has one, or else generated as a
+// random UUID. Incoming IDs longer than 128 characters or containing anything other than printable ASCII
+// are replaced, so that clients can't inject content into logs.
+//
+// The ID is available to handlers with RequestIDFromContext, echoed in the response header, recorded on the
+// span as http.request.id, and included as request_id in the logger from LoggerFromContext.
+func WithServerRequestID(header string) ServerOption {
+	return func(s *Server) error {
+		if header == "" {
+			return errors.New("request ID header must not be empty")
+		}
+		s.requestIDHeader = stdhttp.CanonicalHeaderKey(header)
+		return nil
+	}
+}
+
+// requestID returns the request's ID from the header, or a new one if it has none or it isn't valid.
+func requestID(r *stdhttp.Request, header string) string {
+	if id := r.Header.Get(header); validRequestID(id) {
+		return id
+	}
+	return uuid.NewString()
+}
+
+// validRequestID reports whether id is a non-empty, bounded string of printable ASCII.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// requestIDAttrs returns the http.request.id attribute for the request ID in ctx, if there is one.
+func requestIDAttrs(ctx context.Context) []attribute.KeyValue {
+	if id := RequestIDFromContext(ctx); id != "" {
+		return []attribute.KeyValue{attribute.String(requestIDAttr, id)}
+	}
+	return nil
+}
+
+// WithClientRequestID sends the request ID from the request's context (see RequestIDFromContext) in the
+// header, so that it follows a request across services. Requests that already set the header, or whose
+// context has no request ID, are sent as they are.
+func WithClientRequestID(header string) ClientOption {
+	return func(c *stdhttp.Client) error {
+		if header == "" {
+			return errors.New("request ID header must not be empty")
+		}
+		header = stdhttp.CanonicalHeaderKey(header)
+		wrapTransport(c, func(base stdhttp.RoundTripper) stdhttp.RoundTripper {
+			return &requestIDTransport{base: base, header: header}
+		})
+		return nil
+	}
+}
+
+// requestIDTransport is a RoundTripper that sends the request ID from the request's context in a header.
+type requestIDTransport struct {
+	base   stdhttp.RoundTripper
+	header string
+}
+
+func (t *requestIDTransport) unwrap() stdhttp.RoundTripper {
+	return t.base
+}
+
+func (t *requestIDTransport) setBase(base stdhttp.RoundTripper) {
+	t.base = base
+}
+
+// RoundTrip implements http.RoundTripper. The request is copied before the header is set, as a
+// RoundTripper must not modify it.
+func (t *requestIDTransport) RoundTrip(req *stdhttp.Request) (*stdhttp.Response, error) {
+	id := RequestIDFromContext(req.Context())
+	if id == "" || req.Header.Get(t.header) != "" {
+		return t.base.RoundTrip(req)
+	}
+	next := *req
+	next.Header = req.Header.Clone()
+	if next.Header == nil {
+		next.Header = make(stdhttp.Header, 1)
+	}
+	next.Header.Set(t.header, id)
+	return t.base.RoundTrip(&next)
+}
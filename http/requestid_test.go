@@ -0,0 +1,128 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestWithServerRequestID(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+
+	var buf bytes.Buffer
+	var got string
+	s, err := NewServer(":0", stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		got = RequestIDFromContext(r.Context())
+		LoggerFromContext(r.Context()).Info("handled")
+	}),
+		WithServerTracerProvider(tp),
+		WithRequestLogger(slog.New(slog.NewJSONHandler(&buf, nil))),
+		WithServerRequestID("x-request-id"),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		incoming string
+		generate bool
+	}{
+		{name: "pass-through", incoming: "req-123"},
+		{name: "generated", generate: true},
+		{name: "too long", incoming: strings.Repeat("a", maxRequestIDLength+1), generate: true},
+		{name: "not printable", incoming: "req\x1b[31m", generate: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			exporter.Reset()
+			buf.Reset()
+
+			req := httptest.NewRequest(stdhttp.MethodGet, "/", nil)
+			if tc.incoming != "" {
+				req.Header.Set("X-Request-Id", tc.incoming)
+			}
+			rec := httptest.NewRecorder()
+			s.server.Handler.ServeHTTP(rec, req)
+
+			if tc.generate {
+				if _, err := uuid.Parse(got); err != nil {
+					t.Errorf("expected a generated UUID, got %q", got)
+				}
+			} else if got != tc.incoming {
+				t.Errorf("expected the incoming request ID %q, got %q", tc.incoming, got)
+			}
+			if echoed := rec.Header().Get("X-Request-Id"); echoed != got {
+				t.Errorf("expected the request ID to be echoed, got %q", echoed)
+			}
+			if !hasAttr(exporter.GetSpans()[0].Attributes, attribute.String("http.request.id", got)) {
+				t.Error("expected the request ID on the span")
+			}
+			var record map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &record); err != nil || record["request_id"] != got {
+				t.Errorf("expected request_id in the log record, got %q", buf.String())
+			}
+		})
+	}
+
+	if _, err := NewServer(":0", nil, WithServerRequestID("")); err == nil {
+		t.Error("expected an error for an empty header")
+	}
+}
+
+func TestWithClientRequestID(t *testing.T) {
+	var got string
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		got = r.Header.Get("X-Request-Id")
+	}))
+	defer ts.Close()
+
+	c, err := NewClient(WithClientRequestID("X-Request-Id"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	do := func(req *stdhttp.Request) {
+		t.Helper()
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		_ = resp.Body.Close()
+	}
+
+	// The request ID follows the context.
+	req, _ := stdhttp.NewRequestWithContext(WithRequestID(t.Context(), "req-123"), stdhttp.MethodGet, ts.URL, nil)
+	do(req)
+	if got != "req-123" {
+		t.Errorf("expected the request ID from the context, got %q", got)
+	}
+
+	// A header set on the request is kept.
+	req, _ = stdhttp.NewRequestWithContext(WithRequestID(t.Context(), "req-123"), stdhttp.MethodGet, ts.URL, nil)
+	req.Header.Set("X-Request-Id", "explicit")
+	do(req)
+	if got != "explicit" {
+		t.Errorf("expected the request's header to be kept, got %q", got)
+	}
+
+	// Without a request ID, no header is sent.
+	req, _ = stdhttp.NewRequest(stdhttp.MethodGet, ts.URL, nil)
+	do(req)
+	if got != "" {
+		t.Errorf("expected no request ID, got %q", got)
+	}
+
+	if _, err := NewClient(WithClientRequestID("")); err == nil {
+		t.Error("expected an error for an empty header")
+	}
+}
@@ -0,0 +1,136 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	stdhttp "net/http"
+)
+
+const (
+	// maxAPIErrorBodySize is the maximum number of bytes of a non-2xx response body retained on an APIError.
+	maxAPIErrorBodySize = 4 << 10
+
+	// maxDrainSize is the maximum number of bytes read from a response body to allow the connection to be
+	// reused. Bodies larger than this are closed without being fully read.
+	maxDrainSize = 64 << 10
+)
+
+// APIError is returned for responses with a non-2xx status code. It carries a snapshot of the response so
+// that callers can make decisions (e.g. on Retry-After) without handling the raw response.
+type APIError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+
+	// Header is a copy of the response headers.
+	Header stdhttp.Header
+
+	// Body is the start of the response body, capped at 4KiB.
+	Body []byte
+}
+
+// Error implements error.
+func (e *APIError) Error() string {
+	return fmt.Sprintf("unexpected response status: %d %s", e.StatusCode, stdhttp.StatusText(e.StatusCode))
+}
+
+// CheckResponse returns nil if the response has a 2xx status code. Otherwise, it reads a capped snippet of
+// the body, drains and closes it so the connection can be reused, and returns an *APIError. The bodies of
+// raw responses (see WithRawResponse) are left untouched, and must still be closed by the caller.
+func CheckResponse(resp *stdhttp.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+	}
+
+	if resp.Body != nil && !isRawResponse(resp.Request) {
+		// Errors reading the body are deliberately ignored; the status is the primary signal and the snippet
+		// is best-effort.
+		apiErr.Body, _ = io.ReadAll(io.LimitReader(resp.Body, maxAPIErrorBodySize))
+		drainAndClose(resp)
+	}
+
+	return apiErr
+}
+
+// drainAndClose discards up to maxDrainSize bytes of the response body and closes it, so that the
+// connection can be reused.
+func drainAndClose(resp *stdhttp.Response) {
+	if resp.Body == nil {
+		return
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainSize))
+	_ = resp.Body.Close()
+}
+
+// Do sends the request with the given client and checks the response with CheckResponse. On a non-2xx
+// status the body has already been closed, and the returned error is an *APIError.
+func Do(c *stdhttp.Client, req *stdhttp.Request) (*stdhttp.Response, error) {
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := CheckResponse(resp); err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
+
+// rawResponseKey is the context key marking a request whose response body must be left untouched.
+type rawResponseKey struct{}
+
+// WithRawResponse marks requests made with the returned context as owning the raw response body, for
+// streaming or hijacked responses whose lifecycle the caller manages. For these requests:
+//   - WithMeasureToBodyClose does not apply; the duration is recorded when the response headers arrive.
+//   - WithErrorBodySnippet does not read from the body.
+//   - CheckResponse and Do do not read, drain or close the body.
+//   - WithRetryDeadline and WithMethodTimeout still bound the request, but are not released early when
+//     the body is closed.
+//
+// Active request accounting is unaffected, as it completes when the response headers arrive.
+func WithRawResponse(ctx context.Context) context.Context {
+	return context.WithValue(ctx, rawResponseKey{}, true)
+}
+
+// isRawResponse reports whether the request was made with a context from WithRawResponse.
+func isRawResponse(req *stdhttp.Request) bool {
+	if req == nil {
+		return false
+	}
+	raw, _ := req.Context().Value(rawResponseKey{}).(bool)
+	return raw
+}
+
+// cancelWithBody arranges for cancel to be called once the response body is closed, for contexts that must
+// outlive RoundTrip so that the body can still be read. The bodies of raw responses are not wrapped; their
+// context is released when its deadline passes. Upgraded connections no longer depend on the context, so
+// it is released immediately.
+func cancelWithBody(req *stdhttp.Request, resp *stdhttp.Response, cancel context.CancelFunc) {
+	if resp == nil || resp.StatusCode == stdhttp.StatusSwitchingProtocols {
+		cancel()
+		return
+	}
+	if isRawResponse(req) {
+		return
+	}
+	resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+}
+
+// cancelOnCloseBody releases a context when the response body is closed, for contexts that must outlive
+// RoundTrip so that the body can still be read.
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,112 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDo_APIError(t *testing.T) {
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(stdhttp.StatusTooManyRequests)
+		_, _ = w.Write([]byte(strings.Repeat("x", maxAPIErrorBodySize*2)))
+	}))
+	defer ts.Close()
+
+	c, err := NewClient()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req, _ := stdhttp.NewRequest("GET", ts.URL, nil)
+	_, err = Do(c, req)
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *APIError, got %v", err)
+	}
+	if apiErr.StatusCode != stdhttp.StatusTooManyRequests {
+		t.Errorf("expected status 429, got %d", apiErr.StatusCode)
+	}
+	if got := apiErr.Header.Get("Retry-After"); got != "30" {
+		t.Errorf("expected Retry-After 30, got %q", got)
+	}
+	if len(apiErr.Body) != maxAPIErrorBodySize {
+		t.Errorf("expected body capped at %d bytes, got %d", maxAPIErrorBodySize, len(apiErr.Body))
+	}
+}
+
+func TestDo_Success(t *testing.T) {
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	c, err := NewClient()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req, _ := stdhttp.NewRequest("GET", ts.URL, nil)
+	resp, err := Do(c, req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(resp.Body); err != nil {
+		t.Fatalf("unexpected error reading body: %v", err)
+	}
+	if buf.String() != "ok" {
+		t.Errorf("expected body ok, got %q", buf.String())
+	}
+}
+
+func TestWithRawResponse(t *testing.T) {
+	const body = "streamed error body"
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		w.WriteHeader(stdhttp.StatusInternalServerError)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	c, err := NewClient(
+		WithMeasureToBodyClose(),
+		WithErrorBodySnippet(8),
+		WithMethodTimeout(stdhttp.MethodGet, time.Minute),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req, _ := stdhttp.NewRequestWithContext(WithRawResponse(context.Background()), stdhttp.MethodGet, ts.URL, nil)
+	resp, err := Do(c, req)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *APIError, got %v", err)
+	}
+	if apiErr.Body != nil {
+		t.Errorf("expected the raw body not to be read, got %q", apiErr.Body)
+	}
+
+	switch resp.Body.(type) {
+	case *measuredBody, *cancelOnCloseBody, *struct {
+		io.Reader
+		io.Closer
+	}:
+		t.Errorf("expected the raw body to be left unwrapped, got %T", resp.Body)
+	}
+	got, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil || string(got) != body {
+		t.Errorf("expected the full body %q, got %q (%v)", body, got, err)
+	}
+}
@@ -0,0 +1,152 @@
+package http
+
+import (
+	"bufio"
+	"io"
+	"net"
+	stdhttp "net/http"
+)
+
+// sniffLen is how much of a response body net/http uses to detect its content type.
+const sniffLen = 512
+
+// ReadFrom implements io.ReaderFrom, so that copying a file into the response can still use sendfile. The
+// start of the body goes through Write, so that the headers are committed (and the content type sniffed) as
+// they would be by net/http.
+func (r *responseRecorder) ReadFrom(src io.Reader) (int64, error) {
+	var n int64
+	if !r.wroteHeader {
+		n0, err := io.Copy(writerOnly{r}, io.LimitReader(src, sniffLen))
+		n += n0
+		if err != nil || n0 < sniffLen {
+			return n, err
+		}
+	}
+	rf, ok := r.ResponseWriter.(io.ReaderFrom)
+	if !ok {
+		n1, err := io.Copy(writerOnly{r}, src)
+		return n + n1, err
+	}
+	n1, err := rf.ReadFrom(src)
+	r.bodySize += n1
+	return n + n1, err
+}
+
+// Hijack implements http.Hijacker, recording that the connection was hijacked. It is reached by
+// http.ResponseController, as well as by handlers type-asserting for http.Hijacker.
+func (r *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buf, err := stdhttp.NewResponseController(r.ResponseWriter).Hijack()
+	if err == nil {
+		r.hijacked = true
+	}
+	return conn, buf, err
+}
+
+// writerOnly hides any optional interfaces of a Writer, such as io.ReaderFrom, from io.Copy.
+type writerOnly struct {
+	io.Writer
+}
+
+// exposeOptional returns rw, the ResponseWriter handed to the handler, extended with whichever of http.Flusher,
+// http.Hijacker and io.ReaderFrom the connection's ResponseWriter, w, implements. Handlers that type-assert
+// for them (rather than using http.ResponseController) keep working, and calls go through rw, so that every
+// layer of the instrumentation sees them.
+func exposeOptional(rw, w stdhttp.ResponseWriter) stdhttp.ResponseWriter {
+	_, f := w.(stdhttp.Flusher)
+	_, h := w.(stdhttp.Hijacker)
+	_, rf := w.(io.ReaderFrom)
+
+	u := unwrapper{rw}
+	switch {
+	case f && h && rf:
+		return struct {
+			stdhttp.ResponseWriter
+			unwrapper
+			flusher
+			hijacker
+			readerFrom
+		}{rw, u, flusher{rw}, hijacker{rw}, readerFrom{rw}}
+	case f && h:
+		return struct {
+			stdhttp.ResponseWriter
+			unwrapper
+			flusher
+			hijacker
+		}{rw, u, flusher{rw}, hijacker{rw}}
+	case f && rf:
+		return struct {
+			stdhttp.ResponseWriter
+			unwrapper
+			flusher
+			readerFrom
+		}{rw, u, flusher{rw}, readerFrom{rw}}
+	case h && rf:
+		return struct {
+			stdhttp.ResponseWriter
+			unwrapper
+			hijacker
+			readerFrom
+		}{rw, u, hijacker{rw}, readerFrom{rw}}
+	case f:
+		return struct {
+			stdhttp.ResponseWriter
+			unwrapper
+			flusher
+		}{rw, u, flusher{rw}}
+	case h:
+		return struct {
+			stdhttp.ResponseWriter
+			unwrapper
+			hijacker
+		}{rw, u, hijacker{rw}}
+	case rf:
+		return struct {
+			stdhttp.ResponseWriter
+			unwrapper
+			readerFrom
+		}{rw, u, readerFrom{rw}}
+	}
+	return struct {
+		stdhttp.ResponseWriter
+		unwrapper
+	}{rw, u}
+}
+
+// unwrapper lets http.ResponseController reach the ResponseWriter beneath an exposeOptional wrapper.
+type unwrapper struct {
+	rw stdhttp.ResponseWriter
+}
+
+func (u unwrapper) Unwrap() stdhttp.ResponseWriter {
+	return u.rw
+}
+
+// flusher implements http.Flusher by flushing rw.
+type flusher struct {
+	rw stdhttp.ResponseWriter
+}
+
+func (f flusher) Flush() {
+	_ = stdhttp.NewResponseController(f.rw).Flush()
+}
+
+// hijacker implements http.Hijacker by hijacking rw's connection.
+type hijacker struct {
+	rw stdhttp.ResponseWriter
+}
+
+func (h hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return stdhttp.NewResponseController(h.rw).Hijack()
+}
+
+// readerFrom implements io.ReaderFrom by copying into rw, using its ReadFrom if it has one.
+type readerFrom struct {
+	rw stdhttp.ResponseWriter
+}
+
+func (r readerFrom) ReadFrom(src io.Reader) (int64, error) {
+	if rf, ok := r.rw.(io.ReaderFrom); ok {
+		return rf.ReadFrom(src)
+	}
+	return io.Copy(writerOnly{r.rw}, src)
+}
@@ -0,0 +1,169 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+func TestResponseWriter_OptionalInterfaces(t *testing.T) {
+	body := strings.Repeat("x", 2000)
+	handler := stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		switch r.URL.Path {
+		case "/flush":
+			f, ok := w.(stdhttp.Flusher)
+			if !ok {
+				t.Error("expected the ResponseWriter to implement http.Flusher")
+				return
+			}
+			_, _ = io.WriteString(w, "flushed")
+			f.Flush()
+		case "/hijack":
+			h, ok := w.(stdhttp.Hijacker)
+			if !ok {
+				t.Error("expected the ResponseWriter to implement http.Hijacker")
+				return
+			}
+			conn, buf, err := h.Hijack()
+			if err != nil {
+				t.Errorf("Hijack failed: %v", err)
+				return
+			}
+			defer func() { _ = conn.Close() }()
+			_, _ = buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+			_ = buf.Flush()
+		case "/read-from":
+			if _, ok := w.(io.ReaderFrom); !ok {
+				t.Error("expected the ResponseWriter to implement io.ReaderFrom")
+			}
+			_, _ = io.Copy(w, strings.NewReader(body))
+		}
+	})
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+	srv, err := NewServer(":0", handler, WithServerTracerProvider(tp), WithServerBodySizeAttributes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv.server.Handler)
+	defer ts.Close()
+
+	for _, tc := range []struct{ path, want string }{
+		{"/flush", "flushed"}, {"/hijack", "hijacked"}, {"/read-from", body},
+	} {
+		path, want := tc.path, tc.want
+		t.Run(path, func(t *testing.T) {
+			exporter.Reset()
+			resp, err := stdhttp.Get(ts.URL + path)
+			if err != nil {
+				t.Fatalf("Get failed: %v", err)
+			}
+			got, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			if string(got) != want {
+				t.Errorf("expected %q, got %q", want, got)
+			}
+		})
+	}
+
+	// Bodies copied with ReadFrom are still measured.
+	span := exporter.GetSpans()[0]
+	if !hasAttr(span.Attributes, semconv.HTTPResponseBodySize(len(body))) {
+		t.Errorf("expected http.response.body.size=%d, got %v", len(body), span.Attributes)
+	}
+}
+
+func TestResponseWriter_OptionalInterfacesNotSupported(t *testing.T) {
+	srv, err := NewServer(":0", stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		// httptest.ResponseRecorder can be flushed, but not hijacked.
+		if _, ok := w.(stdhttp.Flusher); !ok {
+			t.Error("expected the ResponseWriter to implement http.Flusher")
+		}
+		if _, ok := w.(stdhttp.Hijacker); ok {
+			t.Error("expected the ResponseWriter not to implement http.Hijacker")
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.server.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
+
+func TestServer_Hijacked(t *testing.T) {
+	handler := stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		// Hijacking through http.ResponseController is recorded too.
+		conn, buf, err := stdhttp.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Errorf("Hijack failed: %v", err)
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		_, _ = buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: echo\r\nConnection: Upgrade\r\n\r\n")
+		_ = buf.Flush()
+	})
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	srv, err := NewServer(":0", handler, WithServerTracerProvider(tp), WithServerMeterProvider(mp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv.server.Handler)
+	defer ts.Close()
+
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	_, _ = io.WriteString(conn, "GET / HTTP/1.1\r\nHost: example.com\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+	status, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || !strings.Contains(status, "101") {
+		t.Fatalf("expected the handler's 101, got %q (%v)", status, err)
+	}
+	_, _ = io.ReadAll(conn)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if !hasAttr(spans[0].Attributes, attribute.Bool("http.connection.hijacked", true)) {
+		t.Errorf("expected http.connection.hijacked on the span, got %v", spans[0].Attributes)
+	}
+	for _, kv := range spans[0].Attributes {
+		if kv.Key == semconv.HTTPResponseStatusCodeKey {
+			t.Errorf("expected no status code for a hijacked connection, got %s", kv.Value.Emit())
+		}
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if h, ok := m.Data.(metricdata.Histogram[float64]); ok && m.Name == "http.server.request.duration" {
+				for _, dp := range h.DataPoints {
+					if _, ok := dp.Attributes.Value(semconv.HTTPResponseStatusCodeKey); ok {
+						t.Error("expected no status code on the duration of a hijacked connection")
+					}
+				}
+			}
+		}
+	}
+}
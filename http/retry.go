@@ -0,0 +1,323 @@
+package http
+
+import (
+	"context"
+	"errors"
+	stdhttp "net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RetryOption configures the retry behavior enabled by WithRetry.
+type RetryOption func(*retryTransport) error
+
+// defaultRetryBudgetRatio and defaultRetryBudgetBurst define the retry budget used when none is supplied:
+// retries may make up at most 10% of requests, with up to 10 retries banked for quiet periods.
+const (
+	defaultRetryBudgetRatio = 0.1
+	defaultRetryBudgetBurst = 10
+)
+
+// RetryBudget limits retries to a fraction of the original requests made, so that retries are suppressed
+// when a dependency is failing broadly rather than amplifying the outage into a retry storm.
+//
+// It is a token bucket: every original request deposits ratio tokens, and every retry withdraws one. The
+// bucket holds at most burst tokens, and starts full. A budget is safe for concurrent use, and may be
+// shared between clients.
+type RetryBudget struct {
+	mu     sync.Mutex
+	ratio  float64
+	burst  float64
+	tokens float64
+}
+
+// NewRetryBudget returns a RetryBudget that allows retries to make up ratio (e.g. 0.1 for 10%) of
+// requests, banking up to burst retries.
+func NewRetryBudget(ratio float64, burst int) (*RetryBudget, error) {
+	if ratio <= 0 {
+		return nil, errors.New("retry budget ratio must be positive")
+	}
+	if burst < 1 {
+		return nil, errors.New("retry budget burst must be at least 1")
+	}
+	return &RetryBudget{ratio: ratio, burst: float64(burst), tokens: float64(burst)}, nil
+}
+
+// deposit records an original (non-retry) request.
+func (b *RetryBudget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(b.burst, b.tokens+b.ratio)
+}
+
+// withdraw reports whether a retry is allowed, consuming budget if it is.
+func (b *RetryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// WithRetryBudget replaces the default retry budget (10% of requests, burst of 10). Pass the same budget
+// to several clients to share it between them.
+func WithRetryBudget(b *RetryBudget) RetryOption {
+	return func(rt *retryTransport) error {
+		if b == nil {
+			return errors.New("retry budget must not be nil")
+		}
+		rt.budget = b
+		return nil
+	}
+}
+
+// WithRetryDeadline caps the total time spent on a request across all attempts. Once the deadline passes,
+// the attempt in progress is aborted and no further attempts are made.
+//
+// The deadlines that apply to a request interact as follows:
+//   - Client.Timeout (WithTimeout) bounds the whole call to Client.Do, including every attempt and reading
+//     the response body. It is the outer ceiling.
+//   - A deadline on the request context bounds the request in the same way, and also applies to attempts.
+//   - The retry deadline bounds the attempts from the start of the first one until the response headers
+//     of the last one. It is useful to cut a retry loop short of Client.Timeout, or to bound retries when
+//     Client.Timeout is disabled.
+//
+// Whichever expires first wins.
+func WithRetryDeadline(d time.Duration) RetryOption {
+	return func(rt *retryTransport) error {
+		if d <= 0 {
+			return errors.New("retry deadline must be positive")
+		}
+		rt.deadline = d
+		return nil
+	}
+}
+
+// defaultRetryStatusCodes are the response status codes that are retried when none are supplied.
+var defaultRetryStatusCodes = []int{
+	stdhttp.StatusBadGateway, stdhttp.StatusServiceUnavailable, stdhttp.StatusGatewayTimeout,
+}
+
+// WithRetryStatusCodes replaces the response status codes that are retried (by default 502, 503 and 504).
+func WithRetryStatusCodes(codes ...int) RetryOption {
+	return func(rt *retryTransport) error {
+		statusCodes := make(map[int]bool, len(codes))
+		for _, code := range codes {
+			if code < 100 || code > 599 {
+				return errors.New("retry status codes must be valid HTTP status codes")
+			}
+			statusCodes[code] = true
+		}
+		rt.statusCodes = statusCodes
+		return nil
+	}
+}
+
+// WithRetry retries idempotent requests up to maxAttempts times in total when the attempt fails to connect,
+// times out or loses its connection, or gets a 502, 503 or 504 response. Other errors, such as TLS
+// verification failures, are not retried. Requests with a body are retried only if it can be replayed, via
+// Request.GetBody (which http.NewRequest sets for in-memory bodies). Retries stop once the request's context
+// is done, so they never outlast Client.Timeout.
+//
+// Retries are immediate unless a backoff is configured with WithExponentialBackoff or WithConstantBackoff.
+//
+// Each retry is recorded as an http.retry event on the request span and counted in http.client.retries,
+// both with the attempt number as http.retry.attempt. Retries are limited by a RetryBudget, and suppressed
+// retries are counted in http.client.retry_budget.exhausted.
+func WithRetry(maxAttempts int, opts ...RetryOption) ClientOption {
+	return func(c *stdhttp.Client) error {
+		if maxAttempts < 1 {
+			return errors.New("max attempts must be at least 1")
+		}
+
+		budget, err := NewRetryBudget(defaultRetryBudgetRatio, defaultRetryBudgetBurst)
+		if err != nil {
+			return err
+		}
+		rt := &retryTransport{maxAttempts: maxAttempts, budget: budget}
+		if err := WithRetryStatusCodes(defaultRetryStatusCodes...)(rt); err != nil {
+			return err
+		}
+		for _, opt := range opts {
+			if err := opt(rt); err != nil {
+				return err
+			}
+		}
+
+		wrapTransport(c, func(base stdhttp.RoundTripper) stdhttp.RoundTripper {
+			rt.base = base
+			return rt
+		})
+		return nil
+	}
+}
+
+// retryTransport is a RoundTripper that retries failed attempts.
+type retryTransport struct {
+	base        stdhttp.RoundTripper
+	maxAttempts int
+	budget      *RetryBudget
+	deadline    time.Duration
+	statusCodes map[int]bool
+
+	// backoff determines the delay between attempts, if set; otherwise retries are immediate. noJitter
+	// records WithoutBackoffJitter, whichever order the options are applied in.
+	backoff  *backoff
+	noJitter bool
+
+	mBudgetExhausted metric.Int64Counter
+	mRetries         metric.Int64Counter
+}
+
+func (t *retryTransport) unwrap() stdhttp.RoundTripper {
+	return t.base
+}
+
+func (t *retryTransport) setBase(base stdhttp.RoundTripper) {
+	t.base = base
+}
+
+func (t *retryTransport) resilience() {}
+
+func (t *retryTransport) makesAttempts() {}
+
+func (t *retryTransport) instrument(meter metric.Meter) error {
+	var err error
+	t.mBudgetExhausted, err = meter.Int64Counter("http.client.retry_budget.exhausted")
+	if err != nil {
+		return err
+	}
+	t.mRetries, err = meter.Int64Counter("http.client.retries")
+	return err
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *retryTransport) RoundTrip(req *stdhttp.Request) (*stdhttp.Response, error) {
+	t.budget.deposit()
+
+	if t.deadline > 0 {
+		ctx, cancel := context.WithTimeout(req.Context(), t.deadline)
+		resp, err := t.roundTrip(req.WithContext(ctx))
+		// The deadline must not cut off reading the body, but the timer is released once it is closed.
+		cancelWithBody(req, resp, cancel)
+		return resp, err
+	}
+	return t.roundTrip(req)
+}
+
+// roundTrip makes attempts until one succeeds, is not retryable, or retries are exhausted.
+func (t *retryTransport) roundTrip(req *stdhttp.Request) (*stdhttp.Response, error) {
+	ctx := req.Context()
+	lr := logicalRequestFromContext(ctx)
+	for attempt := 1; ; attempt++ {
+		var resp *stdhttp.Response
+		var err error
+		if lr != nil {
+			attemptReq, span := lr.startAttempt(req)
+			resp, err = t.base.RoundTrip(attemptReq)
+			lr.endAttempt(span, resp, err)
+		} else {
+			resp, err = t.base.RoundTrip(req)
+		}
+		if attempt >= t.maxAttempts || !t.isRetryable(req, resp, err) || ctx.Err() != nil {
+			return resp, err
+		}
+
+		if !t.budget.withdraw() {
+			if t.mBudgetExhausted != nil {
+				t.mBudgetExhausted.Add(ctx, 1, metric.WithAttributes(clientRequestAttrs(req)...))
+			}
+			return resp, err
+		}
+
+		// Replay the body for the next attempt. If it can't be, the failed attempt is the result.
+		next, replayErr := rewindBody(req)
+		if replayErr != nil {
+			return resp, err
+		}
+		req = next
+
+		var delay time.Duration
+		if t.backoff != nil {
+			delay = t.backoff.delay(attempt, resp)
+		}
+		if resp != nil {
+			drainAndClose(resp)
+		}
+		t.recordRetry(req, attempt+1, delay)
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// recordRetry records that attempt (the second or later) is about to be made, after delay.
+func (t *retryTransport) recordRetry(req *stdhttp.Request, attempt int, delay time.Duration) {
+	ctx := req.Context()
+	attr := attribute.Int("http.retry.attempt", attempt)
+	trace.SpanFromContext(ctx).AddEvent("http.retry", trace.WithAttributes(
+		attr, attribute.Float64("http.retry.delay", delay.Seconds()),
+	))
+	if t.mRetries != nil {
+		t.mRetries.Add(ctx, 1, metric.WithAttributes(append(clientRequestAttrs(req), attr)...))
+	}
+}
+
+// isRetryable reports whether an attempt failed in a way that is safe and worthwhile to retry.
+func (t *retryTransport) isRetryable(req *stdhttp.Request, resp *stdhttp.Response, err error) bool {
+	if !isIdempotent(req.Method) || (hasBody(req) && req.GetBody == nil) {
+		return false
+	}
+	if err != nil {
+		return isTransientError(err)
+	}
+	return t.statusCodes[resp.StatusCode]
+}
+
+// isTransientError reports whether err is a failure to connect, or of the connection, that may not recur.
+// Other errors, such as an untrusted certificate, a failed token source or an open circuit breaker, would
+// only fail again, spending the retry budget and backoff.
+func isTransientError(err error) bool {
+	switch errorType(err) {
+	case errorTypeConnect, errorTypeConnectionRefused, errorTypeConnectionReset, errorTypeTimeout,
+		errorTypeUnexpectedEOF, errorTypeDNS:
+		return true
+	}
+	return false
+}
+
+// hasBody reports whether the request has a body that an attempt may have consumed.
+func hasBody(req *stdhttp.Request) bool {
+	return req.Body != nil && req.Body != stdhttp.NoBody
+}
+
+// rewindBody returns a copy of req with a fresh body from GetBody, so that it can be sent again. Requests
+// without a body are returned as they are.
+func rewindBody(req *stdhttp.Request) (*stdhttp.Request, error) {
+	if !hasBody(req) {
+		return req, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	next := *req
+	next.Body = body
+	return &next, nil
+}
+
+// isIdempotent reports whether the method is idempotent per RFC 9110.
+func isIdempotent(method string) bool {
+	switch method {
+	case stdhttp.MethodGet, stdhttp.MethodHead, stdhttp.MethodOptions, stdhttp.MethodTrace,
+		stdhttp.MethodPut, stdhttp.MethodDelete:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,343 @@
+package http
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"io"
+	"log"
+	"net"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestWithRetry(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(stdhttp.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(stdhttp.StatusOK)
+	}))
+	defer ts.Close()
+
+	c, err := NewClient(WithRetry(3))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resp, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != stdhttp.StatusOK {
+		t.Errorf("expected 200, got %d", resp.StatusCode)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("expected 3 attempts, got %d", got)
+	}
+
+	// The transport must still be reachable through the retry wrapper.
+	if _, err := getTransport(c); err != nil {
+		t.Errorf("expected transport to be reachable: %v", err)
+	}
+}
+
+func TestWithRetry_NonIdempotent(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		calls.Add(1)
+		w.WriteHeader(stdhttp.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	c, err := NewClient(WithRetry(3))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resp, err := c.Post(ts.URL, "text/plain", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("expected 1 attempt for POST, got %d", got)
+	}
+}
+
+func TestWithRetry_Errors(t *testing.T) {
+	t.Run("connection refused", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		addr := ln.Addr().String()
+		_ = ln.Close()
+
+		reader := sdkmetric.NewManualReader()
+		mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+		c, err := NewClient(WithClientMeterProvider(mp), WithRetry(3))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := c.Get("http://" + addr); err == nil {
+			t.Fatal("expected an error")
+		}
+		if got := sumCounter(t, reader, "http.client.retries"); got != 2 {
+			t.Errorf("expected 2 retries, got %d", got)
+		}
+	})
+
+	t.Run("unknown authority", func(t *testing.T) {
+		var conns atomic.Int32
+		ts := httptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {}))
+		ts.Config.ConnState = func(_ net.Conn, state stdhttp.ConnState) {
+			if state == stdhttp.StateNew {
+				conns.Add(1)
+			}
+		}
+		ts.Config.ErrorLog = log.New(io.Discard, "", 0)
+		ts.StartTLS()
+		defer ts.Close()
+
+		// The client doesn't trust the test server's certificate, which won't change on a retry.
+		c, err := NewClient(WithRetry(3))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		_, err = c.Get(ts.URL)
+		var authorityErr x509.UnknownAuthorityError
+		if !errors.As(err, &authorityErr) {
+			t.Fatalf("expected an unknown authority error, got %v", err)
+		}
+		if got := conns.Load(); got != 1 {
+			t.Errorf("expected 1 attempt, got %d", got)
+		}
+	})
+
+	t.Run("token source", func(t *testing.T) {
+		ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {}))
+		defer ts.Close()
+
+		var calls atomic.Int32
+		errExpired := errors.New("refresh token expired")
+		c, err := NewClient(WithRetry(3), WithBearerTokenSource(func(context.Context) (string, error) {
+			calls.Add(1)
+			return "", errExpired
+		}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := c.Get(ts.URL); !errors.Is(err, errExpired) {
+			t.Fatalf("expected the token source's error, got %v", err)
+		}
+		if got := calls.Load(); got != 1 {
+			t.Errorf("expected 1 attempt, got %d", got)
+		}
+	})
+}
+
+func TestWithRetry_Body(t *testing.T) {
+	var calls atomic.Int32
+	var bodies []string
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if calls.Add(1) < 2 {
+			w.WriteHeader(stdhttp.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	c, err := NewClient(WithRetry(3))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// An in-memory body can be replayed.
+	req, _ := stdhttp.NewRequest(stdhttp.MethodPut, ts.URL, strings.NewReader("payload"))
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != stdhttp.StatusOK || len(bodies) != 2 || bodies[1] != "payload" {
+		t.Errorf("expected the body to be replayed, got %d with bodies %q", resp.StatusCode, bodies)
+	}
+
+	// A body without GetBody can't be, so is not retried.
+	calls.Store(0)
+	bodies = nil
+	req, _ = stdhttp.NewRequest(stdhttp.MethodPut, ts.URL, io.NopCloser(strings.NewReader("payload")))
+	resp, err = c.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+	if len(bodies) != 1 {
+		t.Errorf("expected 1 attempt for a body that can't be replayed, got %d", len(bodies))
+	}
+}
+
+func TestWithRetryStatusCodes(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		if calls.Add(1) < 2 {
+			w.WriteHeader(stdhttp.StatusTooManyRequests)
+		}
+	}))
+	defer ts.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	c, err := NewClient(
+		WithClientTracerProvider(tp),
+		WithClientMeterProvider(mp),
+		WithRetry(3, WithRetryStatusCodes(stdhttp.StatusTooManyRequests)),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "parent-span")
+	req, _ := stdhttp.NewRequestWithContext(ctx, stdhttp.MethodGet, ts.URL, nil)
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+	span.End()
+
+	if resp.StatusCode != stdhttp.StatusOK {
+		t.Errorf("expected the 429 to be retried, got %d", resp.StatusCode)
+	}
+	attempt := attribute.Int("http.retry.attempt", 2)
+	if got := sumCounterWithAttr(t, reader, "http.client.retries", attempt); got != 1 {
+		t.Errorf("expected 1 retry counted, got %d", got)
+	}
+	var events int
+	for _, e := range exporter.GetSpans()[0].Events {
+		if e.Name == "http.retry" && hasAttr(e.Attributes, attempt) {
+			events++
+		}
+	}
+	if events != 1 {
+		t.Errorf("expected 1 retry event, got %d", events)
+	}
+
+	if _, err := NewClient(WithRetry(3, WithRetryStatusCodes(42))); err == nil {
+		t.Error("expected an error for an invalid status code")
+	}
+}
+
+func TestWithRetry_BudgetExhausted(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		calls.Add(1)
+		w.WriteHeader(stdhttp.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	budget, err := NewRetryBudget(0.1, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	c, err := NewClient(WithClientMeterProvider(mp), WithRetry(5, WithRetryBudget(budget)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resp, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	// One original attempt, plus the single retry the budget allowed.
+	if got := calls.Load(); got != 2 {
+		t.Errorf("expected 2 attempts, got %d", got)
+	}
+	if got := sumCounter(t, reader, "http.client.retry_budget.exhausted"); got != 1 {
+		t.Errorf("expected budget exhaustion to be recorded once, got %d", got)
+	}
+}
+
+func TestWithRetryDeadline(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		calls.Add(1)
+		time.Sleep(30 * time.Millisecond)
+		w.WriteHeader(stdhttp.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	budget, err := NewRetryBudget(1, 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c, err := NewClient(WithRetry(1000, WithRetryBudget(budget), WithRetryDeadline(100*time.Millisecond)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	start := time.Now()
+	_, err = c.Get(ts.URL)
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	if elapsed > time.Second {
+		t.Errorf("expected the retry loop to be cut off at the deadline, took %v", elapsed)
+	}
+	if got := calls.Load(); got > 5 {
+		t.Errorf("expected at most 5 attempts within the deadline, got %d", got)
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	b, err := NewRetryBudget(0.5, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !b.withdraw() || !b.withdraw() {
+		t.Fatal("expected the initial burst to allow two retries")
+	}
+	if b.withdraw() {
+		t.Fatal("expected the budget to be exhausted")
+	}
+
+	b.deposit()
+	if b.withdraw() {
+		t.Error("expected half a token to be insufficient for a retry")
+	}
+	b.deposit()
+	b.deposit()
+	if !b.withdraw() {
+		t.Error("expected two deposits at 0.5 to allow a retry")
+	}
+
+	if _, err := NewRetryBudget(0, 1); err == nil {
+		t.Error("expected an error for a zero ratio")
+	}
+}
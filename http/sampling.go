@@ -0,0 +1,83 @@
+package http
+
+import (
+	"context"
+	"errors"
+	stdhttp "net/http"
+	"strconv"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+type forceSampleKey struct{}
+
+// withForceSample returns a copy of ctx marking spans started from it to be sampled.
+func withForceSample(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forceSampleKey{}, true)
+}
+
+// isForceSampled reports whether ctx was marked by withForceSample.
+func isForceSampled(ctx context.Context) bool {
+	forced, _ := ctx.Value(forceSampleKey{}).(bool)
+	return forced
+}
+
+// WithForceSampleHeader marks requests with the header set to a true value (such as "X-Force-Trace: 1") to be
+// sampled, for debugging requests in production that would otherwise go unsampled. The mark is carried by
+// the request context, so spans the handler starts are sampled too. Forcibly sampled server spans are recorded
+// with http.server.force_sampled=true.
+//
+// Sampling is decided by the TracerProvider's sampler, which this package can't override, so the provider
+// must be configured with a sampler from NewForceSampler to honour the mark:
+//
+//	tp := sdktrace.NewTracerProvider(sdktrace.WithSampler(http.NewForceSampler(sdktrace.ParentBased(
+//		sdktrace.TraceIDRatioBased(0.01),
+//	))))
+//
+// Any client can send the header, so strip it from untrusted requests at the edge if sampling costs matter.
+func WithForceSampleHeader(name string) ServerOption {
+	return func(s *Server) error {
+		if name == "" {
+			return errors.New("force sample header must not be empty")
+		}
+		s.forceSampleHeader = stdhttp.CanonicalHeaderKey(name)
+		return nil
+	}
+}
+
+// forceSampled reports whether the request sets the force sample header to a true value.
+func forceSampled(r *stdhttp.Request, header string) bool {
+	if header == "" {
+		return false
+	}
+	forced, err := strconv.ParseBool(r.Header.Get(header))
+	return err == nil && forced
+}
+
+// NewForceSampler returns a sampler that samples spans started within a request marked by
+// WithForceSampleHeader, and defers to base for all others. Downstream services sample the trace too if their
+// samplers follow a sampled parent (see sdktrace.ParentBased).
+func NewForceSampler(base sdktrace.Sampler) sdktrace.Sampler {
+	return forceSampler{base: base}
+}
+
+// forceSampler is a sampler that samples spans whose context is marked by withForceSample.
+type forceSampler struct {
+	base sdktrace.Sampler
+}
+
+// ShouldSample implements sdktrace.Sampler.
+func (s forceSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	if !isForceSampled(p.ParentContext) {
+		return s.base.ShouldSample(p)
+	}
+	// The base sampler still supplies the trace state.
+	result := s.base.ShouldSample(p)
+	result.Decision = sdktrace.RecordAndSample
+	return result
+}
+
+// Description implements sdktrace.Sampler.
+func (s forceSampler) Description() string {
+	return "ForceSampler{" + s.base.Description() + "}"
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	stdhttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestWithForceSampleHeader(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(
+		trace.WithSyncer(exporter),
+		trace.WithSampler(NewForceSampler(trace.NeverSample())),
+	)
+
+	s, err := NewServer(":0", stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		_, span := tp.Tracer("test").Start(r.Context(), "child")
+		span.End()
+	}), WithServerTracerProvider(tp), WithForceSampleHeader("X-Force-Trace"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, tc := range []struct {
+		value     string
+		wantSpans int
+	}{
+		{value: "", wantSpans: 0},
+		{value: "0", wantSpans: 0},
+		{value: "nonsense", wantSpans: 0},
+		{value: "1", wantSpans: 2},
+		{value: "true", wantSpans: 2},
+	} {
+		exporter.Reset()
+		req := httptest.NewRequest(stdhttp.MethodGet, "/", nil)
+		if tc.value != "" {
+			req.Header.Set("X-Force-Trace", tc.value)
+		}
+		s.server.Handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		spans := exporter.GetSpans()
+		if len(spans) != tc.wantSpans {
+			t.Errorf("expected %d spans for %q, got %d", tc.wantSpans, tc.value, len(spans))
+			continue
+		}
+		for _, span := range spans {
+			if span.Name != "child" &&
+				!hasAttr(span.Attributes, attribute.Bool("http.server.force_sampled", true)) {
+				t.Error("expected the server span to be marked as force sampled")
+			}
+		}
+	}
+
+	if got := NewForceSampler(trace.NeverSample()).Description(); got != "ForceSampler{AlwaysOffSampler}" {
+		t.Errorf("unexpected description %q", got)
+	}
+	if _, err := NewServer(":0", nil, WithForceSampleHeader("")); err == nil {
+		t.Error("expected an error for an empty header")
+	}
+}
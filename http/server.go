@@ -4,25 +4,179 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
+	"log/slog"
 	"net"
 	stdhttp "net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 )
 
 // Server wraps net/http.Server to provide defaults and graceful shutdown.
 type Server struct {
-	server           *stdhttp.Server
-	tracer           trace.Tracer
-	meter            metric.Meter
-	mOpenConnections metric.Int64UpDownCounter
-	mActiveRequests  metric.Int64UpDownCounter
+	server               *stdhttp.Server
+	tracer               trace.Tracer
+	meter                metric.Meter
+	mOpenConnections     metric.Int64UpDownCounter
+	mActiveRequests      metric.Int64UpDownCounter
+	mDuration            metric.Float64Histogram
+	mRejectedConnections metric.Int64Counter
+	mBodyReadTimeouts    metric.Int64Counter
+	mBodyTooLarge        metric.Int64Counter
+	mRequestHeaderSize   metric.Int64Histogram
+	mResponseHeaderSize  metric.Int64Histogram
+	mMiddlewareDuration  metric.Float64Histogram
+	mQueueTime           metric.Float64Histogram
+	mPanics              metric.Int64Counter
+	mStreamsAtLimit      metric.Int64Counter
+	mMalformedRequests   metric.Int64Counter
+	mPeakStreams         metric.Int64Histogram
+	mRateLimited         metric.Int64Counter
+	mCORSPreflight       metric.Int64Counter
+
+	// middleware is the named handler chain, outermost first.
+	middleware       []namedMiddleware
+	middlewareTiming bool
+
+	// handlerTimeout, if set, is how long the handler may run before the request is answered with a 503 and
+	// handlerTimeoutMessage as its body.
+	handlerTimeout        time.Duration
+	handlerTimeoutMessage string
+
+	// requestDeadline, if set, is the deadline placed on each request's context.
+	requestDeadline time.Duration
+
+	// connStateHooks are invoked on every connection state transition, after the built-in accounting.
+	connStateHooks []func(net.Conn, stdhttp.ConnState)
+
+	// connContextHooks are invoked on every new connection to modify its context, in the order registered.
+	connContextHooks []func(context.Context, net.Conn) context.Context
+
+	// queueTimeMetrics controls whether the time requests wait before being handled is recorded.
+	queueTimeMetrics bool
+
+	// malformedRequestMetrics controls whether requests rejected by net/http are counted, with the client
+	// addresses recorded bounded by malformedClients.
+	malformedRequestMetrics bool
+	malformedClients        *boundedValues
+
+	// http2MaxStreams is the configured HTTP/2 concurrent stream limit, or zero if it is not configured.
+	http2MaxStreams int
+
+	// conns holds the connInfo of each open connection, while a feature that needs it is enabled.
+	conns sync.Map
+
+	// maxOpenConns caps the number of open connections. Zero means unlimited.
+	maxOpenConns int
+	openConns    atomic.Int64
+
+	// rejectOnShutdown controls whether new requests are refused once shutdown has begun.
+	rejectOnShutdown bool
+	shuttingDown     atomic.Bool
+
+	// draining is set once Drain has been called. activeRequests counts the requests being handled, for
+	// Drain to wait on.
+	draining       atomic.Bool
+	activeRequests atomic.Int64
+
+	// notReady is set while the application has marked the server not ready with SetReady.
+	notReady atomic.Bool
+
+	// ignore holds the functions that exclude requests from instrumentation.
+	ignore []func(*stdhttp.Request) bool
+
+	// livePath and readyPath are the paths health checks are answered on, if configured.
+	livePath  string
+	readyPath string
+
+	// hardDrain controls whether the listener and connections yet to start a request are closed as soon as
+	// shutdown begins. newConns holds the connections yet to start a request while it is enabled.
+	hardDrain bool
+	newConns  sync.Map
+
+	// unixSocketMode is the permissions of the socket file for a "unix:" address, or zero for the default.
+	unixSocketMode os.FileMode
+
+	// requestIDHeader is the header request IDs are read from and echoed in, if configured.
+	requestIDHeader string
+
+	// forceSampleHeader is the header that marks requests to be sampled, if configured.
+	forceSampleHeader string
+
+	// cors is the cross-origin resource sharing policy, if configured.
+	cors *corsPolicy
+
+	// rateLimit limits the rate of requests from each client, if configured.
+	rateLimit *keyedRateLimiter
+
+	// onListen holds the functions called with the bound address once the server is listening.
+	onListen []func(net.Addr)
+
+	// listener is the listener being served, once serving has started.
+	listenerMu sync.Mutex
+	listener   net.Listener
+
+	// certs holds the certificate configured with WithServerTLS, if the server serves HTTPS.
+	certs *certificateReloader
+
+	// shutdownTimeout bounds graceful shutdown in Run. Zero means defaultShutdownTimeout.
+	shutdownTimeout time.Duration
+
+	// background tracks tasks registered with WithBackgroundTask, which shutdown waits for.
+	background backgroundTasks
+
+	// contentTypeAttrs controls whether request and response Content-Type are recorded on the span.
+	contentTypeAttrs bool
+
+	// bodySizeAttrs controls whether request and response body sizes are recorded on the span.
+	bodySizeAttrs bool
+
+	// requestHeaders and responseHeaders are the headers recorded on the span.
+	requestHeaders, responseHeaders headerCapture
+
+	// baggageKeys are the baggage members copied onto the span.
+	baggageKeys []string
+
+	// compression configures response compression, if enabled.
+	compression *compression
+
+	// bodyReadTimeout bounds how long a handler may spend reading the request body. Zero means unbounded.
+	bodyReadTimeout time.Duration
+
+	// maxRequestBodySize limits the size of request bodies. Zero means unlimited.
+	maxRequestBodySize int64
+
+	// streamDeadline manages the write deadline of streaming responses, if set.
+	streamDeadline *streamDeadline
+
+	// headerSizeMetrics controls whether request and response header sizes are recorded.
+	headerSizeMetrics bool
+
+	// propagator extracts trace context from requests. Nil means the global propagator.
+	propagator propagation.TextMapPropagator
+
+	// requirePropagator controls whether construction fails when the propagator is a no-op.
+	requirePropagator bool
+
+	// clientAddress determines how the client address is recorded on spans.
+	clientAddress clientAddressSource
+
+	// logger is the base of the request-scoped logger. Nil means no logger is placed in the context.
+	logger *slog.Logger
+
+	// requestContext derives the context each server span is started from. spanName names the span.
+	requestContext func(ctx context.Context, r *stdhttp.Request) context.Context
+	spanName       func(r *stdhttp.Request) string
 }
 
 // ServerOption configures the Server.
@@ -33,6 +187,8 @@ var defaultServerOptions = []ServerOption{
 	WithReadTimeout(2 * time.Second),
 	WithWriteTimeout(2 * time.Second),
 	WithIdleTimeout(2 * time.Second),
+	WithReadHeaderTimeout(1 * time.Second),
+	WithMaxHeaderBytes(64 << 10),
 }
 
 // WithServerTracerProvider configures the server with a specific tracer provider.
@@ -51,6 +207,246 @@ func WithServerMeterProvider(mp metric.MeterProvider) ServerOption {
 	}
 }
 
+// WithServerContentTypeAttributes records the request and response Content-Type on the server span as
+// http.request.header.content-type and http.response.header.content-type.
+func WithServerContentTypeAttributes() ServerOption {
+	return func(s *Server) error {
+		s.contentTypeAttrs = true
+		return nil
+	}
+}
+
+// WithServerBodySizeAttributes records the request and response body sizes on the server span as
+// http.request.body.size and http.response.body.size: the bytes the handler read from the request body, and
+// the bytes it wrote to the response.
+func WithServerBodySizeAttributes() ServerOption {
+	return func(s *Server) error {
+		s.bodySizeAttrs = true
+		return nil
+	}
+}
+
+// WithConnStateHook registers a function that is called on every connection state transition, in addition
+// to the built-in connection accounting. Hooks are called in the order they are registered, and are also
+// called for connections rejected by WithMaxOpenConnections.
+func WithConnStateHook(hook func(net.Conn, stdhttp.ConnState)) ServerOption {
+	return func(s *Server) error {
+		if hook == nil {
+			return errors.New("conn state hook must not be nil")
+		}
+		s.connStateHooks = append(s.connStateHooks, hook)
+		return nil
+	}
+}
+
+// WithMaxOpenConnections caps the number of connections the server holds open at once. Connections
+// accepted beyond the limit are closed immediately and counted in http.server.rejected_connections.
+// Zero (the default) means unlimited.
+func WithMaxOpenConnections(n int) ServerOption {
+	return func(s *Server) error {
+		if n < 0 {
+			return errors.New("max open connections must not be negative")
+		}
+		s.maxOpenConns = n
+		return nil
+	}
+}
+
+// WithBodyReadTimeout sets a per-request deadline for reading the request body, starting when the handler
+// is invoked. Reads past the deadline return a timeout error (a net.Error with Timeout() == true), which
+// handlers can surface as a 408 Request Timeout. Timeouts are counted in
+// http.server.request.body_read_timeouts.
+//
+// This guards against clients that send headers promptly but trickle the body, which ReadHeaderTimeout does
+// not cover and ReadTimeout only covers coarsely. It only ever shortens ReadTimeout: if ReadTimeout would
+// expire first, it still applies.
+func WithBodyReadTimeout(d time.Duration) ServerOption {
+	return func(s *Server) error {
+		if d < 0 {
+			return errors.New("body read timeout must not be negative")
+		}
+		s.bodyReadTimeout = d
+		return nil
+	}
+}
+
+// WithServerHandlerTimeout cuts off handlers that run for longer than d, answering the request with a 503
+// Service Unavailable with msg as its body (or a default message if msg is empty), using http.TimeoutHandler.
+// Unlike WriteTimeout, which closes the connection, the client gets a clean response. The timeout covers the
+// middleware added with WithMiddleware as well as the handler, and sits inside the instrumentation, so the span
+// and metrics record the 503.
+//
+// The handler's context is cancelled at the timeout, and its writes after it fail with http.ErrHandlerTimeout.
+// Responses are buffered until the handler returns, so it can't stream them (http.Flusher) or take over the
+// connection (http.Hijacker).
+func WithServerHandlerTimeout(d time.Duration, msg string) ServerOption {
+	return func(s *Server) error {
+		if d <= 0 {
+			return errors.New("handler timeout must be positive")
+		}
+		s.handlerTimeout = d
+		s.handlerTimeoutMessage = msg
+		return nil
+	}
+}
+
+// WithRequestDeadline gives each request's context a deadline d after the handler is invoked, so that work the
+// handler does with the context, such as requests made with this package's client, is abandoned once the deadline
+// passes. Unlike WithServerHandlerTimeout, the handler isn't cut off: it is up to it (and what it calls) to honour
+// the deadline. Requests whose handler is still running when the deadline passes are recorded on the span with
+// http.server.deadline_exceeded=true.
+func WithRequestDeadline(d time.Duration) ServerOption {
+	return func(s *Server) error {
+		if d <= 0 {
+			return errors.New("request deadline must be positive")
+		}
+		s.requestDeadline = d
+		return nil
+	}
+}
+
+// WithConnContext registers a hook to modify the context used for each new connection, as with
+// http.Server.ConnContext. Hooks run in the order they are registered, each receiving the context returned
+// by the last.
+func WithConnContext(hook func(ctx context.Context, c net.Conn) context.Context) ServerOption {
+	return func(s *Server) error {
+		if hook == nil {
+			return errors.New("conn context hook must not be nil")
+		}
+		s.connContextHooks = append(s.connContextHooks, hook)
+		return nil
+	}
+}
+
+// WithRequestContextFunc derives the context each request is handled with, before its server span is started,
+// so that request-derived values (such as a tenant from the Host header, or a deadline from a request header)
+// can influence sampling and the span name.
+//
+// fn runs after the trace context has been extracted from the request headers, so the context it receives
+// carries the remote span context; it must keep it, by deriving the context it returns from ctx. The span is
+// started from the returned context, and the handler receives the span's context derived from it.
+func WithRequestContextFunc(fn func(ctx context.Context, r *stdhttp.Request) context.Context) ServerOption {
+	return func(s *Server) error {
+		if fn == nil {
+			return errors.New("request context func must not be nil")
+		}
+		s.requestContext = fn
+		return nil
+	}
+}
+
+// WithIgnorePaths serves requests for the given paths without instrumentation: no span is started and no
+// metrics are recorded for them. A path matches exactly, or, if it ends in "*", as a prefix (e.g.
+// "/debug/*" matches everything beneath /debug/).
+func WithIgnorePaths(paths ...string) ServerOption {
+	return func(s *Server) error {
+		exact := make(map[string]bool)
+		var prefixes []string
+		for _, path := range paths {
+			if prefix, ok := strings.CutSuffix(path, "*"); ok {
+				prefixes = append(prefixes, prefix)
+				continue
+			}
+			exact[path] = true
+		}
+		return WithIgnoreFunc(func(r *stdhttp.Request) bool {
+			if exact[r.URL.Path] {
+				return true
+			}
+			for _, prefix := range prefixes {
+				if strings.HasPrefix(r.URL.Path, prefix) {
+					return true
+				}
+			}
+			return false
+		})(s)
+	}
+}
+
+// WithIgnoreFunc serves requests for which fn returns true without instrumentation: no span is started and no
+// metrics are recorded for them. It may be used more than once, and with WithIgnorePaths; a request is
+// ignored if any of them match it.
+func WithIgnoreFunc(fn func(*stdhttp.Request) bool) ServerOption {
+	return func(s *Server) error {
+		if fn == nil {
+			return errors.New("ignore func must not be nil")
+		}
+		s.ignore = append(s.ignore, fn)
+		return nil
+	}
+}
+
+// WithServerSpanNameFormatter names each server span with fn, in place of the default "HTTP {method}". The
+// request it receives carries the context the span is started from, including any values set by
+// WithRequestContextFunc. Spans named by fn are not renamed after the matched route.
+func WithServerSpanNameFormatter(fn func(r *stdhttp.Request) string) ServerOption {
+	return func(s *Server) error {
+		if fn == nil {
+			return errors.New("span name formatter must not be nil")
+		}
+		s.spanName = fn
+		return nil
+	}
+}
+
+// WithQueueTimeMetrics records how long each request waited between its connection being accepted (or, for
+// later requests on a kept-alive connection, becoming active again) and its handler starting, in the
+// http.server.request.queue_time histogram. A growing queue time means the server is saturated rather than
+// its handlers being slow. Requests multiplexed on an already-active HTTP/2 connection are not recorded.
+func WithQueueTimeMetrics() ServerOption {
+	return func(s *Server) error {
+		s.queueTimeMetrics = true
+		return nil
+	}
+}
+
+// WithHTTP2MaxConcurrentStreams limits the number of concurrent streams (requests) a client may open on each
+// HTTP/2 connection, and records how close connections run to the limit, to make tuning it data-driven:
+//   - http.server.http2.streams_at_limit counts requests that brought a connection to the limit. Often
+//     hitting the limit means it is too low, as clients then queue requests or open more connections.
+//   - http.server.http2.peak_concurrent_streams records the peak concurrent requests of each HTTP/2
+//     connection when it closes.
+//
+// net/http does not expose refused streams or its own stream counts, so these are approximated from the
+// requests being handled on each connection. They undercount streams that are open but not yet handled.
+func WithHTTP2MaxConcurrentStreams(n int) ServerOption {
+	return func(s *Server) error {
+		if n < 1 {
+			return errors.New("HTTP/2 max concurrent streams must be at least 1")
+		}
+		if s.server.HTTP2 == nil {
+			s.server.HTTP2 = &stdhttp.HTTP2Config{}
+		}
+		s.server.HTTP2.MaxConcurrentStreams = n
+		s.http2MaxStreams = n
+		return nil
+	}
+}
+
+// WithHeaderSizeMetrics records the size of request and response headers in the
+// http.server.request.header.size and http.server.response.header.size histograms, to help diagnose
+// clients sending pathological headers (e.g. giant cookies) and handlers adding excessive ones.
+//
+// Sizes are computed from the headers as serialized on the wire ("Key: Value\r\n"). The response size is
+// computed from the headers set by the handler when they are committed, and excludes those net/http adds
+// itself (e.g. Date and Content-Length).
+func WithHeaderSizeMetrics() ServerOption {
+	return func(s *Server) error {
+		s.headerSizeMetrics = true
+		return nil
+	}
+}
+
+// WithServerRequirePropagator makes NewServer return ErrNoopPropagator if no text map propagator has been
+// configured, in which case incoming trace context would be silently ignored. By default this is not
+// checked.
+func WithServerRequirePropagator() ServerOption {
+	return func(s *Server) error {
+		s.requirePropagator = true
+		return nil
+	}
+}
+
 // WithReadTimeout sets the ReadTimeout.
 func WithReadTimeout(d time.Duration) ServerOption {
 	return func(s *Server) error {
@@ -75,9 +471,102 @@ func WithIdleTimeout(d time.Duration) ServerOption {
 	}
 }
 
+// WithReadHeaderTimeout sets the ReadHeaderTimeout, bounding how long a client may take to send the request
+// headers. This guards against slowloris attacks, which hold connections open by trickling headers.
+func WithReadHeaderTimeout(d time.Duration) ServerOption {
+	return func(s *Server) error {
+		s.server.ReadHeaderTimeout = d
+		return nil
+	}
+}
+
+// WithMaxHeaderBytes sets the MaxHeaderBytes, the maximum size of the request line and headers. Requests with
+// larger headers are rejected with a 431 Request Header Fields Too Large.
+func WithMaxHeaderBytes(n int) ServerOption {
+	return func(s *Server) error {
+		if n < 0 {
+			return errors.New("max header bytes must not be negative")
+		}
+		s.server.MaxHeaderBytes = n
+		return nil
+	}
+}
+
+// defaultShutdownTimeout is how long Run waits for graceful shutdown when no timeout is configured.
+const defaultShutdownTimeout = 5 * time.Second
+
+// ErrShutdownTimeout is returned by Run when graceful shutdown does not finish within the shutdown timeout,
+// and the remaining connections are closed.
+var ErrShutdownTimeout = errors.New("http: graceful shutdown timed out")
+
+// WithShutdownTimeout sets how long Run waits (by default 5s) for in-flight requests and background tasks to
+// finish during graceful shutdown. Once it passes, the remaining connections are closed and Run returns an
+// error wrapping ErrShutdownTimeout.
+func WithShutdownTimeout(d time.Duration) ServerOption {
+	return func(s *Server) error {
+		if d <= 0 {
+			return errors.New("shutdown timeout must be positive")
+		}
+		s.shutdownTimeout = d
+		return nil
+	}
+}
+
+// WithRejectOnShutdown configures the server to respond to new requests with a 503 once shutdown
+// has begun, rather than serving them. In-flight requests are allowed to complete. The response
+// carries "Connection: close" and a "Retry-After" header so that clients and load balancers move on
+// to another instance while this one drains.
+func WithRejectOnShutdown() ServerOption {
+	return func(s *Server) error {
+		s.rejectOnShutdown = true
+		return nil
+	}
+}
+
+// WithHardDrain makes shutdown faster and stricter. By default, shutdown stops accepting connections and
+// closes idle ones, but connections that have been accepted and not yet sent a request are given a few
+// seconds to do so, and are served if they do. With a hard drain, as soon as shutdown begins:
+//   - The listener is closed, so new connections are refused by the operating system.
+//   - Keep-alives are disabled, closing idle connections, and connections that have been accepted but not
+//     yet started a request are closed.
+//   - Connections with a request in flight finish it, and are then closed.
+//
+// Shutdown then waits for the in-flight requests as usual.
+func WithHardDrain() ServerOption {
+	return func(s *Server) error {
+		s.hardDrain = true
+		return nil
+	}
+}
+
+// WithOnListen registers a function that is called with the address the server is listening on, once it is
+// listening and before it starts serving. Functions are called in the order they are registered. It is
+// useful for learning the port assigned when listening on port 0; see also Addr.
+func WithOnListen(fn func(net.Addr)) ServerOption {
+	return func(s *Server) error {
+		if fn == nil {
+			return errors.New("on listen function must not be nil")
+		}
+		s.onListen = append(s.onListen, fn)
+		return nil
+	}
+}
+
 // NewServer creates a new Server with defaults.
 // Defaults are defined in defaultServerOptions.
+//
+// The address is a TCP address such as ":8080", or the path of a Unix domain socket prefixed with "unix:",
+// such as "unix:/run/app.sock" (see WithUnixSocketMode).
 func NewServer(addr string, handler stdhttp.Handler, opts ...ServerOption) (*Server, error) {
+	return NewServerWithDefaults(addr, handler, defaultServerOptions, opts...)
+}
+
+// NewServerWithDefaults is like NewServer, but applies the given defaults in place of the package defaults.
+// The defaults are fully replaced, not augmented: any setting they don't cover keeps the net/http zero
+// value, and a nil slice applies no defaults at all.
+func NewServerWithDefaults(
+	addr string, handler stdhttp.Handler, defaults []ServerOption, opts ...ServerOption,
+) (*Server, error) {
 	srv := &stdhttp.Server{
 		Addr:    addr,
 		Handler: handler,
@@ -86,7 +575,7 @@ func NewServer(addr string, handler stdhttp.Handler, opts ...ServerOption) (*Ser
 	s := &Server{server: srv}
 
 	// Apply defaults
-	for _, opt := range defaultServerOptions {
+	for _, opt := range defaults {
 		if err := opt(s); err != nil {
 			return nil, err
 		}
@@ -99,6 +588,16 @@ func NewServer(addr string, handler stdhttp.Handler, opts ...ServerOption) (*Ser
 		}
 	}
 
+	if s.server.TLSConfig != nil && !hasCertificate(s.server.TLSConfig) {
+		return nil, errors.New("server TLS config must provide a certificate")
+	}
+
+	if s.requirePropagator {
+		if err := checkPropagator(s.propagator); err != nil {
+			return nil, err
+		}
+	}
+
 	// Finalize instrumentation
 	if s.tracer == nil {
 		s.tracer = otel.GetTracerProvider().Tracer(instrumentationName)
@@ -116,61 +615,383 @@ func NewServer(addr string, handler stdhttp.Handler, opts ...ServerOption) (*Ser
 	if err != nil {
 		return nil, err
 	}
+	s.mDuration, err = s.meter.Float64Histogram("http.server.request.duration", metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+
+	s.mRejectedConnections, err = s.meter.Int64Counter("http.server.rejected_connections")
+	if err != nil {
+		return nil, err
+	}
+
+	s.mBodyReadTimeouts, err = s.meter.Int64Counter("http.server.request.body_read_timeouts")
+	if err != nil {
+		return nil, err
+	}
+
+	if s.maxRequestBodySize > 0 {
+		s.mBodyTooLarge, err = s.meter.Int64Counter("http.server.body_too_large")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.mPanics, err = s.meter.Int64Counter("http.server.panics")
+	if err != nil {
+		return nil, err
+	}
+
+	if s.headerSizeMetrics {
+		s.mRequestHeaderSize, err = s.meter.Int64Histogram("http.server.request.header.size", metric.WithUnit("By"))
+		if err != nil {
+			return nil, err
+		}
+		s.mResponseHeaderSize, err = s.meter.Int64Histogram("http.server.response.header.size", metric.WithUnit("By"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if s.middlewareTiming {
+		s.mMiddlewareDuration, err = s.meter.Float64Histogram("http.server.middleware.duration", metric.WithUnit("s"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if s.queueTimeMetrics {
+		s.mQueueTime, err = s.meter.Float64Histogram("http.server.request.queue_time", metric.WithUnit("s"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if s.malformedRequestMetrics {
+		s.mMalformedRequests, err = s.meter.Int64Counter("http.server.malformed_requests")
+		if err != nil {
+			return nil, err
+		}
+		s.malformedClients = newBoundedValues(maxMalformedClients)
+		s.server.ErrorLog = log.New(&errorLogWriter{s: s, next: s.server.ErrorLog}, "", 0)
+	}
+
+	if s.cors != nil {
+		s.mCORSPreflight, err = s.meter.Int64Counter("http.server.cors.preflight_requests")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if s.rateLimit != nil {
+		if s.rateLimit.key == nil {
+			s.rateLimit.key = s.clientAddress.host
+		}
+		s.mRateLimited, err = s.meter.Int64Counter("http.server.rate_limited")
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	s.server.ConnState = func(c net.Conn, cs stdhttp.ConnState) {
-		switch cs {
-		case stdhttp.StateNew:
-			s.mOpenConnections.Add(context.Background(), 1)
-		case stdhttp.StateClosed, stdhttp.StateHijacked:
-			s.mOpenConnections.Add(context.Background(), -1)
+	if s.http2MaxStreams > 0 {
+		s.mStreamsAtLimit, err = s.meter.Int64Counter("http.server.http2.streams_at_limit")
+		if err != nil {
+			return nil, err
+		}
+		s.mPeakStreams, err = s.meter.Int64Histogram("http.server.http2.peak_concurrent_streams")
+		if err != nil {
+			return nil, err
 		}
 	}
 
+	s.server.ConnState = s.connState
+	s.server.ConnContext = s.connContext
+
 	// Wrap handler
 	if srv.Handler == nil {
 		srv.Handler = stdhttp.DefaultServeMux
 	}
-	s.server.Handler = &instrumentedHandler{
-		base:            srv.Handler,
-		tracer:          s.tracer,
-		meter:           s.meter,
-		mActiveRequests: s.mActiveRequests,
+	srv.Handler = chainMiddleware(srv.Handler, s.middleware, s.mMiddlewareDuration)
+	if s.handlerTimeout > 0 {
+		srv.Handler = stdhttp.TimeoutHandler(srv.Handler, s.handlerTimeout, s.handlerTimeoutMessage)
+	}
+	ih := &instrumentedHandler{
+		base:                srv.Handler,
+		tracer:              s.tracer,
+		meter:               s.meter,
+		mActiveRequests:     s.mActiveRequests,
+		activeRequests:      &s.activeRequests,
+		mDuration:           s.mDuration,
+		contentTypeAttrs:    s.contentTypeAttrs,
+		bodySizeAttrs:       s.bodySizeAttrs,
+		requestHeaders:      s.requestHeaders,
+		responseHeaders:     s.responseHeaders,
+		baggageKeys:         s.baggageKeys,
+		requestIDHeader:     s.requestIDHeader,
+		forceSampleHeader:   s.forceSampleHeader,
+		compression:         s.compression,
+		propagator:          s.propagator,
+		bodyReadTimeout:     s.bodyReadTimeout,
+		readTimeout:         s.server.ReadTimeout,
+		requestDeadline:     s.requestDeadline,
+		mBodyReadTimeouts:   s.mBodyReadTimeouts,
+		maxRequestBodySize:  s.maxRequestBodySize,
+		mBodyTooLarge:       s.mBodyTooLarge,
+		streamDeadline:      s.streamDeadline,
+		writeTimeout:        s.server.WriteTimeout,
+		mRequestHeaderSize:  s.mRequestHeaderSize,
+		mResponseHeaderSize: s.mResponseHeaderSize,
+		background:          &s.background,
+		clientAddress:       s.clientAddress,
+		mQueueTime:          s.mQueueTime,
+		logger:              s.logger,
+		mPanics:             s.mPanics,
+		http2MaxStreams:     s.http2MaxStreams,
+		mStreamsAtLimit:     s.mStreamsAtLimit,
+		requestContext:      s.requestContext,
+		spanName:            s.spanName,
+		ignore:              s.ignore,
+		rateLimit:           s.rateLimit,
+		mRateLimited:        s.mRateLimited,
+	}
+	if s.rejectOnShutdown {
+		ih.shuttingDown = &s.shuttingDown
+	}
+	s.server.Handler = ih
+	if s.cors != nil {
+		// Preflight requests are answered ahead of the instrumentation; others carry the CORS headers into it.
+		s.server.Handler = &corsHandler{next: s.server.Handler, policy: s.cors, mPreflight: s.mCORSPreflight}
+	}
+	if s.livePath != "" || s.readyPath != "" {
+		s.server.Handler = &healthHandler{
+			next: s.server.Handler, livePath: s.livePath, readyPath: s.readyPath, ready: s.ReadinessHandler(),
+		}
 	}
 
 	return s, nil
 }
 
-// Run starts the server and waits for a signal to shutdown.
+// connState performs the built-in connection accounting before calling any registered hooks.
+func (s *Server) connState(c net.Conn, cs stdhttp.ConnState) {
+	if s.hardDrain {
+		if cs == stdhttp.StateNew {
+			s.newConns.Store(c, struct{}{})
+		} else {
+			s.newConns.Delete(c)
+		}
+	}
+
+	switch cs {
+	case stdhttp.StateNew:
+		s.mOpenConnections.Add(context.Background(), 1)
+		if n := s.openConns.Add(1); s.maxOpenConns > 0 && n > int64(s.maxOpenConns) {
+			// Closing the connection causes net/http to transition it to StateClosed, which releases
+			// its slot again.
+			s.mRejectedConnections.Add(context.Background(), 1)
+			_ = c.Close()
+		}
+	case stdhttp.StateActive:
+		if v, ok := s.conns.Load(c); ok {
+			ci := v.(*connInfo)
+			ci.activeAt.Store(time.Now().UnixNano())
+			ci.awaitingHandler.Store(true)
+		}
+	case stdhttp.StateClosed, stdhttp.StateHijacked:
+		s.mOpenConnections.Add(context.Background(), -1)
+		s.openConns.Add(-1)
+		if v, ok := s.conns.LoadAndDelete(c); ok {
+			ci := v.(*connInfo)
+			if peak := ci.peakStreams.Load(); peak > 0 && s.mPeakStreams != nil {
+				s.mPeakStreams.Record(context.Background(), peak)
+			}
+			if ci.awaitingHandler.Load() && cs == stdhttp.StateClosed && s.mMalformedRequests != nil {
+				s.recordMalformedRequest(malformedUnhandled, c.RemoteAddr().String())
+			}
+		}
+	}
+
+	for _, hook := range s.connStateHooks {
+		hook(c, cs)
+	}
+}
+
+// Run starts the server and shuts it down gracefully on SIGINT or SIGTERM. It is RunContext with a context
+// cancelled by those signals.
 func (s *Server) Run() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return s.RunContext(ctx)
+}
+
+// RunContext starts the server and shuts it down gracefully once ctx is done, so that the server's lifecycle
+// can be tied to the rest of the process (for example, with an errgroup). It returns nil after a clean
+// shutdown, or the error that stopped the server.
+func (s *Server) RunContext(ctx context.Context) error {
+	ln, err := s.listen()
+	if err != nil {
+		return fmt.Errorf("server error: %w", err)
+	}
+	return s.ServeContext(ctx, ln)
+}
+
+// Serve is like Run, but serves connections accepted on ln rather than listening on the server's address. It
+// is useful for sockets created elsewhere, such as by systemd socket activation, and for tests that listen
+// on port 0 and need to know the port assigned. The listener is closed when Serve returns.
+func (s *Server) Serve(ln net.Listener) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return s.ServeContext(ctx, ln)
+}
+
+// ServeContext is like RunContext, but serves connections accepted on ln rather than listening on the
+// server's address. The listener is closed when ServeContext returns.
+func (s *Server) ServeContext(ctx context.Context, ln net.Listener) error {
+	if ln == nil {
+		return errors.New("listener must not be nil")
+	}
+
 	// Channel to listen for errors coming from the listener.
 	serverErrors := make(chan error, 1)
 
+	if s.certs != nil {
+		reloadCtx, stopReloading := context.WithCancel(ctx)
+		defer stopReloading()
+		s.reloadCertificateOnSignal(reloadCtx)
+	}
+
+	// The listener is tracked before serving starts, so that Addr is set once the server is listening.
+	ln = s.trackListener(ln)
+	for _, fn := range s.onListen {
+		fn(ln.Addr())
+	}
+
 	go func() {
-		if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, stdhttp.ErrServerClosed) {
+		if err := s.serveTracked(ln); err != nil && !errors.Is(err, stdhttp.ErrServerClosed) {
 			serverErrors <- err
 		}
 	}()
 
-	// Channel to listen for an interrupt or terminate signal from the OS.
-	shutdown := make(chan os.Signal, 1)
-	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
-
 	select {
 	case err := <-serverErrors:
 		return fmt.Errorf("server error: %w", err)
 
-	case sig := <-shutdown:
-		// Graceful shutdown
-		// We'll use a timeout for the shutdown itself.
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-
+	case <-ctx.Done():
 		// Ask the server to shutdown gracefully.
-		if err := s.server.Shutdown(ctx); err != nil {
-			// We return that error.
-			return fmt.Errorf("could not stop server gracefully: %w (signal: %v)", err, sig)
+		if err := s.gracefulShutdown(); err != nil {
+			return fmt.Errorf("could not stop server gracefully: %w", err)
 		}
 	}
 
 	return nil
 }
+
+// gracefulShutdown shuts the server down within the shutdown timeout, closing any connections that remain
+// once it passes.
+func (s *Server) gracefulShutdown() error {
+	timeout := s.shutdownTimeout
+	if timeout == 0 {
+		timeout = defaultShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := s.shutdown(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	err = fmt.Errorf("%w after %v: %w", ErrShutdownTimeout, timeout, err)
+	if closeErr := s.server.Close(); closeErr != nil {
+		return errors.Join(err, fmt.Errorf("closing server: %w", closeErr))
+	}
+	return err
+}
+
+// Addr returns the address the server is listening on, or nil if it has not started listening. This is the
+// address actually bound, such as the port assigned when listening on port 0.
+func (s *Server) Addr() net.Addr {
+	s.listenerMu.Lock()
+	defer s.listenerMu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// trackListener wraps ln so that it can be closed both by a drain and by shutdown, and keeps hold of it so
+// that they can.
+func (s *Server) trackListener(ln net.Listener) net.Listener {
+	ln = &onceCloseListener{Listener: ln}
+	s.listenerMu.Lock()
+	s.listener = ln
+	s.listenerMu.Unlock()
+	return ln
+}
+
+// serve serves connections accepted on ln, keeping hold of it so that shutdown can close it.
+func (s *Server) serve(ln net.Listener) error {
+	return s.serveTracked(s.trackListener(ln))
+}
+
+// serveTracked serves connections accepted on a listener returned by trackListener.
+func (s *Server) serveTracked(ln net.Listener) error {
+	if s.server.TLSConfig != nil {
+		// The certificate comes from the TLS config, rather than from files.
+		return s.server.ServeTLS(ln, "", "")
+	}
+	return s.server.Serve(ln)
+}
+
+// stopAccepting closes the listener and the connections that are yet to start a request, and disables
+// keep-alives so that connections close once their current request completes.
+func (s *Server) stopAccepting() error {
+	s.listenerMu.Lock()
+	ln := s.listener
+	s.listenerMu.Unlock()
+	if ln != nil {
+		if err := ln.Close(); err != nil {
+			return err
+		}
+	}
+
+	s.server.SetKeepAlivesEnabled(false)
+	s.newConns.Range(func(c, _ any) bool {
+		_ = c.(net.Conn).Close()
+		return true
+	})
+	return nil
+}
+
+// onceCloseListener wraps a net.Listener so that it can be closed both by a drain and by
+// http.Server.Shutdown, which reports an error if it is already closed.
+type onceCloseListener struct {
+	net.Listener
+	once sync.Once
+	err  error
+}
+
+func (l *onceCloseListener) Close() error {
+	l.once.Do(func() { l.err = l.Listener.Close() })
+	return l.err
+}
+
+// shutdown marks the server as shutting down and then gracefully stops it, waiting for in-flight
+// requests and then background tasks (see WithBackgroundTask) to complete, or for ctx to expire.
+func (s *Server) shutdown(ctx context.Context) error {
+	s.shuttingDown.Store(true)
+	if s.hardDrain {
+		if err := s.stopAccepting(); err != nil {
+			return fmt.Errorf("closing listener: %w", err)
+		}
+	}
+	if err := s.server.Shutdown(ctx); err != nil {
+		return err
+	}
+
+	// A handler can still be running after Shutdown, so tasks registered from here on aren't waited for.
+	select {
+	case <-s.background.wait():
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for background tasks: %w", ctx.Err())
+	}
+}
@@ -1,8 +1,25 @@
 package http
 
 import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
 )
 
 func TestNewServer_Defaults(t *testing.T) {
@@ -20,6 +37,12 @@ func TestNewServer_Defaults(t *testing.T) {
 	if s.server.IdleTimeout != 2*time.Second {
 		t.Errorf("expected default IdleTimeout 2s, got %v", s.server.IdleTimeout)
 	}
+	if s.server.ReadHeaderTimeout != time.Second {
+		t.Errorf("expected default ReadHeaderTimeout 1s, got %v", s.server.ReadHeaderTimeout)
+	}
+	if s.server.MaxHeaderBytes != 64<<10 {
+		t.Errorf("expected default MaxHeaderBytes 64KiB, got %d", s.server.MaxHeaderBytes)
+	}
 }
 
 func TestNewServer_Options(t *testing.T) {
@@ -27,6 +50,8 @@ func TestNewServer_Options(t *testing.T) {
 		WithReadTimeout(100*time.Millisecond),
 		WithWriteTimeout(200*time.Millisecond),
 		WithIdleTimeout(300*time.Millisecond),
+		WithReadHeaderTimeout(400*time.Millisecond),
+		WithMaxHeaderBytes(8<<10),
 	)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -41,4 +66,872 @@ func TestNewServer_Options(t *testing.T) {
 	if s.server.IdleTimeout != 300*time.Millisecond {
 		t.Errorf("expected IdleTimeout 300ms, got %v", s.server.IdleTimeout)
 	}
+	if s.server.ReadHeaderTimeout != 400*time.Millisecond {
+		t.Errorf("expected ReadHeaderTimeout 400ms, got %v", s.server.ReadHeaderTimeout)
+	}
+	if s.server.MaxHeaderBytes != 8<<10 {
+		t.Errorf("expected MaxHeaderBytes 8KiB, got %d", s.server.MaxHeaderBytes)
+	}
+
+	if _, err := NewServer(":0", nil, WithMaxHeaderBytes(-1)); err == nil {
+		t.Error("expected an error for negative max header bytes")
+	}
+}
+
+func TestNewServerWithDefaults(t *testing.T) {
+	s, err := NewServerWithDefaults(":0", nil, []ServerOption{WithReadTimeout(10 * time.Second)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if s.server.ReadTimeout != 10*time.Second {
+		t.Errorf("expected custom default ReadTimeout 10s, got %v", s.server.ReadTimeout)
+	}
+	// The package defaults are replaced, not augmented.
+	if s.server.WriteTimeout != 0 {
+		t.Errorf("expected WriteTimeout to be unset, got %v", s.server.WriteTimeout)
+	}
+}
+
+func TestServer_RejectOnShutdown(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		if r.URL.Path == "/slow" {
+			close(started)
+			<-release
+		}
+		w.WriteHeader(stdhttp.StatusOK)
+	})
+
+	s, err := NewServer(":0", handler, WithRejectOnShutdown())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	inflight := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.server.Handler.ServeHTTP(inflight, httptest.NewRequest("GET", "/slow", nil))
+	}()
+	<-started
+
+	if err := s.shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected shutdown error: %v", err)
+	}
+
+	rejected := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rejected, httptest.NewRequest("GET", "/new", nil))
+
+	if rejected.Code != stdhttp.StatusServiceUnavailable {
+		t.Errorf("expected 503 for new request, got %d", rejected.Code)
+	}
+	if got := rejected.Header().Get("Connection"); got != "close" {
+		t.Errorf("expected Connection: close, got %q", got)
+	}
+	if got := rejected.Header().Get("Retry-After"); got == "" {
+		t.Error("expected Retry-After header to be set")
+	}
+
+	close(release)
+	<-done
+
+	if inflight.Code != stdhttp.StatusOK {
+		t.Errorf("expected in-flight request to complete with 200, got %d", inflight.Code)
+	}
+}
+
+func TestServer_ServesDuringShutdownByDefault(t *testing.T) {
+	s, err := NewServer(":0", stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		w.WriteHeader(stdhttp.StatusOK)
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := s.shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected shutdown error: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != stdhttp.StatusOK {
+		t.Errorf("expected 200 without WithRejectOnShutdown, got %d", w.Code)
+	}
+}
+
+func TestServer_MaxOpenConnections(t *testing.T) {
+	states := make(chan stdhttp.ConnState, 10)
+	s, err := NewServer(":0", nil,
+		WithMaxOpenConnections(1),
+		WithConnStateHook(func(_ net.Conn, cs stdhttp.ConnState) {
+			states <- cs
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	go func() { _ = s.server.Serve(l) }()
+	defer func() { _ = s.shutdown(context.Background()) }()
+
+	first, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = first.Close() }()
+	if cs := <-states; cs != stdhttp.StateNew {
+		t.Fatalf("expected hook to observe StateNew, got %v", cs)
+	}
+
+	second, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = second.Close() }()
+
+	_ = second.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := second.Read(make([]byte, 1)); err == nil {
+		t.Error("expected connection over the limit to be closed")
+	} else if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		t.Error("expected connection over the limit to be closed, but it stayed open")
+	}
+}
+
+func TestServer_BodyReadTimeout(t *testing.T) {
+	handler := stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			w.WriteHeader(stdhttp.StatusRequestTimeout)
+			return
+		}
+		w.WriteHeader(stdhttp.StatusOK)
+	})
+
+	s, err := NewServer(":0", handler, WithBodyReadTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ts := httptest.NewServer(s.server.Handler)
+	defer ts.Close()
+
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	// Promise a 10 byte body, but only send one byte of it.
+	_, err = io.WriteString(conn, "POST / HTTP/1.1\r\nHost: example\r\nContent-Length: 10\r\n\r\nx")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	resp, err := stdhttp.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("unexpected error reading response: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != stdhttp.StatusRequestTimeout {
+		t.Errorf("expected 408, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_BodyReadTimeout_ReadTimeout(t *testing.T) {
+	handler := stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			w.WriteHeader(stdhttp.StatusRequestTimeout)
+			return
+		}
+		w.WriteHeader(stdhttp.StatusOK)
+	})
+
+	// The shorter ReadTimeout must still apply, rather than being extended by the body read timeout.
+	s, err := NewServer(":0", handler, WithReadTimeout(50*time.Millisecond), WithBodyReadTimeout(5*time.Second))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ts := httptest.NewUnstartedServer(s.server.Handler)
+	ts.Config.ReadTimeout = s.server.ReadTimeout
+	ts.Start()
+	defer ts.Close()
+
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	start := time.Now()
+	_, err = io.WriteString(conn, "POST / HTTP/1.1\r\nHost: example\r\nContent-Length: 10\r\n\r\nx")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(4 * time.Second))
+	resp, err := stdhttp.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("unexpected error reading response: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != stdhttp.StatusRequestTimeout {
+		t.Errorf("expected 408, got %d", resp.StatusCode)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the read to time out with ReadTimeout, took %s", elapsed)
+	}
+}
+
+func TestServer_HandlerTimeout(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+
+	handlerDone := make(chan struct{})
+	handler := stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		if r.URL.Path == "/fast" {
+			_, _ = io.WriteString(w, "ok")
+			return
+		}
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+		_, _ = io.WriteString(w, "too late")
+		close(handlerDone)
+	})
+
+	s, err := NewServer(":0", handler,
+		WithServerTracerProvider(tp),
+		WithServerHandlerTimeout(50*time.Millisecond, "handler timed out"),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, httptest.NewRequest(stdhttp.MethodGet, "/slow", nil))
+	if w.Code != stdhttp.StatusServiceUnavailable {
+		t.Errorf("expected 503, got %d", w.Code)
+	}
+	if w.Body.String() != "handler timed out" {
+		t.Errorf("expected the timeout message, got %q", w.Body.String())
+	}
+	<-handlerDone
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if !hasAttr(spans[0].Attributes, semconv.HTTPResponseStatusCodeKey.Int(stdhttp.StatusServiceUnavailable)) {
+		t.Errorf("expected the span to record the 503, got %v", spans[0].Attributes)
+	}
+
+	w = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, httptest.NewRequest(stdhttp.MethodGet, "/fast", nil))
+	if w.Code != stdhttp.StatusOK || w.Body.String() != "ok" {
+		t.Errorf("expected fast handlers to respond as usual, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestWithServerHandlerTimeout_Invalid(t *testing.T) {
+	if _, err := NewServer(":0", nil, WithServerHandlerTimeout(0, "")); err == nil {
+		t.Error("expected an error for a zero handler timeout")
+	}
+}
+
+func TestServer_RequestDeadline(t *testing.T) {
+	backendCancelled := make(chan struct{})
+	backend := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		select {
+		case <-r.Context().Done():
+			close(backendCancelled)
+		case <-time.After(time.Second):
+		}
+	}))
+	defer backend.Close()
+
+	client, err := NewClient()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+	var clientErr error
+	handler := stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		if _, ok := r.Context().Deadline(); !ok {
+			t.Error("expected the request context to have a deadline")
+		}
+		// The outbound request inherits the deadline from the request context.
+		req, _ := stdhttp.NewRequestWithContext(r.Context(), stdhttp.MethodGet, backend.URL, nil)
+		resp, err := client.Do(req)
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		clientErr = err
+		w.WriteHeader(stdhttp.StatusGatewayTimeout)
+	})
+
+	s, err := NewServer(":0", handler, WithServerTracerProvider(tp), WithRequestDeadline(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	start := time.Now()
+	s.server.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(stdhttp.MethodGet, "/", nil))
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf("expected the outbound request to be abandoned at the deadline, took %v", elapsed)
+	}
+	if !errors.Is(clientErr, context.DeadlineExceeded) {
+		t.Errorf("expected the outbound request to fail with the deadline, got %v", clientErr)
+	}
+	select {
+	case <-backendCancelled:
+	case <-time.After(time.Second):
+		t.Error("expected the backend to see the outbound request cancelled")
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || !hasAttr(spans[0].Attributes, attribute.Bool("http.server.deadline_exceeded", true)) {
+		t.Errorf("expected the span to record the exceeded deadline, got %+v", spans)
+	}
+}
+
+func TestServer_RequestDeadline_Met(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+	s, err := NewServer(":0", stdhttp.NotFoundHandler(), WithServerTracerProvider(tp), WithRequestDeadline(time.Second))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.server.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(stdhttp.MethodGet, "/", nil))
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	for _, a := range spans[0].Attributes {
+		if a.Key == "http.server.deadline_exceeded" {
+			t.Errorf("expected no exceeded deadline to be recorded, got %v", a.Value.Emit())
+		}
+	}
+
+	if _, err := NewServer(":0", nil, WithRequestDeadline(0)); err == nil {
+		t.Error("expected an error for a zero request deadline")
+	}
+}
+
+func TestServer_Panics(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		panicWith   any
+		wroteHeader bool
+		wantPanics  int64
+		wantAborted bool
+		wantStatus  int
+	}{
+		{name: "abort", panicWith: stdhttp.ErrAbortHandler, wantPanics: 0, wantAborted: true},
+		{name: "panic", panicWith: "boom", wantPanics: 1, wantStatus: stdhttp.StatusInternalServerError},
+		{name: "panic after headers", panicWith: "boom", wroteHeader: true, wantPanics: 1, wantAborted: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+			reader := sdkmetric.NewManualReader()
+			mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+			s, err := NewServer(":0", stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+				if tc.wroteHeader {
+					w.WriteHeader(stdhttp.StatusOK)
+				}
+				panic(tc.panicWith)
+			}), WithServerTracerProvider(tp), WithServerMeterProvider(mp))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			rec := httptest.NewRecorder()
+			func() {
+				// Aborts are passed on for net/http to handle; other panics are recovered.
+				defer func() {
+					v := recover()
+					if tc.wantAborted && v != stdhttp.ErrAbortHandler { //nolint:errorlint // as net/http compares.
+						t.Errorf("expected the response to be aborted, got %v", v)
+					}
+					if !tc.wantAborted && v != nil {
+						t.Errorf("expected the panic to be recovered, got %v", v)
+					}
+				}()
+				s.server.Handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+			}()
+
+			if tc.wantStatus != 0 && rec.Code != tc.wantStatus {
+				t.Errorf("expected %d, got %d", tc.wantStatus, rec.Code)
+			}
+			if got := sumCounter(t, reader, "http.server.panics"); got != tc.wantPanics {
+				t.Errorf("expected %d panics counted, got %d", tc.wantPanics, got)
+			}
+			span := exporter.GetSpans()[0]
+			if got := hasAttr(span.Attributes, attribute.Bool("http.server.aborted", true)); got != tc.wantAborted {
+				t.Errorf("expected aborted attribute %v, got %v", tc.wantAborted, got)
+			}
+			if wantError := tc.wantPanics > 0; (span.Status.Code == codes.Error) != wantError {
+				t.Errorf("expected error status %v, got %v", wantError, span.Status.Code)
+			}
+			if tc.wantStatus != 0 && !hasAttr(span.Attributes, semconv.HTTPResponseStatusCodeKey.Int(tc.wantStatus)) {
+				t.Errorf("expected the span to record status %d", tc.wantStatus)
+			}
+		})
+	}
+}
+
+type tenantKey struct{}
+
+// tenantSampler samples only requests for the "sampled" tenant.
+type tenantSampler struct{}
+
+func (tenantSampler) ShouldSample(p trace.SamplingParameters) trace.SamplingResult {
+	if p.ParentContext.Value(tenantKey{}) == "sampled" {
+		return trace.SamplingResult{Decision: trace.RecordAndSample}
+	}
+	return trace.SamplingResult{Decision: trace.Drop}
+}
+
+func (tenantSampler) Description() string { return "tenantSampler" }
+
+func TestServer_RequestContextFunc(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter), trace.WithSampler(tenantSampler{}))
+
+	var seen any
+	s, err := NewServer(":0", stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		seen = r.Context().Value(tenantKey{})
+	}),
+		WithServerTracerProvider(tp),
+		WithRequestContextFunc(func(ctx context.Context, r *stdhttp.Request) context.Context {
+			tenant, _, _ := strings.Cut(r.Host, ".")
+			return context.WithValue(ctx, tenantKey{}, tenant)
+		}),
+		WithServerSpanNameFormatter(func(r *stdhttp.Request) string {
+			return "HTTP " + r.Method + " " + r.Context().Value(tenantKey{}).(string)
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, host := range []string{"sampled.example.com", "dropped.example.com"} {
+		req := httptest.NewRequest(stdhttp.MethodGet, "/", nil)
+		req.Host = host
+		s.server.Handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if seen != "dropped" {
+		t.Errorf("expected the handler to see the derived context, got %v", seen)
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected the sampler to see the derived context, got %d spans", len(spans))
+	}
+	if got := spans[0].Name; got != "HTTP GET sampled" {
+		t.Errorf("expected the span name formatter to see the derived context, got %q", got)
+	}
+}
+
+func TestServer_RequestContextFunc_Nil(t *testing.T) {
+	if _, err := NewServer(":0", stdhttp.NotFoundHandler(), WithRequestContextFunc(nil)); err == nil {
+		t.Error("expected an error for a nil request context func")
+	}
+	if _, err := NewServer(":0", stdhttp.NotFoundHandler(), WithServerSpanNameFormatter(nil)); err == nil {
+		t.Error("expected an error for a nil span name formatter")
+	}
+}
+
+func TestServer_HardDrain(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	s, err := NewServer(":0", stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		if r.URL.Path == "/slow" {
+			close(started)
+			<-release
+		}
+	}), WithHardDrain())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	addr := l.Addr().String()
+	go func() { _ = s.serve(l) }()
+
+	// An accepted connection that has yet to send a request.
+	idle, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = idle.Close() }()
+
+	inFlight := make(chan *stdhttp.Response, 1)
+	go func() {
+		resp, err := stdhttp.Get("http://" + addr + "/slow")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		inFlight <- resp
+	}()
+	<-started
+
+	shutdownErr := make(chan error, 1)
+	go func() { shutdownErr <- s.shutdown(context.Background()) }()
+
+	// New connections are refused at the listener.
+	deadline := time.Now().Add(time.Second)
+	for {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			break
+		}
+		_ = conn.Close()
+		if time.Now().After(deadline) {
+			t.Fatal("expected new connections to be refused")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// The connection yet to send a request is closed straight away.
+	_ = idle.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := idle.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("expected the unused connection to be closed, got %v", err)
+	}
+
+	// The in-flight request completes, and the connection is then closed.
+	close(release)
+	if resp := <-inFlight; resp != nil {
+		if resp.StatusCode != stdhttp.StatusOK || !resp.Close {
+			t.Errorf("expected a 200 closing the connection, got %d (close %v)", resp.StatusCode, resp.Close)
+		}
+		_ = resp.Body.Close()
+	}
+	if err := <-shutdownErr; err != nil {
+		t.Errorf("unexpected shutdown error: %v", err)
+	}
+}
+
+func TestServer_ShutdownTimeout(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	s, err := NewServer(":0", stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		close(started)
+		<-release
+	}), WithShutdownTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	go func() { _ = s.serve(l) }()
+
+	reqErr := make(chan error, 1)
+	go func() {
+		resp, err := stdhttp.Get("http://" + l.Addr().String())
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		reqErr <- err
+	}()
+	<-started
+
+	start := time.Now()
+	err = s.gracefulShutdown()
+	if !errors.Is(err, ErrShutdownTimeout) {
+		t.Errorf("expected ErrShutdownTimeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected shutdown to give up after the timeout, took %v", elapsed)
+	}
+
+	// The stuck request's connection is closed rather than left open.
+	select {
+	case err := <-reqErr:
+		if err == nil {
+			t.Error("expected the request to fail when its connection was closed")
+		}
+	case <-time.After(time.Second):
+		t.Error("expected the request's connection to be closed")
+	}
+}
+
+func TestServer_ShutdownTimeout_Graceful(t *testing.T) {
+	s, err := NewServer(":0", stdhttp.NotFoundHandler())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.shutdownTimeout != 0 {
+		t.Errorf("expected the default shutdown timeout, got %v", s.shutdownTimeout)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	go func() { _ = s.serve(l) }()
+	if err := s.gracefulShutdown(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if _, err := NewServer(":0", stdhttp.NotFoundHandler(), WithShutdownTimeout(0)); err == nil {
+		t.Error("expected an error for a zero shutdown timeout")
+	}
+}
+
+func TestServer_RunContext(t *testing.T) {
+	s, err := NewServer("127.0.0.1:0", stdhttp.NotFoundHandler())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- s.RunContext(ctx) }()
+
+	// Wait for the server to start serving before asking it to stop.
+	listeningAddr(t, s)
+	cancel()
+
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected RunContext to return once the context was cancelled")
+	}
+
+	// A server that fails to start returns the error straight away.
+	s, err = NewServer("256.0.0.1:0", stdhttp.NotFoundHandler())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.RunContext(context.Background()); err == nil {
+		t.Error("expected an error for an address that can't be listened on")
+	}
+}
+
+func TestServer_ServeContext(t *testing.T) {
+	var newConns atomic.Int32
+	s, err := NewServer("", stdhttp.NotFoundHandler(), WithConnStateHook(func(_ net.Conn, cs stdhttp.ConnState) {
+		if cs == stdhttp.StateNew {
+			newConns.Add(1)
+		}
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- s.ServeContext(ctx, ln) }()
+
+	resp, err := stdhttp.Get("http://" + ln.Addr().String() + "/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != stdhttp.StatusNotFound {
+		t.Errorf("expected a 404 from the handler, got %d", resp.StatusCode)
+	}
+	if newConns.Load() == 0 {
+		t.Error("expected connections on the listener to be instrumented")
+	}
+
+	cancel()
+	select {
+	case err := <-serveErr:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected ServeContext to return once the context was cancelled")
+	}
+	if _, err := net.Dial("tcp", ln.Addr().String()); err == nil {
+		t.Error("expected the listener to be closed by the shutdown")
+	}
+
+	if err := s.ServeContext(context.Background(), nil); err == nil {
+		t.Error("expected an error for a nil listener")
+	}
+}
+
+func TestServer_Addr(t *testing.T) {
+	listening := make(chan net.Addr, 1)
+	s, err := NewServer("127.0.0.1:0", stdhttp.NotFoundHandler(), WithOnListen(func(addr net.Addr) {
+		listening <- addr
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if addr := s.Addr(); addr != nil {
+		t.Errorf("expected no address before listening, got %v", addr)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = s.RunContext(ctx) }()
+
+	var addr net.Addr
+	select {
+	case addr = <-listening:
+	case <-time.After(time.Second):
+		t.Fatal("expected the on listen function to be called")
+	}
+	if got := s.Addr(); got == nil || got.String() != addr.String() {
+		t.Errorf("expected Addr to return %v, got %v", addr, got)
+	}
+	if addr.(*net.TCPAddr).Port == 0 {
+		t.Error("expected the assigned port, not 0")
+	}
+
+	resp, err := stdhttp.Get("http://" + addr.String() + "/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if _, err := NewServer(":0", nil, WithOnListen(nil)); err == nil {
+		t.Error("expected an error for a nil on listen function")
+	}
+}
+
+func TestServer_RequestDuration(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	s, err := NewServer(":0", stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		w.WriteHeader(stdhttp.StatusCreated)
+	}), WithServerMeterProvider(mp))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s.server.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/", nil))
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	var found bool
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != "http.server.request.duration" {
+				continue
+			}
+			found = true
+			if m.Unit != "s" {
+				t.Errorf("expected unit s, got %q", m.Unit)
+			}
+			hist, ok := m.Data.(metricdata.Histogram[float64])
+			if !ok || len(hist.DataPoints) != 1 {
+				t.Fatalf("expected a single data point, got %+v", m.Data)
+			}
+			dp := hist.DataPoints[0]
+			if dp.Count != 1 {
+				t.Errorf("expected 1 measurement, got %d", dp.Count)
+			}
+			attrs := dp.Attributes.ToSlice()
+			if !hasAttr(attrs, semconv.HTTPRequestMethodKey.String("POST")) ||
+				!hasAttr(attrs, semconv.HTTPResponseStatusCodeKey.Int(stdhttp.StatusCreated)) {
+				t.Errorf("expected method and status code attributes, got %v", attrs)
+			}
+		}
+	}
+	if !found {
+		t.Error("expected http.server.request.duration to be recorded")
+	}
+}
+
+// listeningAddr waits for the server to start serving, returning the address it listens on.
+func listeningAddr(t *testing.T, s *Server) string {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		if addr := s.Addr(); addr != nil {
+			return addr.String()
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the server to start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestServer_IgnorePaths(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	var served int
+	s, err := NewServer(":0", stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		served++
+	}),
+		WithServerTracerProvider(tp),
+		WithServerMeterProvider(mp),
+		WithIgnorePaths("/metrics", "/debug/*"),
+		WithIgnoreFunc(func(r *stdhttp.Request) bool { return r.Header.Get("X-Probe") != "" }),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	probe := httptest.NewRequest("GET", "/users", nil)
+	probe.Header.Set("X-Probe", "1")
+	for _, r := range []*stdhttp.Request{
+		httptest.NewRequest("GET", "/metrics", nil),
+		httptest.NewRequest("GET", "/debug/pprof/heap", nil),
+		probe,
+	} {
+		s.server.Handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	if served != 3 {
+		t.Errorf("expected ignored requests to be served, got %d", served)
+	}
+	if spans := exporter.GetSpans(); len(spans) != 0 {
+		t.Errorf("expected no spans for ignored requests, got %d", len(spans))
+	}
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == "http.server.active_requests" || m.Name == "http.server.request.duration" {
+				t.Errorf("expected no %s for ignored requests", m.Name)
+			}
+		}
+	}
+
+	// Paths that only resemble ignored ones are instrumented.
+	for _, path := range []string{"/metrics/extra", "/debug"} {
+		s.server.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	if spans := exporter.GetSpans(); len(spans) != 2 {
+		t.Errorf("expected spans for other requests, got %d", len(spans))
+	}
 }
@@ -0,0 +1,110 @@
+package http
+
+import (
+	"context"
+	"errors"
+	stdhttp "net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// requestTimeoutKey is the context key for the timeout set with WithRequestTimeout.
+type requestTimeoutKey struct{}
+
+// WithRequestTimeout returns a copy of ctx that is done d from now, for a request that needs a different
+// deadline from the rest of the client's. The timeout is recorded as http.request.timeout (in seconds) on the
+// client span and on http.client.request.duration, so that durations can be read against their deadline.
+//
+// Client.Timeout (WithTimeout) still bounds the request, so a request timeout can only shorten it:
+//
+//	ctx, cancel := http.WithRequestTimeout(ctx, 200*time.Millisecond)
+//	defer cancel()
+//	req, err := stdhttp.NewRequestWithContext(ctx, stdhttp.MethodGet, url, nil)
+func WithRequestTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithValue(ctx, requestTimeoutKey{}, d), d)
+}
+
+// requestTimeoutAttrs returns the http.request.timeout attribute for a request timeout set on ctx, if any.
+func requestTimeoutAttrs(ctx context.Context) []attribute.KeyValue {
+	d, ok := ctx.Value(requestTimeoutKey{}).(time.Duration)
+	if !ok {
+		return nil
+	}
+	return []attribute.KeyValue{attribute.Float64("http.request.timeout", d.Seconds())}
+}
+
+// WithMethodTimeout sets a deadline for each attempt of a request with the given method, overriding the
+// client-wide deadlines for that method where it is shorter. For example, GETs can be made to fail quickly
+// while a client still allows slow POSTs to a reporting endpoint.
+//
+// The deadline covers the attempt from sending the request until its response body is closed, and is
+// applied per attempt: when used with WithRetry, each retry gets a fresh deadline. Client.Timeout
+// (WithTimeout) remains the outer ceiling, so a method timeout longer than it has no effect; set
+// WithTimeout(0) to rely on method timeouts alone.
+func WithMethodTimeout(method string, d time.Duration) ClientOption {
+	return func(c *stdhttp.Client) error {
+		if method == "" {
+			return errors.New("method must not be empty")
+		}
+		if d <= 0 {
+			return errors.New("method timeout must be positive")
+		}
+
+		mt := findMethodTimeoutTransport(c)
+		if mt == nil {
+			mt = &methodTimeoutTransport{timeouts: map[string]time.Duration{}}
+			wrapInnermost(c, func(base stdhttp.RoundTripper) stdhttp.RoundTripper {
+				mt.base = base
+				return mt
+			})
+		}
+		mt.timeouts[method] = d
+		return nil
+	}
+}
+
+// findMethodTimeoutTransport returns the client's methodTimeoutTransport, if it has one.
+func findMethodTimeoutTransport(c *stdhttp.Client) *methodTimeoutTransport {
+	rt := c.Transport
+	if it, ok := rt.(*InstrumentedTransport); ok {
+		rt = it.Base
+	}
+	for {
+		switch t := rt.(type) {
+		case *methodTimeoutTransport:
+			return t
+		case wrappingTransport:
+			rt = t.unwrap()
+		default:
+			return nil
+		}
+	}
+}
+
+// methodTimeoutTransport is a RoundTripper that applies a deadline to each request based on its method.
+type methodTimeoutTransport struct {
+	base     stdhttp.RoundTripper
+	timeouts map[string]time.Duration
+}
+
+func (t *methodTimeoutTransport) unwrap() stdhttp.RoundTripper {
+	return t.base
+}
+
+func (t *methodTimeoutTransport) setBase(base stdhttp.RoundTripper) {
+	t.base = base
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *methodTimeoutTransport) RoundTrip(req *stdhttp.Request) (*stdhttp.Response, error) {
+	d, ok := t.timeouts[req.Method]
+	if !ok {
+		return t.base.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), d)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	cancelWithBody(req, resp, cancel)
+	return resp, err
+}
@@ -0,0 +1,125 @@
+package http
+
+import (
+	"context"
+	"errors"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestWithMethodTimeout(t *testing.T) {
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(stdhttp.StatusOK)
+	}))
+	defer ts.Close()
+
+	c, err := NewClient(
+		WithMethodTimeout(stdhttp.MethodGet, 20*time.Millisecond),
+		WithMethodTimeout(stdhttp.MethodPost, time.Second),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := c.Get(ts.URL); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected GET to exceed its deadline, got %v", err)
+	}
+
+	resp, err := c.Post(ts.URL, "text/plain", strings.NewReader("report"))
+	if err != nil {
+		t.Fatalf("expected POST to succeed within its deadline, got %v", err)
+	}
+	_ = resp.Body.Close()
+}
+
+func TestWithMethodTimeout_PerAttempt(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		if calls.Add(1) == 1 {
+			time.Sleep(100 * time.Millisecond)
+		}
+		w.WriteHeader(stdhttp.StatusOK)
+	}))
+	defer ts.Close()
+
+	// The method timeout is placed beneath the retries regardless of option order, so the first attempt
+	// times out and the second gets a fresh deadline.
+	c, err := NewClient(WithRetry(2), WithMethodTimeout(stdhttp.MethodGet, 50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resp, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if got := calls.Load(); got != 2 {
+		t.Errorf("expected 2 attempts, got %d", got)
+	}
+}
+
+func TestWithMethodTimeout_Invalid(t *testing.T) {
+	if _, err := NewClient(WithMethodTimeout("", time.Second)); err == nil {
+		t.Error("expected error for empty method")
+	}
+	if _, err := NewClient(WithMethodTimeout(stdhttp.MethodGet, 0)); err == nil {
+		t.Error("expected error for non-positive timeout")
+	}
+}
+
+func TestWithRequestTimeout(t *testing.T) {
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(100 * time.Millisecond)
+		}
+	}))
+	defer ts.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	c, err := NewClient(WithTimeout(10*time.Second), WithClientMeterProvider(mp))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "parent-span")
+	ctx, cancel := WithRequestTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	req, _ := stdhttp.NewRequestWithContext(ctx, stdhttp.MethodGet, ts.URL+"/slow", nil)
+	if _, err := c.Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the request timeout to override the client timeout, got %v", err)
+	}
+	span.End()
+
+	timeout := attribute.Float64("http.request.timeout", 0.02)
+	if !hasAttr(exporter.GetSpans()[0].Attributes, timeout) {
+		t.Errorf("expected http.request.timeout on the span, got %v", exporter.GetSpans()[0].Attributes)
+	}
+	if got := histogramCountWithAttr(t, reader, "http.client.request.duration", timeout); got != 1 {
+		t.Errorf("expected 1 duration recorded with http.request.timeout, got %d", got)
+	}
+
+	// Requests without a request timeout aren't annotated.
+	resp, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+	if got := histogramCountWithAttr(t, reader, "http.client.request.duration", timeout); got != 1 {
+		t.Errorf("expected the request without a timeout not to be annotated, got %d", got)
+	}
+}
@@ -0,0 +1,117 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+)
+
+// WithServerTLS serves HTTPS with the certificate and key in the given PEM-encoded files, which are loaded
+// straight away. The files are loaded again when the process receives SIGHUP while Run or RunContext is
+// running, or when ReloadCertificate is called, so certificates can be rotated without a restart: new
+// connections use the new certificate, while existing ones carry on with the old.
+func WithServerTLS(certPath, keyPath string) ServerOption {
+	return func(s *Server) error {
+		certs := &certificateReloader{certPath: certPath, keyPath: keyPath}
+		if err := certs.reload(); err != nil {
+			return err
+		}
+
+		cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+		if s.server.TLSConfig != nil {
+			cfg = s.server.TLSConfig.Clone()
+		}
+		cfg.GetCertificate = certs.getCertificate
+		s.server.TLSConfig = cfg
+		s.certs = certs
+		return nil
+	}
+}
+
+// WithServerTLSConfig serves HTTPS with cfg, which must provide the server's certificate (with Certificates,
+// GetCertificate or GetConfigForClient) unless WithServerTLS does. The config is copied. If WithServerTLS
+// was applied first, its certificate is kept.
+func WithServerTLSConfig(cfg *tls.Config) ServerOption {
+	return func(s *Server) error {
+		if cfg == nil {
+			return errors.New("TLS config must not be nil")
+		}
+		cfg = cfg.Clone()
+		if s.certs != nil && !hasCertificate(cfg) {
+			cfg.GetCertificate = s.certs.getCertificate
+		}
+		s.server.TLSConfig = cfg
+		return nil
+	}
+}
+
+// hasCertificate reports whether cfg provides a server certificate.
+func hasCertificate(cfg *tls.Config) bool {
+	return len(cfg.Certificates) > 0 || cfg.GetCertificate != nil || cfg.GetConfigForClient != nil
+}
+
+// ReloadCertificate loads the certificate configured with WithServerTLS from its files again, for new
+// connections to use. If loading fails, the current certificate is kept.
+func (s *Server) ReloadCertificate() error {
+	if s.certs == nil {
+		return errors.New("server TLS is not configured")
+	}
+	return s.certs.reload()
+}
+
+// reloadCertificateOnSignal reloads the certificate each time the process receives SIGHUP, until ctx is done.
+// Failures are reported to the server's ErrorLog.
+func (s *Server) reloadCertificateOnSignal(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				if err := s.ReloadCertificate(); err != nil {
+					s.logf("http: reloading TLS certificate: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// logf writes to the server's ErrorLog, or the standard logger if it has none, as net/http does.
+func (s *Server) logf(format string, args ...any) {
+	if s.server.ErrorLog != nil {
+		s.server.ErrorLog.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// certificateReloader holds the current server certificate, which can be replaced while connections are being
+// accepted.
+type certificateReloader struct {
+	certPath, keyPath string
+	cert              atomic.Pointer[tls.Certificate]
+}
+
+// reload loads the certificate from its files, replacing the current one if it succeeds.
+func (r *certificateReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certPath, r.keyPath)
+	if err != nil {
+		return fmt.Errorf("loading server certificate: %w", err)
+	}
+	r.cert.Store(&cert)
+	return nil
+}
+
+// getCertificate implements tls.Config.GetCertificate.
+func (r *certificateReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
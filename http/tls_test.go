@@ -0,0 +1,156 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	stdhttp "net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+// servedCertificate returns the certificate the server at addr presents to a new connection.
+func servedCertificate(t *testing.T, addr string) *x509.Certificate {
+	t.Helper()
+	conn, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true}) //nolint:gosec // Only inspected.
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	return conn.ConnectionState().PeerCertificates[0]
+}
+
+func TestWithServerTLS_Reload(t *testing.T) {
+	dir := t.TempDir()
+	original, certPath, keyPath := writeCertificate(t, dir, "server")
+
+	s, err := NewServer("127.0.0.1:0", stdhttp.NotFoundHandler(), WithServerTLS(certPath, keyPath))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runErr := make(chan error, 1)
+	go func() { runErr <- s.RunContext(ctx) }()
+
+	addr := listeningAddr(t, s)
+
+	if got := servedCertificate(t, addr); !got.Equal(original) {
+		t.Fatal("expected the configured certificate to be served")
+	}
+
+	// Replace the files, and signal the server to reload them.
+	rotated, _, _ := writeCertificate(t, dir, "server")
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for !servedCertificate(t, addr).Equal(rotated) {
+		if time.Now().After(deadline) {
+			t.Fatal("expected new connections to use the rotated certificate")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	if err := <-runErr; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestWithServerTLS_Errors(t *testing.T) {
+	dir := t.TempDir()
+	_, certPath, keyPath := writeCertificate(t, dir, "server")
+
+	if _, err := NewServer(":0", nil, WithServerTLS(certPath, "missing.key")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a missing key to be reported, got %v", err)
+	}
+
+	s, err := NewServer(":0", nil, WithServerTLS(certPath, keyPath))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	current := s.certs.cert.Load()
+
+	// A failed reload keeps the current certificate.
+	if err := os.WriteFile(keyPath, []byte("not a key"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.ReloadCertificate(); err == nil {
+		t.Error("expected reloading an invalid key to fail")
+	}
+	if s.certs.cert.Load() != current {
+		t.Error("expected the current certificate to be kept")
+	}
+
+	fromConfig, err := NewServerFromConfig(":0", nil, ServerConfig{TLSCertFile: certPath, TLSKeyFile: "missing.key"})
+	if fromConfig != nil || err == nil {
+		t.Error("expected the config's certificate to be loaded")
+	}
+
+	s, err = NewServer(":0", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.ReloadCertificate(); err == nil {
+		t.Error("expected an error when TLS is not configured")
+	}
+}
+
+func TestWithServerTLSConfig(t *testing.T) {
+	_, certPath, keyPath := writeCertificate(t, t.TempDir(), "server")
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	s, err := NewServer("127.0.0.1:0", stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		if r.TLS == nil {
+			t.Error("expected the request to be served over TLS")
+		}
+	}), WithServerTLSConfig(&tls.Config{Certificates: []tls.Certificate{cert}}), WithServerMeterProvider(mp))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runErr := make(chan error, 1)
+	go func() { runErr <- s.RunContext(ctx) }()
+	addr := listeningAddr(t, s)
+
+	c, err := NewClient(WithInsecureSkipVerify(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp, err := c.Get("https://" + addr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+	if got := sumCounter(t, reader, "http.server.open_connections"); got != 1 {
+		t.Errorf("expected 1 open connection, got %d", got)
+	}
+
+	// Graceful shutdown closes the idle connection.
+	cancel()
+	if err := <-runErr; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if got := sumCounter(t, reader, "http.server.open_connections"); got != 0 {
+		t.Errorf("expected no open connections after shutdown, got %d", got)
+	}
+
+	if _, err := NewServer(":0", nil, WithServerTLSConfig(&tls.Config{})); err == nil {
+		t.Error("expected an error for a config without a certificate")
+	}
+	if _, err := NewServer(":0", nil, WithServerTLSConfig(nil)); err == nil {
+		t.Error("expected an error for a nil config")
+	}
+}
@@ -0,0 +1,101 @@
+package http
+
+import (
+	"errors"
+	stdhttp "net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TransportOption configures a transport created with NewTransport.
+type TransportOption func(*InstrumentedTransport) error
+
+// WithTransportTracerProvider configures the transport with a specific tracer provider.
+func WithTransportTracerProvider(tp trace.TracerProvider) TransportOption {
+	return func(t *InstrumentedTransport) error {
+		if tp == nil {
+			return errors.New("tracer provider must not be nil")
+		}
+		t.Tracer = tp.Tracer(instrumentationName)
+		return nil
+	}
+}
+
+// WithTransportMeterProvider configures the transport with a specific meter provider.
+func WithTransportMeterProvider(mp metric.MeterProvider) TransportOption {
+	return func(t *InstrumentedTransport) error {
+		if mp == nil {
+			return errors.New("meter provider must not be nil")
+		}
+		t.Meter = mp.Meter(instrumentationName)
+		return nil
+	}
+}
+
+// WithTransportPropagator injects trace context into requests with p, in place of the global propagator.
+func WithTransportPropagator(p propagation.TextMapPropagator) TransportOption {
+	return func(t *InstrumentedTransport) error {
+		if p == nil {
+			return errors.New("propagator must not be nil")
+		}
+		t.propagator = p
+		return nil
+	}
+}
+
+// NewTransport wraps base (or http.DefaultTransport, if nil) in this package's client instrumentation, for
+// clients that can't be replaced or passed to InstrumentExistingClient:
+//
+//	transport, err := http.NewTransport(client.Transport)
+//	if err != nil {
+//		return err
+//	}
+//	client.Transport = transport
+//
+// Requests are recorded in http.client.request.duration, http.client.active_requests and
+// http.client.connection.wait_time, and their spans enriched, as for a client from NewClient. The telemetry
+// that needs the client or its dialer, redirects and open connections, is left out, as base is used as it is
+// rather than modified.
+func NewTransport(base stdhttp.RoundTripper, opts ...TransportOption) (*InstrumentedTransport, error) {
+	if base == nil {
+		base = stdhttp.DefaultTransport
+	}
+	t := &InstrumentedTransport{Base: base}
+	for _, opt := range opts {
+		if err := opt(t); err != nil {
+			return nil, err
+		}
+	}
+	if err := t.createInstruments(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// createInstruments creates the transport's request instruments with its meter, or the global meter if it has
+// none. Instruments that can't be created are left nil, and their errors returned.
+func (t *InstrumentedTransport) createInstruments() error {
+	if t.Meter == nil {
+		t.Meter = otel.GetMeterProvider().Meter(instrumentationName)
+	}
+
+	var errs []error
+	var err error
+	if t.mDuration, err = t.Meter.Float64Histogram("http.client.request.duration", metric.WithUnit("s")); err != nil {
+		t.mDuration = nil
+		errs = append(errs, err)
+	}
+	if t.mWaitTime, err = t.Meter.Float64Histogram("http.client.connection.wait_time",
+		metric.WithUnit("s")); err != nil {
+		t.mWaitTime = nil
+		errs = append(errs, err)
+	}
+	if t.mActiveRequests, err = t.Meter.Int64UpDownCounter("http.client.active_requests"); err != nil {
+		t.mActiveRequests = nil
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
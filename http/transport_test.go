@@ -0,0 +1,108 @@
+package http
+
+import (
+	"context"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+func TestNewTransport(t *testing.T) {
+	var traceparent string
+	ts := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		traceparent = r.Header.Get("Traceparent")
+		w.WriteHeader(stdhttp.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	// A client constructed elsewhere, which can only have its transport replaced.
+	client := ts.Client()
+	transport, err := NewTransport(client.Transport,
+		WithTransportTracerProvider(tp),
+		WithTransportMeterProvider(mp),
+		WithTransportPropagator(propagation.TraceContext{}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client.Transport = transport
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "parent")
+	req, _ := stdhttp.NewRequestWithContext(ctx, stdhttp.MethodGet, ts.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+	span.End()
+
+	if traceparent == "" {
+		t.Error("expected trace context to be injected")
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || !hasAttr(spans[0].Attributes, semconv.HTTPResponseStatusCodeKey.Int(204)) {
+		t.Errorf("expected the caller's span to be enriched, got %+v", spans)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	want := map[string]bool{
+		"http.client.request.duration":     true,
+		"http.client.active_requests":      true,
+		"http.client.connection.wait_time": true,
+	}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			delete(want, m.Name)
+		}
+	}
+	if len(want) != 0 {
+		t.Errorf("expected metrics to be recorded, missing %v", want)
+	}
+}
+
+func TestNewTransport_NilBase(t *testing.T) {
+	rt, err := NewTransport(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rt.Base != stdhttp.DefaultTransport {
+		t.Errorf("expected the default transport, got %T", rt.Base)
+	}
+}
+
+func TestNewTransport_Options(t *testing.T) {
+	for _, opt := range []TransportOption{
+		WithTransportTracerProvider(nil), WithTransportMeterProvider(nil), WithTransportPropagator(nil),
+	} {
+		if _, err := NewTransport(nil, opt); err == nil {
+			t.Error("expected an error for a nil option value")
+		}
+	}
+}
+
+func TestNewClient_GlobalMeterInstruments(t *testing.T) {
+	// The instruments are created with the global meter when no meter provider is given.
+	c, err := NewClient()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	it := c.Transport.(*InstrumentedTransport)
+	if it.mDuration == nil || it.mWaitTime == nil || it.mActiveRequests == nil {
+		t.Error("expected the duration, wait time and active requests instruments to be created")
+	}
+}
@@ -0,0 +1,79 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// unixAddrPrefix marks a server address as the path of a Unix domain socket, as in "unix:/run/app.sock".
+const unixAddrPrefix = "unix:"
+
+// defaultUnixSocketMode lets the owner and group connect to the socket, such as a reverse proxy running as
+// a member of the group.
+const defaultUnixSocketMode os.FileMode = 0o660
+
+// WithUnixSocketMode sets the permissions of the socket file created for a "unix:" address (by default
+// 0660, read and write for the owner and group). Connecting to a Unix socket requires write permission.
+func WithUnixSocketMode(mode os.FileMode) ServerOption {
+	return func(s *Server) error {
+		if mode == 0 || mode&^os.ModePerm != 0 {
+			return errors.New("unix socket mode must be a non-zero permission mode")
+		}
+		s.unixSocketMode = mode
+		return nil
+	}
+}
+
+// listen listens on the server's address: a Unix domain socket for a "unix:" address, and TCP otherwise.
+func (s *Server) listen() (net.Listener, error) {
+	addr := s.server.Addr
+	if path, ok := strings.CutPrefix(addr, unixAddrPrefix); ok {
+		mode := s.unixSocketMode
+		if mode == 0 {
+			mode = defaultUnixSocketMode
+		}
+		return listenUnix(path, mode)
+	}
+
+	if addr == "" && s.server.TLSConfig != nil {
+		addr = ":https"
+	} else if addr == "" {
+		addr = ":http"
+	}
+	return net.Listen("tcp", addr)
+}
+
+// listenUnix listens on a Unix domain socket at path with the given permissions. A socket file left behind
+// by a previous process is removed first, but one that is still being listened on is left alone. The
+// socket file is removed again when the listener is closed.
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if path == "" {
+		return nil, errors.New("unix socket path must not be empty")
+	}
+
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if c, err := net.Dial("unix", path); err == nil {
+			_ = c.Close()
+			return nil, fmt.Errorf("socket %s is in use", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("removing stale socket: %w", err)
+		}
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		_ = ln.Close()
+		return nil, fmt.Errorf("setting socket permissions: %w", err)
+	}
+	return ln, nil
+}
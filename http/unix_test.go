@@ -0,0 +1,105 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net"
+	stdhttp "net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestServer_UnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.sock")
+
+	// A socket file left behind by a previous process is replaced.
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = stale.Close()
+
+	s, err := NewServer(unixAddrPrefix+path, stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		_, _ = io.WriteString(w, "hello")
+	}), WithUnixSocketMode(0o600))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- s.RunContext(ctx) }()
+	listeningAddr(t, s)
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fi.Mode().Perm() != 0o600 {
+		t.Errorf("expected socket mode 0600, got %v", fi.Mode().Perm())
+	}
+
+	c := &stdhttp.Client{Transport: &stdhttp.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := c.Get("http://unix/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if string(body) != "hello" {
+		t.Errorf("expected the handler's response, got %q", body)
+	}
+	c.CloseIdleConnections()
+
+	// A socket that is still being listened on is not replaced.
+	other, err := NewServer(unixAddrPrefix+path, stdhttp.NotFoundHandler())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := other.RunContext(context.Background()); err == nil {
+		t.Error("expected an error for a socket in use")
+	}
+
+	cancel()
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected RunContext to return once the context was cancelled")
+	}
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the socket to be removed on shutdown, got %v", err)
+	}
+}
+
+func TestServer_UnixSocketNotASocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.sock")
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s, err := NewServer(unixAddrPrefix+path, stdhttp.NotFoundHandler())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.RunContext(context.Background()); err == nil {
+		t.Error("expected an error for a path that is not a socket")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected the file to be left alone, got %v", err)
+	}
+
+	if _, err := NewServer(":0", nil, WithUnixSocketMode(os.ModeDir|0o700)); err == nil {
+		t.Error("expected an error for a mode with more than permission bits")
+	}
+}
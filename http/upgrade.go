@@ -0,0 +1,81 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	stdhttp "net/http"
+	"net/http/httptrace"
+	"strings"
+)
+
+// DoUpgrade sends req as an HTTP/1.1 upgrade to protocol (e.g. "websocket") and, if the server switches
+// protocols, returns the upgraded connection. The caller owns the connection and must close it; the request
+// remains counted in http.client.active_requests until it does. The upgrade is recorded as an "http.upgrade"
+// event on the span in the request context.
+//
+// Client.Timeout would cut the upgraded connection off, and is not applied; use the request context to bound
+// the handshake. If the server does not switch protocols, the response is returned (with its body closed)
+// along with an error, which is an *APIError for non-2xx responses.
+func DoUpgrade(c *stdhttp.Client, req *stdhttp.Request, protocol string) (net.Conn, *stdhttp.Response, error) {
+	if protocol == "" {
+		return nil, nil, errors.New("upgrade protocol must not be empty")
+	}
+
+	// The connection's addresses and deadlines are exposed through the underlying net.Conn, while reads
+	// and writes go through the response body, which holds any bytes the transport has already buffered.
+	var conn net.Conn
+	ctx := httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) { conn = info.Conn },
+	})
+	req = req.Clone(ctx)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", protocol)
+
+	uc := *c
+	uc.Timeout = 0
+	resp, err := uc.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if resp.StatusCode != stdhttp.StatusSwitchingProtocols {
+		if err := CheckResponse(resp); err != nil {
+			return nil, resp, err
+		}
+		drainAndClose(resp)
+		return nil, resp, fmt.Errorf("server did not switch protocols: %d %s", resp.StatusCode,
+			stdhttp.StatusText(resp.StatusCode))
+	}
+
+	rwc, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok || conn == nil {
+		_ = resp.Body.Close()
+		return nil, resp, errors.New("transport did not return an upgraded connection")
+	}
+	if got := resp.Header.Get("Upgrade"); !strings.EqualFold(got, protocol) {
+		_ = rwc.Close()
+		return nil, resp, fmt.Errorf("server switched to protocol %q, not %q", got, protocol)
+	}
+	return &upgradedConn{Conn: conn, rwc: rwc}, resp, nil
+}
+
+// upgradedConn is an upgraded connection. Reads, writes and closes go through the response body, so that
+// data already buffered by the transport is not lost; everything else is served by the underlying net.Conn.
+type upgradedConn struct {
+	net.Conn
+	rwc io.ReadWriteCloser
+}
+
+func (c *upgradedConn) Read(p []byte) (int, error) {
+	return c.rwc.Read(p)
+}
+
+func (c *upgradedConn) Write(p []byte) (int, error) {
+	return c.rwc.Write(p)
+}
+
+func (c *upgradedConn) Close() error {
+	return c.rwc.Close()
+}
@@ -0,0 +1,115 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// echoUpgradeHandler upgrades requests for the "echo" protocol and echoes everything sent afterwards.
+func echoUpgradeHandler(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	if r.Header.Get("Upgrade") != "echo" {
+		w.WriteHeader(stdhttp.StatusBadRequest)
+		return
+	}
+	conn, brw, err := stdhttp.NewResponseController(w).Hijack()
+	if err != nil {
+		return
+	}
+	defer func() { _ = conn.Close() }()
+	_, _ = brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+	_ = brw.Flush()
+	_, _ = io.Copy(conn, brw)
+}
+
+func TestDoUpgrade(t *testing.T) {
+	srv, err := NewServer(":0", stdhttp.HandlerFunc(echoUpgradeHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv.server.Handler)
+	defer ts.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	c, err := NewClient(
+		WithClientTracerProvider(tp),
+		WithClientMeterProvider(mp),
+		WithTimeout(50*time.Millisecond),
+		WithMethodTimeout(stdhttp.MethodGet, 50*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "parent-span")
+	ctx, cancel := context.WithCancel(ctx)
+	req, _ := stdhttp.NewRequestWithContext(ctx, stdhttp.MethodGet, ts.URL, nil)
+	conn, resp, err := DoUpgrade(c, req, "echo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cancel()
+	span.End()
+
+	if resp.StatusCode != stdhttp.StatusSwitchingProtocols {
+		t.Errorf("expected 101, got %d", resp.StatusCode)
+	}
+	if conn.RemoteAddr() == nil {
+		t.Error("expected the connection to expose its remote address")
+	}
+
+	// The connection outlives the request context, the client timeout and the method timeout.
+	time.Sleep(100 * time.Millisecond)
+	if _, err := conn.Write([]byte("ping\n")); err != nil {
+		t.Fatalf("unexpected write error: %v", err)
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || line != "ping\n" {
+		t.Errorf("expected echo of ping, got %q (%v)", line, err)
+	}
+
+	if got := sumCounter(t, reader, "http.client.active_requests"); got != 1 {
+		t.Errorf("expected the upgraded connection to count as active, got %d", got)
+	}
+	_ = conn.Close()
+	if got := sumCounter(t, reader, "http.client.active_requests"); got != 0 {
+		t.Errorf("expected no active requests after close, got %d", got)
+	}
+
+	var sawEvent bool
+	for _, e := range exporter.GetSpans()[0].Events {
+		sawEvent = sawEvent || e.Name == "http.upgrade"
+	}
+	if !sawEvent {
+		t.Error("expected an http.upgrade span event")
+	}
+}
+
+func TestDoUpgrade_Refused(t *testing.T) {
+	ts := httptest.NewServer(stdhttp.HandlerFunc(echoUpgradeHandler))
+	defer ts.Close()
+
+	c, err := NewClient()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req, _ := stdhttp.NewRequest(stdhttp.MethodGet, ts.URL, nil)
+	_, _, err = DoUpgrade(c, req, "websocket")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != stdhttp.StatusBadRequest {
+		t.Errorf("expected *APIError with 400, got %v", err)
+	}
+}